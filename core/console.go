@@ -0,0 +1,396 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/SSwser/exe-version-selector/internal"
+)
+
+// 认证帧读取超时
+const authTimeout = 5 * time.Second
+
+// setupAuthToken 生成令牌并写入令牌文件，须在监听成功后调用，避免覆盖已运行实例的令牌
+func (inst *Instance) setupAuthToken(addr internal.SocketAddr) error {
+	path, err := addr.TokenPath()
+	if err != nil {
+		return err
+	}
+	token, err := internal.GenerateToken()
+	if err != nil {
+		return fmt.Errorf("生成令牌失败: %v", err)
+	}
+	if err := internal.WriteTokenFile(path, token); err != nil {
+		return fmt.Errorf("写入令牌文件失败: %v", err)
+	}
+	inst.authToken = token
+	inst.tokenPath = path
+	return nil
+}
+
+func (inst *Instance) unauthorizedError() *internal.ProtocolError {
+	return internal.NewProtocolError(internal.ErrCodeUnauthorized, "unauthorized: first frame must be auth with the token from %s", inst.tokenPath)
+}
+
+// exitCore 清理 socket 文件与实例注册后退出
+func (inst *Instance) exitCore(code int) {
+	if inst.unregister != nil {
+		inst.unregister()
+	}
+	inst.consoleAddr.Cleanup()
+	os.Exit(code)
+}
+
+// listenConsole 监听控制 socket、生成令牌并登记实例；须在启动应用之前调用，监听失败时直接退出，不会留下未托管的应用
+func (inst *Instance) listenConsole() net.Listener {
+	cfg, err := inst.getConfig()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "[console] %v\n", err)
+		os.Exit(1)
+	}
+	addr, err := cfg.SocketAddr()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "[console] %v\n", err)
+		os.Exit(1)
+	}
+	ln, err := addr.Listen()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "[console] 启动 socket 失败（%s）: %v\n", addr, err)
+		fmt.Fprintln(os.Stderr, "[console] 地址被占用时可在配置文件中设置 name 与 socket（如 socket: user 或 socket: 127.0.0.1:50506）")
+		os.Exit(1)
+	}
+	inst.consoleAddr = addr
+	if err := inst.setupAuthToken(addr); err != nil {
+		fmt.Fprintf(os.Stderr, "[console] %v\n", err)
+		inst.exitCore(1)
+	}
+	unregister, err := internal.RegisterInstance(internal.InstanceInfo{
+		Name:    cfg.InstanceName(),
+		Config:  cfg.Source,
+		Socket:  addr.String(),
+		Pid:     os.Getpid(),
+		Started: time.Now(),
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "[console] 注册实例失败: %v\n", err)
+	} else {
+		inst.unregister = unregister
+	}
+	fmt.Printf("[console] 实例 %s 已启动，监听 %s\n", cfg.InstanceName(), addr)
+	fmt.Printf("[console] 令牌文件: %s\n", inst.tokenPath)
+	return ln
+}
+
+// serveConsole 处理控制 socket 连接
+func (inst *Instance) serveConsole(ln net.Listener) {
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			continue
+		}
+		go inst.handleConsoleConn(conn)
+	}
+}
+
+// handleConsoleConn 根据首字节自动识别协议：'{' 为 JSON-lines，否则为旧文本协议
+func (inst *Instance) handleConsoleConn(conn net.Conn) {
+	defer conn.Close()
+	conn.SetReadDeadline(time.Now().Add(authTimeout))
+	r := bufio.NewReader(conn)
+	first, err := r.Peek(1)
+	if err != nil {
+		return
+	}
+	if internal.IsJSONFrame(first[0]) {
+		inst.serveJSONConn(conn, r)
+		return
+	}
+	inst.serveLegacyConn(conn, r)
+}
+
+// serveJSONConn 处理 JSON-lines 协议，同一连接可连续发送多个请求
+// 第一帧必须为 {"cmd":"auth","token":"..."}
+// subscribe 后该连接同时接收服务端推送的事件，直到连接关闭
+func (inst *Instance) serveJSONConn(conn net.Conn, r *bufio.Reader) {
+	authed := false
+	var writeMu sync.Mutex
+	writeLine := func(v interface{}) error {
+		data, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+		writeMu.Lock()
+		defer writeMu.Unlock()
+		_, err = conn.Write(append(data, '\n'))
+		return err
+	}
+
+	var unsubscribe func()
+	var stopFollow chan struct{} // logs -f 的跟踪，连接关闭时结束
+	defer func() {
+		if unsubscribe != nil {
+			unsubscribe()
+		}
+		if stopFollow != nil {
+			close(stopFollow)
+		}
+	}()
+
+	for {
+		line, err := r.ReadBytes('\n')
+		line = bytes.TrimSpace(line)
+		if len(line) > 0 {
+			req, resp := internal.DecodeRequest(line)
+			if !authed {
+				if resp == nil && (req.Cmd != "auth" || !internal.TokenEqual(req.Token, inst.authToken)) {
+					resp = internal.EncodeResponse(req.ID, nil, inst.unauthorizedError())
+				}
+				if resp != nil {
+					writeLine(resp)
+					return
+				}
+				authed = true
+				conn.SetReadDeadline(time.Time{})
+				writeLine(internal.EncodeResponse(req.ID, nil, nil))
+				continue
+			}
+			if resp == nil && req.Cmd == "subscribe" {
+				if unsubscribe != nil {
+					resp = internal.EncodeResponse(req.ID, nil, internal.NewProtocolError(internal.ErrCodeBadRequest, "already subscribed"))
+				} else {
+					fmt.Println("[SOCKET] 新的事件订阅")
+					var ch <-chan internal.Event
+					ch, unsubscribe = inst.events.Subscribe()
+					writeLine(internal.EncodeResponse(req.ID, internal.SubscribeResult{
+						Status:   inst.currentStatus(),
+						Activate: inst.getActivate(),
+						Instance: inst.instanceName(),
+						Managed:  inst.managedStatuses(),
+					}, nil))
+					go forwardEvents(conn, ch, writeLine)
+					continue
+				}
+			}
+			if resp == nil && req.Cmd == "logs" {
+				result, opts, offset, err := inst.getLogs(req.Name, req.Args)
+				if err == nil && opts.Follow && stopFollow != nil {
+					err = internal.NewProtocolError(internal.ErrCodeBadRequest, "already following logs")
+				}
+				writeLine(internal.EncodeResponse(req.ID, result, err))
+				if err == nil && opts.Follow {
+					stopFollow = make(chan struct{})
+					go followLog(conn, result, offset, stopFollow, writeLine)
+				}
+				continue
+			}
+			if resp == nil {
+				fmt.Printf("[SOCKET] 收到请求: %s %s %v\n", req.Cmd, req.Name, req.Args)
+				result, cmdErr := inst.dispatchCommand(req)
+				resp = internal.EncodeResponse(req.ID, result, cmdErr)
+			}
+			writeLine(resp)
+			if req.Cmd == "exit" && resp.OK {
+				time.Sleep(50 * time.Millisecond)
+				inst.exitCore(0)
+			}
+		}
+		if err != nil {
+			if err != io.EOF {
+				fmt.Printf("[SOCKET] 读取请求失败: %v\n", err)
+			}
+			return
+		}
+	}
+}
+
+// forwardEvents 将订阅到的事件写入连接；订阅被断开或写入失败时关闭连接，由客户端重连
+func forwardEvents(conn net.Conn, ch <-chan internal.Event, writeLine func(interface{}) error) {
+	for ev := range ch {
+		if err := writeLine(ev); err != nil {
+			break
+		}
+	}
+	conn.Close()
+}
+
+// followLog 将日志文件新写入的行作为 log 事件写入连接，写入失败时关闭连接
+func followLog(conn net.Conn, logs internal.LogsResult, offset int64, stop <-chan struct{}, writeLine func(interface{}) error) {
+	internal.FollowLog(logs.File, offset, stop, func(line string) error {
+		data, _ := json.Marshal(internal.LogLineEvent{Name: logs.Name, Line: line})
+		return writeLine(internal.Event{V: internal.ProtocolVersion, Event: internal.EventLog, Data: data, Time: time.Now()})
+	})
+	conn.Close()
+}
+
+// serveLegacyConn 处理旧文本协议：首行 "auth:<token>"，次行 "cmd" 或 "cmd:arg"，响应后关闭连接
+func (inst *Instance) serveLegacyConn(conn net.Conn, r *bufio.Reader) {
+	authLine, err := r.ReadString('\n')
+	if err != nil {
+		return
+	}
+	if token, ok := strings.CutPrefix(strings.TrimSpace(authLine), "auth:"); !ok || !internal.TokenEqual(token, inst.authToken) {
+		conn.Write([]byte("ERR " + inst.unauthorizedError().Message + "\n"))
+		return
+	}
+	conn.SetReadDeadline(time.Time{})
+
+	cmdLine, err := r.ReadString('\n')
+	if err != nil {
+		return
+	}
+	fmt.Printf("[SOCKET] 收到命令: %s\n", strings.TrimSpace(cmdLine))
+	req, perr := internal.ParseLegacyRequest(cmdLine)
+	if perr != nil {
+		conn.Write([]byte("ERR " + perr.Message + "\n"))
+		return
+	}
+	result, cmdErr := inst.dispatchCommand(req)
+	if cmdErr != nil {
+		perr := internal.AsProtocolError(cmdErr)
+		if perr.Code == internal.ErrCodeInternal {
+			conn.Write([]byte(perr.Message))
+		} else {
+			conn.Write([]byte("ERR " + perr.Message + "\n"))
+		}
+		return
+	}
+
+	switch v := result.(type) {
+	case internal.ActivateResult:
+		conn.Write([]byte(v.Name))
+	case internal.StatusResult:
+		conn.Write([]byte(v.String())) // 返回详细状态字符串
+	case internal.ManagedResult:
+		for _, st := range v.Instances {
+			conn.Write([]byte(st.Target + " | " + st.String() + "\n"))
+		}
+	case internal.ListResult:
+		conn.Write([]byte(strings.Join(v.Apps, "\n")))
+	case internal.DryRunResult:
+		conn.Write([]byte(strings.Join(v.Argv, " ")))
+	case internal.HistoryResult:
+		data, _ := json.Marshal(v.Entries)
+		conn.Write(data)
+	case internal.LogsResult:
+		conn.Write([]byte(strings.Join(v.Lines, "\n")))
+	case *internal.AppInfoResult:
+		conn.Write(fmt.Appendf(nil, "%s|||%s|||%s\n", v.Name, v.Path, strings.Join(v.Args, " ")))
+	default:
+		conn.Write([]byte("OK\n"))
+	}
+	if req.Cmd == "exit" {
+		time.Sleep(50 * time.Millisecond)
+		inst.exitCore(0)
+	}
+}
+
+// dispatchCommand 执行一条控制命令，JSON 与旧文本协议共用
+// 返回的 result 为 internal 中的 *Result 类型，无结果的命令返回 nil
+func (inst *Instance) dispatchCommand(req internal.Request) (interface{}, error) {
+	switch req.Cmd {
+	case "ping":
+		return internal.PingResult{Version: internal.ProtocolVersion}, nil
+	case "activate":
+		return inst.activateResult(), nil
+	case "status":
+		return inst.getStatus(req.Name)
+	case "ps":
+		return internal.ManagedResult{Instances: inst.managedStatuses()}, nil
+	case "list":
+		cfg, err := inst.getConfig()
+		if err != nil {
+			return nil, err
+		}
+		return internal.ListResult{Apps: cfg.AllApps(), Scanned: cfg.ScannedOrder}, nil
+	case "info":
+		return inst.getAppInfo(req.Name, slices.Contains(req.Args, "--resolved"))
+	case "logs":
+		result, _, _, err := inst.getLogs(req.Name, req.Args)
+		return result, err
+	case "history":
+		return inst.getHistory(req.Name, req.Args)
+	case "dry-run":
+		return inst.dryRun(req.Name, req.Args)
+	case "reload":
+		fmt.Println("[reload]")
+		diff, err := inst.config.Reload()
+		if err != nil {
+			return nil, err
+		}
+		if diff == nil {
+			diff = &internal.ConfigDiff{}
+		}
+		return inst.publishReload(*diff), nil
+	case "run":
+		// 指定应用时在已有实例之外同时启动，profile 只用于该实例
+		if req.Name != "" {
+			return inst.startNamed(req.Name, req.Args, req.Profile)
+		}
+		// 指定 profile 时先写入配置，并停止以其它方式运行的默认实例
+		if req.Profile != "" {
+			if err := inst.selectProfile(req.Profile); err != nil {
+				return nil, err
+			}
+			if err := inst.stopManaged(inst.currentManaged()); err != nil {
+				return nil, err
+			}
+		}
+		go inst.runAppProxy(req.Args)
+		return nil, nil
+	case "switch":
+		if req.Name == "" {
+			return nil, internal.NewProtocolError(internal.ErrCodeBadRequest, "need app name")
+		}
+		if err := inst.switchActivate(req.Name); err != nil {
+			return nil, err
+		}
+		return nil, nil
+	case "restart":
+		targets, err := inst.findTargets(req.Name)
+		if err != nil {
+			return nil, err
+		}
+		for _, m := range targets {
+			if err := inst.stopManaged(m); err != nil {
+				return nil, err
+			}
+		}
+		fmt.Println("[restart] 启动新进程...")
+		if req.Name == "" {
+			go inst.runAppProxy(nil) // 默认实例按激活应用重启（激活应用可能已变化）
+			return nil, nil
+		}
+		for _, m := range targets {
+			if inst.reserveManaged(m) {
+				go inst.startManaged(m, nil)
+			}
+		}
+		return nil, nil
+	case "stop":
+		targets, err := inst.findTargets(req.Name)
+		if err != nil {
+			return nil, err
+		}
+		for _, m := range targets {
+			if err := inst.stopManaged(m); err != nil {
+				return nil, err
+			}
+		}
+		fmt.Println("[stop] 已终止")
+		return nil, nil
+	case "exit":
+		inst.stopAll()
+		return nil, nil
+	default:
+		return nil, internal.NewProtocolError(internal.ErrCodeUnknownCommand, "unknown command: %s", req.Cmd)
+	}
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/SSwser/exe-version-selector/internal"
+)
+
+// startTestConsole 监听随机端口并以 handleConsoleConn 处理连接，返回地址与令牌
+func startTestConsole(t *testing.T) (string, string) {
+	t.Helper()
+	token, err := internal.GenerateToken()
+	if err != nil {
+		t.Fatal(err)
+	}
+	inst := newInstance("")
+	inst.authToken, inst.tokenPath = token, "token"
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go inst.handleConsoleConn(conn)
+		}
+	}()
+	return ln.Addr().String(), token
+}
+
+// exchange 发送 frames 后读取全部响应直到服务端关闭连接或超时
+func exchange(t *testing.T, addr, frames string) string {
+	t.Helper()
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(2 * time.Second))
+	if _, err := io.WriteString(conn, frames); err != nil {
+		t.Fatal(err)
+	}
+	data, _ := io.ReadAll(conn)
+	return string(data)
+}
+
+func TestConsoleAuthJSON(t *testing.T) {
+	addr, token := startTestConsole(t)
+	cases := []struct {
+		name  string
+		first string
+		code  string
+	}{
+		{"错误的令牌", `{"v":1,"id":"a","cmd":"auth","token":"wrong"}`, internal.ErrCodeUnauthorized},
+		{"缺少令牌", `{"v":1,"id":"a","cmd":"auth"}`, internal.ErrCodeUnauthorized},
+		{"长度不同的令牌", `{"v":1,"id":"a","cmd":"auth","token":"` + token[:10] + `"}`, internal.ErrCodeUnauthorized},
+		{"首帧不是 auth", `{"v":1,"id":"a","cmd":"ping"}`, internal.ErrCodeUnauthorized},
+		{"首帧无法解析", `{"cmd":`, internal.ErrCodeBadRequest},
+	}
+	for _, c := range cases {
+		// 认证失败后连接立即关闭，之后的 ping 不会被处理
+		out := exchange(t, addr, c.first+"\n"+`{"v":1,"id":"b","cmd":"ping"}`+"\n")
+		lines := strings.Split(strings.TrimSpace(out), "\n")
+		if len(lines) != 1 {
+			t.Errorf("%s: 响应 %q，期望只有一行", c.name, out)
+			continue
+		}
+		var resp internal.Response
+		if err := json.Unmarshal([]byte(lines[0]), &resp); err != nil {
+			t.Fatalf("%s: %v", c.name, err)
+		}
+		if resp.OK || resp.Error == nil || resp.Error.Code != c.code {
+			t.Errorf("%s: 响应 %s，期望 %s", c.name, lines[0], c.code)
+		}
+	}
+
+	// 认证通过后连接保持打开，逐行读取两个响应
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(2 * time.Second))
+	io.WriteString(conn, `{"v":1,"id":"a","cmd":"auth","token":"`+token+`"}`+"\n"+`{"v":1,"id":"b","cmd":"ping"}`+"\n")
+	r := bufio.NewScanner(conn)
+	for _, id := range []string{"a", "b"} {
+		var resp internal.Response
+		if !r.Scan() || json.Unmarshal(r.Bytes(), &resp) != nil || !resp.OK || resp.ID != id {
+			t.Errorf("正确的令牌: 响应 %q，期望 %s 成功", r.Text(), id)
+		}
+	}
+}
+
+func TestConsoleAuthLegacy(t *testing.T) {
+	addr, token := startTestConsole(t)
+	for _, frames := range []string{
+		"auth:wrong\nping\n",
+		"auth:\nping\n",
+		"auth:" + token[:10] + "\nping\n",
+		"ping\n",
+	} {
+		if out := exchange(t, addr, frames); !strings.HasPrefix(out, "ERR unauthorized") {
+			t.Errorf("%q: 响应 %q，期望 ERR unauthorized", frames, out)
+		}
+	}
+	if out := exchange(t, addr, "auth:"+token+"\nping\n"); out != "OK\n" {
+		t.Errorf("正确的令牌: 响应 %q，期望 OK", out)
+	}
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/SSwser/exe-version-selector/internal"
+)
+
+// watchHealth 在后台按应用的健康检查探测托管实例的进程 pid，健康状态变化时调用 report；
+// 返回的 channel 在进程退出时关闭以停止探测
+func (inst *Instance) watchHealth(m *managedApp, app internal.App, pid int, probe internal.Probe, report func(main internal.AppMainStatus, detail string)) chan struct{} {
+	stop := make(chan struct{})
+	monitor := internal.NewHealthMonitor(app.Health, probe, func(main internal.AppMainStatus, detail string) {
+		inst.mu.Lock()
+		alive := m.pid == pid
+		inst.mu.Unlock()
+		if !alive {
+			return // 进程已退出或已被替换
+		}
+		report(main, detail)
+		if main == internal.AppUnhealthy && app.Health.Restart {
+			go inst.restartUnhealthy(m, app, pid)
+		}
+	})
+	go monitor.Run(stop)
+	return stop
+}
+
+// restartUnhealthy 按停止策略终止不健康的进程，退出后由 scheduleRestart 按重启策略重启
+func (inst *Instance) restartUnhealthy(m *managedApp, app internal.App, pid int) {
+	inst.mu.Lock()
+	if m.pid != pid {
+		inst.mu.Unlock()
+		return
+	}
+	m.unhealthyPid = pid
+	logFile := m.status.LogFile
+	inst.mu.Unlock()
+
+	fmt.Printf("[health] %s 不健康，终止进程 (PID=%d) 后重启\n", m.target(), pid)
+	err := internal.StopProcessTree(pid, app.Stop, func(detail string) {
+		s := internal.NewAppStatus(internal.AppUnhealthy, pid, 0, "不健康，正在停止: "+detail)
+		s.LogFile = logFile
+		inst.setAppStatus(m, s)
+	})
+	if err != nil {
+		fmt.Printf("[health] %s 终止不健康的进程失败: %v\n", m.target(), err)
+	}
+}
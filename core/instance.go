@@ -0,0 +1,785 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/SSwser/exe-version-selector/internal"
+)
+
+// Instance 一个 evs core 实例的全部运行状态，由 --config 指定的配置文件驱动
+// 同一台机器可以为不同工具族（如 node、python）各运行一个实例，互不干扰
+type Instance struct {
+	config *internal.ConfigStore
+
+	pin           *internal.Selection // 启动时由环境变量或目录版本文件选定的应用，手动切换后清除；受 mu 保护
+	profile       string              // 命令行 --profile 指定的 profile，前台模式只用于本次运行
+	extraArgs     []string
+	lastFoundArgs []string          // 仅记录 FindProcessByPath 找到的参数（不含exe路径）；受 mu 保护
+	lastFoundApp  string            // lastFoundArgs 所属的应用；受 mu 保护
+	history       *internal.History // 状态变化历史，nil 表示不记录
+
+	mu      sync.Mutex
+	managed []*managedApp // 托管实例（按创建顺序），见 managed.go
+	current *managedApp   // 默认实例：最近一次以激活应用启动的实例
+
+	// 控制 socket 订阅者的事件中心
+	events *internal.EventHub
+
+	logMu sync.Mutex
+	logs  map[string]*internal.AppLog // 各应用的日志文件（按路径），多次启动共用
+
+	idleMu    sync.Mutex
+	idleTimer *time.Timer // 没有任何实例运行时的自动退出计时
+
+	consoleAddr internal.SocketAddr // 控制 socket 地址
+	authToken   string              // 本次启动生成的控制 socket 令牌
+	tokenPath   string              // 令牌文件路径
+	unregister  func()              // 从实例注册表移除
+}
+
+func newInstance(configPath string) *Instance {
+	return &Instance{
+		config: internal.NewConfigStore(configPath),
+		events: internal.NewEventHub(),
+	}
+}
+
+// setAppStatus 更新托管实例的状态、记录状态历史并推送 managed 事件，默认实例同时推送 status 事件
+func (inst *Instance) setAppStatus(m *managedApp, s internal.AppStatus) {
+	inst.mu.Lock()
+	m.status = s
+	primary := m == inst.current
+	result := m.statusResult()
+	run := m.run
+	inst.mu.Unlock()
+	inst.recordHistory(m, s, run)
+	if primary {
+		inst.events.Publish(internal.EventStatus, result)
+	}
+	inst.events.Publish(internal.EventManaged, internal.ManagedResult{Instances: inst.managedStatuses()})
+}
+
+// recordHistory 记录一次状态变化，参数与运行时长取自对应的那次启动 run
+func (inst *Instance) recordHistory(m *managedApp, s internal.AppStatus, run runInfo) {
+	if inst.history == nil {
+		return
+	}
+	e := internal.HistoryEntry{
+		Time:     s.Timestamp,
+		App:      m.app,
+		Target:   m.target(),
+		Main:     s.Main,
+		Status:   s.Main.String(),
+		Pid:      s.Pid,
+		ExitCode: s.ExitCode,
+		Detail:   s.Detail,
+	}
+	if run.pid == s.Pid {
+		e.Args = run.args
+		if !run.started.IsZero() {
+			e.DurationMs = s.Timestamp.Sub(run.started).Milliseconds()
+		}
+	}
+	inst.history.Add(e)
+}
+
+// publishProcessExit 推送应用进程退出事件
+func (inst *Instance) publishProcessExit(m *managedApp, pid, exitCode int, reason string) {
+	inst.events.Publish(internal.EventProcessExit, internal.ProcessExitEvent{Name: m.app, Pid: pid, ExitCode: exitCode, Reason: reason, Target: m.target()})
+}
+
+func (inst *Instance) getConfig() (*internal.Config, error) {
+	cfg := inst.config.Get()
+	if cfg == nil {
+		return nil, internal.NewProtocolError(internal.ErrCodeConfigNotLoaded, "config not loaded")
+	}
+	return cfg, nil
+}
+
+// getActivate 返回实际使用的应用：启动时固定的应用优先（每次重新解析，约束可跟随新安装的版本），其次为配置中的 activate
+func (inst *Instance) getActivate() string {
+	cfg, err := inst.getConfig()
+	if err != nil {
+		return ""
+	}
+	if pin := inst.pinned(); pin != nil {
+		if res, err := cfg.ResolveApp(pin.Spec); err == nil {
+			return res.Name
+		}
+	}
+	return cfg.Activate
+}
+
+// activateResult 返回激活应用及其当前使用的 profile
+func (inst *Instance) activateResult() internal.ActivateResult {
+	name := inst.getActivate()
+	res := internal.ActivateResult{Name: name}
+	if cfg, err := inst.getConfig(); err == nil {
+		res.Profile = cfg.ProfileFor(name)
+	}
+	return res
+}
+
+// pinSource 返回固定应用的来源说明，未固定时为空
+func (inst *Instance) pinSource() string {
+	cfg, err := inst.getConfig()
+	pin := inst.pinned()
+	if err != nil || pin == nil {
+		return ""
+	}
+	return pin.Describe(cfg)
+}
+
+// pinned 返回启动时固定的应用，未固定时为 nil
+func (inst *Instance) pinned() *internal.Selection {
+	inst.mu.Lock()
+	defer inst.mu.Unlock()
+	return inst.pin
+}
+
+// setPin 固定启动时选定的应用，sel 为 nil 时清除
+func (inst *Instance) setPin(sel *internal.Selection) {
+	inst.mu.Lock()
+	inst.pin = sel
+	inst.mu.Unlock()
+}
+
+// setFoundArgs 记录启动 evs 时检测到的已运行实例所属的应用及其参数（不含 exe 路径）
+func (inst *Instance) setFoundArgs(app string, args []string) {
+	inst.mu.Lock()
+	inst.lastFoundApp, inst.lastFoundArgs = app, args
+	inst.mu.Unlock()
+}
+
+// publishReload 推送 reload 事件（附带变化的应用），激活应用变化时同时推送 activate 事件
+func (inst *Instance) publishReload(diff internal.ConfigDiff) internal.ReloadEvent {
+	cfg, err := inst.getConfig()
+	if err != nil {
+		return internal.ReloadEvent{}
+	}
+	if inst.history != nil {
+		inst.history.Resize(cfg.HistorySizeOrDefault())
+	}
+	ev := internal.ReloadEvent{
+		Apps:     cfg.AllApps(),
+		Scanned:  cfg.ScannedOrder,
+		Activate: inst.getActivate(),
+		Added:    diff.Added,
+		Removed:  diff.Removed,
+		Changed:  diff.Changed,
+	}
+	inst.events.Publish(internal.EventReload, ev)
+	if diff.ActivateChanged {
+		inst.events.Publish(internal.EventActivate, inst.activateResult())
+	}
+	return ev
+}
+
+// startConfigWatcher 监听配置文件，外部修改（编辑器、CLI）校验通过后自动生效并推送 reload 事件
+func (inst *Instance) startConfigWatcher() {
+	inst.config.Watch(func(diff internal.ConfigDiff) {
+		fmt.Printf("[watch] 配置已重新加载: %s\n", diff)
+		inst.publishReload(diff)
+	}, func(err error) {
+		fmt.Printf("[watch] 重新加载配置失败，保留原配置: %v\n", err)
+	})
+}
+
+func (inst *Instance) instanceName() string {
+	cfg, err := inst.getConfig()
+	if err != nil {
+		return internal.DefaultInstanceName
+	}
+	return cfg.InstanceName()
+}
+
+// switchActivate 切换激活应用，spec 为应用名或版本约束（如 ^18、latest）
+func (inst *Instance) switchActivate(spec string) error {
+	cfg, err := inst.getConfig()
+	if err != nil {
+		return err
+	}
+
+	if _, err := cfg.ResolveApp(spec); err != nil {
+		return internal.NewProtocolError(internal.ErrCodeNotFound, "%v", err)
+	}
+
+	// 切换前按停止策略终止默认实例（软停止 -> 宽限 -> 强制终止进程树），同时运行的其它实例不受影响
+	err = inst.stopManaged(inst.currentManaged())
+	if err != nil {
+		return err
+	}
+
+	// 加锁读取最新配置再修改，避免覆盖 CLI 等其他进程的并发修改
+	_, err = inst.config.Update(func(cfg *internal.Config) error {
+		res, err := cfg.ResolveApp(spec)
+		if err != nil {
+			return internal.NewProtocolError(internal.ErrCodeNotFound, "%v", err)
+		}
+		cfg.SetActivate(res)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	inst.setPin(nil) // 手动切换优先于启动时的目录/环境变量选择
+	inst.events.Publish(internal.EventActivate, inst.activateResult())
+
+	// 不要清空 inst.lastFoundArgs，保证参数全程跟随
+	go inst.runAppProxy(nil)
+	return nil
+}
+
+// selectProfile 选择激活应用启动时使用的 profile 并写入配置（default 恢复默认），推送 activate 事件
+func (inst *Instance) selectProfile(profile string) error {
+	if !internal.IsDefaultProfile(profile) {
+		cfg, err := inst.getConfig()
+		if err != nil {
+			return err
+		}
+		name := inst.getActivate()
+		app, _ := cfg.LookupApp(name)
+		if _, err := app.WithProfile(profile); err != nil {
+			return internal.NewProtocolError(internal.ErrCodeNotFound, "应用 %s %v", name, err)
+		}
+	}
+	_, err := inst.config.Update(func(cfg *internal.Config) error {
+		cfg.SetProfile(profile)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	inst.events.Publish(internal.EventActivate, inst.activateResult())
+	return nil
+}
+
+// 参数 name 为空时返回当前激活应用，否则返回指定应用（也可以是版本约束）
+// Cwd/Env 为解析后的值，解析失败时留空；resolved 为 true 时 Path/Args 为模板展开后的值
+func (inst *Instance) getAppInfo(name string, resolved bool) (*internal.AppInfoResult, error) {
+	spec := name
+	if name == "" {
+		spec = inst.getActivate()
+	}
+
+	cfg, err := inst.getConfig()
+	if err != nil {
+		return nil, err
+	}
+	res, err := cfg.ResolveApp(spec)
+	if err != nil {
+		return nil, internal.NewProtocolError(internal.ErrCodeNotFound, "%v", err)
+	}
+	appName := res.Name
+	app, _ := cfg.LookupApp(appName)
+	profiles := app.ProfileNames()
+	if resolved {
+		if app, err = cfg.PrepareApp(appName, ""); err != nil {
+			return nil, internal.NewProtocolError(internal.ErrCodeBadRequest, "%v", err)
+		}
+	}
+	info := &internal.AppInfoResult{
+		Name:       appName,
+		Path:       app.Path,
+		Args:       app.Args,
+		Resolved:   resolved,
+		Scanned:    cfg.IsScanned(appName),
+		Version:    res.Version,
+		Resolution: cfg.ResolutionLine(res, name == ""),
+		Profile:    cfg.ProfileFor(appName),
+		Profiles:   profiles,
+	}
+	if name == "" {
+		info.Source = inst.pinSource()
+	}
+	if env, err := internal.ResolveAppEnv(app, cfg.Dir()); err == nil {
+		info.Cwd = env.Cwd
+		info.Env = env.Vars
+	}
+	return info, nil
+}
+
+// stopManaged 按应用的停止策略终止托管实例的进程，各阶段写入状态详情；m 为 nil 或未在运行时只取消等待中的自动重启
+func (inst *Instance) stopManaged(m *managedApp) error {
+	if m == nil {
+		return nil
+	}
+	inst.mu.Lock()
+	m.restartSeq++
+	pid := m.pid
+	if pid == 0 {
+		inst.mu.Unlock()
+		return nil
+	}
+	m.stoppedPid = pid
+	logFile := m.status.LogFile
+	alive := m.status.Main // 停止期间保留启动中/不健康等状态
+	inst.mu.Unlock()
+	var policy internal.StopPolicy
+	if cfg, err := inst.getConfig(); err == nil {
+		app, _ := cfg.LookupApp(m.app)
+		policy = app.Stop
+	}
+	if !alive.Alive() {
+		alive = internal.AppRunning
+	}
+	setStatus := func(main internal.AppMainStatus, detail string) {
+		s := internal.NewAppStatus(main, pid, 0, detail)
+		s.LogFile = logFile
+		inst.setAppStatus(m, s)
+	}
+	err := internal.StopProcessTree(pid, policy, func(detail string) {
+		setStatus(alive, "正在停止: "+detail)
+	})
+	if err == nil {
+		setStatus(internal.AppExited, "已终止")
+		inst.setPid(m, 0)
+	} else {
+		setStatus(internal.AppExited, "终止失败")
+	}
+	return err
+}
+
+// runAppProxy 手动启动当前激活应用（作为默认实例）
+func (inst *Instance) runAppProxy(args []string) {
+	cfg, err := inst.getConfig()
+	if err != nil {
+		fmt.Println("配置未加载")
+		return
+	}
+	appName := inst.getActivate()
+	if _, ok := cfg.LookupApp(appName); !ok {
+		fmt.Println("未找到激活应用")
+		return
+	}
+	inst.startManaged(inst.primaryManaged(appName), args)
+}
+
+// startManaged 手动启动托管实例，同时清零自动重启计数；m 须已由 acquireManaged/primaryManaged/reserveManaged 标记为启动中
+func (inst *Instance) startManaged(m *managedApp, args []string) {
+	inst.mu.Lock()
+	m.restartSeq++
+	m.restartTracker.Reset()
+	profile := m.profile
+	inst.mu.Unlock()
+	cfg, err := inst.getConfig()
+	if err != nil {
+		inst.startFailed(m, internal.NewAppStatus(internal.AppExited, 0, 0, "启动失败: 配置未加载"))
+		fmt.Println("配置未加载")
+		return
+	}
+	// profile 与模板只在这里处理一次，之后的自动重启沿用结果（如 {{now}} 生成的日志路径）
+	if profile == "" {
+		profile = cfg.ProfileFor(m.app)
+	}
+	if !internal.IsDefaultProfile(profile) {
+		fmt.Printf("[profile] %s 使用 profile: %s\n", m.target(), profile)
+	}
+	app, err := cfg.PrepareApp(m.app, profile)
+	if err != nil {
+		inst.startFailed(m, internal.NewAppStatus(internal.AppExited, 0, 0, "启动失败: "+err.Error()))
+		fmt.Printf("启动应用失败: %v\n", err)
+		return
+	}
+	inst.launchApp(m, app, args)
+}
+
+// scheduleRestart 按应用的重启策略安排自动重启，超过最大重试次数后进入“已放弃”终态
+func (inst *Instance) scheduleRestart(m *managedApp, app internal.App, pid int, failed bool, uptime time.Duration, args []string) {
+	inst.mu.Lock()
+	if pid != 0 && pid == m.stoppedPid {
+		inst.mu.Unlock()
+		return // 主动停止
+	}
+	policy := app.Restart
+	if pid != 0 && pid == m.unhealthyPid {
+		// 健康检查的 restart 即使重启模式为 never 也重启，重试次数与退避仍按重启策略
+		failed = true
+		if policy.ModeOrDefault() == internal.RestartNever {
+			policy.Mode = internal.RestartOnFailure
+		}
+	}
+	delay, restart, giveUp, attempt := m.restartTracker.Next(policy, failed, uptime)
+	last := m.status
+	seq := m.restartSeq
+	inst.mu.Unlock()
+	if giveUp {
+		inst.setAppStatus(m, internal.NewAppStatus(internal.AppGaveUp, pid, last.ExitCode, fmt.Sprintf("连续重启 %d 次仍失败，已放弃", attempt)))
+		fmt.Printf("[restart] %s 重启次数已达上限（%d），放弃自动重启\n", m.target(), attempt)
+		return
+	}
+	if !restart {
+		return
+	}
+	last.Detail = fmt.Sprintf("%s，%s 后第 %d 次自动重启", last.Detail, delay, attempt)
+	inst.setAppStatus(m, last)
+	fmt.Printf("[restart] %s 将在 %s 后第 %d 次自动重启\n", m.target(), delay, attempt)
+	go func() {
+		time.Sleep(delay)
+		inst.mu.Lock()
+		if seq != m.restartSeq || m.busy() {
+			inst.mu.Unlock()
+			return // 期间已手动启动/停止/切换
+		}
+		m.starting = true
+		inst.mu.Unlock()
+		inst.launchApp(m, app, args)
+	}()
+}
+
+// launchApp 合并参数并启动已标记为启动中的托管实例（app 为已应用 profile、展开模板的配置），退出时按重启策略处理
+func (inst *Instance) launchApp(m *managedApp, app internal.App, args []string) {
+	appName := m.app
+	cfg, err := inst.getConfig()
+	if err != nil {
+		inst.startFailed(m, internal.NewAppStatus(internal.AppExited, 0, 0, "启动失败: 配置未加载"))
+		fmt.Println("配置未加载")
+		return
+	}
+
+	inputs := inst.argInputs(args)
+	fmt.Printf("[DEBUG] app.Args: %v\n", app.Args)
+	fmt.Printf("[DEBUG] lastFoundArgs: %v\n", inputs.Inherited)
+	fmt.Printf("[DEBUG] extraArgs: %v\n", inst.extraArgs)
+	fmt.Printf("[DEBUG] runAppProxy args: %v\n", args)
+	var startedAt time.Time
+
+	// 参数按应用的 args_policy 合并（见 internal.BuildArgs）：
+	// 1. app.Args：应用配置文件中的默认参数
+	// 2. lastFoundArgs：启动 evs 时检测到的已运行实例参数（不含 exe 路径）
+	// 3. extraArgs：命令行参数（evs.exe 启动时的参数）
+	// 4. args：本次 run 传入的参数
+	plan := internal.BuildArgs(appName, app, inputs)
+	for _, part := range plan.Dropped {
+		fmt.Printf("[args] 丢弃 %s 的参数 %v: %s\n", part.Source, part.Args, part.Reason)
+	}
+	finalArgs := plan.Argv
+	fmt.Printf("[DEBUG] finalArgs: %v\n", finalArgs)
+
+	env, err := internal.ResolveAppEnv(app, cfg.Dir())
+	if err != nil {
+		inst.startFailed(m, internal.NewAppStatus(internal.AppExited, 0, 0, "启动失败: "+err.Error()))
+		fmt.Printf("启动应用失败: %v\n", err)
+		return
+	}
+	opts := internal.ProcessOptions{Env: env.Environ(), Dir: env.Cwd}
+
+	// 输出写入日志文件的同时照常输出到 core 控制台
+	logf := func(string, ...interface{}) {}
+	logFile := ""
+	if lg := inst.appLog(cfg, appName); lg != nil {
+		opts.Stdout = lg.Stream("out"+m.logSuffix(), os.Stdout)
+		opts.Stderr = lg.Stream("err"+m.logSuffix(), os.Stderr)
+		logf, logFile = lg.Printf, lg.Path()
+	}
+	// 配置了健康检查时准备探测；log 探测在输出写入日志/控制台的同时逐行匹配
+	var probe internal.Probe
+	if app.Health.Enabled() {
+		var output *internal.OutputMatcher
+		if app.Health.Log != "" {
+			if output, err = internal.NewOutputMatcher(app.Health.Log); err != nil {
+				inst.startFailed(m, internal.NewAppStatus(internal.AppExited, 0, 0, "启动失败: 健康检查"+err.Error()))
+				fmt.Printf("启动应用失败: %v\n", err)
+				return
+			}
+			opts.Stdout = output.Writer(writerOr(opts.Stdout, os.Stdout))
+			opts.Stderr = output.Writer(writerOr(opts.Stderr, os.Stderr))
+		}
+		if probe, err = internal.NewProbe(app.Health, opts, output); err != nil {
+			inst.startFailed(m, internal.NewAppStatus(internal.AppExited, 0, 0, "启动失败: "+err.Error()))
+			fmt.Printf("启动应用失败: %v\n", err)
+			return
+		}
+	}
+	var stopHealth chan struct{}
+
+	// setStatus 在状态中记录日志文件，crashed 为 true 时同时写入详情，便于查看崩溃前的输出
+	setStatus := func(s internal.AppStatus, crashed bool) {
+		s.LogFile = logFile
+		if crashed && logFile != "" {
+			s.Detail += "，日志: " + logFile
+		}
+		inst.setAppStatus(m, s)
+	}
+
+	inst.mu.Lock()
+	m.run = runInfo{args: finalArgs}
+	inst.mu.Unlock()
+	// 启动失败时同样经由 start_failed 回调处理，无需检查返回的错误
+	internal.StartAppProcess(app.Path, finalArgs, opts, func(status string, pid int, exitErr error) {
+		if status != "running" && stopHealth != nil {
+			close(stopHealth)
+			stopHealth = nil
+		}
+		exitCode := 0
+		if exitErr != nil {
+			if c, ok := internal.ExtractExitCode(exitErr); ok {
+				exitCode = c
+			}
+		}
+
+		switch status {
+		case "start_failed":
+			inst.startFailed(m, internal.NewAppStatus(internal.AppExited, 0, exitCode, "启动失败: "+exitErr.Error()))
+			fmt.Printf("启动应用失败: %v\n", exitErr)
+			logf("启动失败: %v", exitErr)
+			// 可执行文件缺失或被占用（如升级中）时同样按重启策略重试，超过次数后进入“已放弃”
+			inst.scheduleRestart(m, app, 0, true, 0, args)
+		case "running":
+			startedAt = time.Now()
+			inst.setRunning(m, runInfo{pid: pid, args: finalArgs, started: startedAt})
+			fmt.Printf("已启动应用: %s %s (PID=%d)\n", m.target(), app.Path, pid)
+			logf("已启动 %s (PID=%d): %s %s", m.target(), pid, app.Path, strings.Join(finalArgs, " "))
+			if probe == nil {
+				setStatus(internal.NewAppStatus(internal.AppRunning, pid, 0, "运行中"), false)
+				break
+			}
+			setStatus(internal.NewAppStatus(internal.AppStarting, pid, 0, "启动中，等待健康检查通过"), false)
+			stopHealth = inst.watchHealth(m, app, pid, probe, func(main internal.AppMainStatus, detail string) {
+				setStatus(internal.NewAppStatus(main, pid, 0, detail), false)
+				fmt.Printf("[health] %s %s: %s\n", m.target(), main, detail)
+				logf("健康检查 (PID=%d): %s", pid, detail)
+			})
+		case "exited":
+			inst.setPid(m, 0)
+			setStatus(internal.NewAppStatus(internal.AppExited, pid, exitCode, "已退出"), false)
+			fmt.Println("应用已正常退出")
+			logf("进程已退出 (PID=%d)", pid)
+			inst.publishProcessExit(m, pid, exitCode, status)
+			inst.scheduleRestart(m, app, pid, false, time.Since(startedAt), args)
+		case "exit_failed":
+			inst.setPid(m, 0)
+			code := 1
+			if exitCode != 0 {
+				code = exitCode
+			}
+			setStatus(internal.NewAppStatus(internal.AppExited, pid, code, "异常退出"), true)
+			logf("异常退出 (PID=%d, ExitCode=%d): %v", pid, code, exitErr)
+			fmt.Printf("应用异常退出，返回码非0: %v\n", exitErr)
+			inst.publishProcessExit(m, pid, code, status)
+			inst.scheduleRestart(m, app, pid, true, time.Since(startedAt), args)
+		case "killed":
+			inst.setPid(m, 0)
+			code := 1
+			if exitCode != 0 {
+				code = exitCode
+			}
+			setStatus(internal.NewAppStatus(internal.AppExited, pid, code, "被终止"), true)
+			logf("被终止 (PID=%d, ExitCode=%d): %v", pid, code, exitErr)
+			fmt.Printf("应用被信号终止: %v\n", exitErr)
+			inst.publishProcessExit(m, pid, code, status)
+			inst.scheduleRestart(m, app, pid, true, time.Since(startedAt), args)
+		case "crashed":
+			inst.setPid(m, 0)
+			code := 1
+			if exitCode != 0 {
+				code = exitCode
+			}
+			setStatus(internal.NewAppStatus(internal.AppCrashed, pid, code, "已崩溃"), true)
+			logf("已崩溃 (PID=%d, ExitCode=%d): %v", pid, code, exitErr)
+			fmt.Printf("应用崩溃: %v\n", exitErr)
+			inst.publishProcessExit(m, pid, code, status)
+			inst.scheduleRestart(m, app, pid, true, time.Since(startedAt), args)
+		}
+	})
+}
+
+// writerOr 返回 w，w 为 nil 时返回 def
+func writerOr(w, def io.Writer) io.Writer {
+	if w == nil {
+		return def
+	}
+	return w
+}
+
+// runForeground 前台运行激活应用（--exec 模式）：应用直接使用当前终端的标准输入输出，
+// 不启动 socket 服务与配置监听，也不复用已运行的实例，返回应使用的退出码
+func (inst *Instance) runForeground() int {
+	cfg, err := inst.getConfig()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "配置未加载")
+		return 1
+	}
+	appName := inst.getActivate()
+	if _, ok := cfg.LookupApp(appName); !ok {
+		fmt.Fprintln(os.Stderr, "未找到激活应用")
+		return 1
+	}
+	app, err := cfg.PrepareApp(appName, inst.profile)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "启动应用失败: %v\n", err)
+		return 1
+	}
+	args := internal.BuildArgs(appName, app, internal.ArgInputs{CLI: inst.extraArgs}).Argv
+	env, err := internal.ResolveAppEnv(app, cfg.Dir())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "启动应用失败: %v\n", err)
+		return 1
+	}
+	code, err := internal.RunForeground(app.Path, args, internal.ProcessOptions{Env: env.Environ(), Dir: env.Cwd})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "启动应用失败: %v\n", err)
+	}
+	return code
+}
+
+// openHistory 载入状态历史，之后每次状态变化都会记录并写入文件
+func (inst *Instance) openHistory() {
+	cfg, err := inst.getConfig()
+	if err != nil {
+		return
+	}
+	path, err := cfg.HistoryPath()
+	if err != nil {
+		fmt.Printf("[history] %v，状态历史只保存在内存中\n", err)
+	}
+	inst.history = internal.NewHistory(path, cfg.HistorySizeOrDefault())
+}
+
+// getHistory 返回状态历史，name 与 args 的含义同 history 命令
+func (inst *Instance) getHistory(name string, args []string) (internal.HistoryResult, error) {
+	opts, err := internal.ParseHistoryArgs(args)
+	if err != nil {
+		return internal.HistoryResult{}, internal.NewProtocolError(internal.ErrCodeBadRequest, "%v", err)
+	}
+	if name != "" {
+		opts.Name = name
+	}
+	var entries []internal.HistoryEntry
+	if inst.history != nil {
+		entries = inst.history.Entries()
+	}
+	return internal.HistoryResult{Entries: internal.FilterHistory(entries, opts)}, nil
+}
+
+// appLog 返回应用的日志文件，配置关闭日志或打开失败时返回 nil
+func (inst *Instance) appLog(cfg *internal.Config, name string) *internal.AppLog {
+	if cfg.Log.Disable {
+		return nil
+	}
+	path, err := cfg.LogFile(name)
+	if err != nil {
+		fmt.Printf("[log] %v\n", err)
+		return nil
+	}
+	inst.logMu.Lock()
+	defer inst.logMu.Unlock()
+	if lg, ok := inst.logs[path]; ok {
+		return lg
+	}
+	lg, err := internal.OpenAppLog(cfg, name)
+	if err != nil {
+		fmt.Printf("[log] 打开日志文件失败: %v\n", err)
+		return nil
+	}
+	if inst.logs == nil {
+		inst.logs = make(map[string]*internal.AppLog)
+	}
+	inst.logs[path] = lg
+	return lg
+}
+
+// getLogs 读取应用日志的最后若干行，name 为空时为当前运行（或激活）的应用；同时返回读到的位置供 -f 继续跟踪
+func (inst *Instance) getLogs(name string, args []string) (internal.LogsResult, internal.LogsOptions, int64, error) {
+	opts, err := internal.ParseLogsArgs(args)
+	if err != nil {
+		return internal.LogsResult{}, opts, 0, internal.NewProtocolError(internal.ErrCodeBadRequest, "%v", err)
+	}
+	cfg, err := inst.getConfig()
+	if err != nil {
+		return internal.LogsResult{}, opts, 0, err
+	}
+	if name == "" {
+		name = opts.Name
+	}
+	if m := inst.currentManaged(); name == "" && m != nil {
+		name = m.app
+	}
+	if name == "" {
+		name = inst.getActivate()
+	}
+	res, err := cfg.ResolveApp(name)
+	if err != nil {
+		return internal.LogsResult{}, opts, 0, internal.NewProtocolError(internal.ErrCodeNotFound, "%v", err)
+	}
+	if cfg.Log.Disable {
+		return internal.LogsResult{}, opts, 0, internal.NewProtocolError(internal.ErrCodeNotFound, "配置中已关闭日志（log.disable）")
+	}
+	path, err := cfg.LogFile(res.Name)
+	if err != nil {
+		return internal.LogsResult{}, opts, 0, err
+	}
+	result := internal.LogsResult{Name: res.Name, File: path, Lines: []string{}}
+	lines, offset, err := internal.TailLog(path, opts.Tail)
+	if err != nil && !os.IsNotExist(err) {
+		return result, opts, 0, err
+	}
+	if lines != nil {
+		result.Lines = lines
+	}
+	return result, opts, offset, nil
+}
+
+// argInputs 参与参数合并的运行时参数，args 为本次启动传入的参数
+func (inst *Instance) argInputs(args []string) internal.ArgInputs {
+	inst.mu.Lock()
+	defer inst.mu.Unlock()
+	return internal.ArgInputs{
+		Inherited:     inst.lastFoundArgs,
+		InheritedFrom: inst.lastFoundApp,
+		CLI:           inst.extraArgs,
+		Run:           args,
+	}
+}
+
+// dryRun 返回按当前状态启动应用（name 为空时为激活应用）将使用的参数，不实际启动
+func (inst *Instance) dryRun(name string, args []string) (internal.DryRunResult, error) {
+	cfg, err := inst.getConfig()
+	if err != nil {
+		return internal.DryRunResult{}, err
+	}
+	if name == "" {
+		name = inst.getActivate()
+	}
+	res, err := cfg.ResolveApp(name)
+	if err != nil {
+		return internal.DryRunResult{}, internal.NewProtocolError(internal.ErrCodeNotFound, "%v", err)
+	}
+	app, err := cfg.PrepareApp(res.Name, "")
+	if err != nil {
+		return internal.DryRunResult{}, internal.NewProtocolError(internal.ErrCodeBadRequest, "%v", err)
+	}
+	return internal.DryRunResult{
+		Name:    res.Name,
+		Path:    app.Path,
+		ArgPlan: internal.BuildArgs(res.Name, app, inst.argInputs(args)),
+	}, nil
+}
+
+// selectApp 按优先级（环境变量 > 目录版本文件 > activate）选择本次启动的应用，前两者命中时固定该应用
+func (inst *Instance) selectApp() error {
+	cfg, err := inst.getConfig()
+	if err != nil {
+		return err
+	}
+	wd, _ := os.Getwd()
+	sel, err := cfg.SelectApp(wd)
+	if errors.Is(err, internal.ErrNoActivate) {
+		return nil // 未指定任何应用，保持原有行为（不启动）
+	}
+	if err != nil {
+		return err
+	}
+	if sel.Source != internal.SelectGlobal {
+		inst.setPin(&sel)
+		// 输出到 stderr，避免混入前台模式下应用的标准输出
+		fmt.Fprintf(os.Stderr, "[evs] 使用 %s 指定的应用: %s\n", sel.Describe(cfg), sel.Name)
+	}
+	return nil
+}
@@ -10,412 +10,160 @@
 // 3. .\evs.exe list
 //    只列出应用，不启动应用和 socket 服务
 //
-// 只有内置命令（list/add/remove/switch/help）会直接执行并退出，其他参数均作为启动参数传递给激活应用。
+// 4. .\evs.exe --config node.yaml [...]
+//    使用指定配置文件，每个配置文件对应一个独立实例（独立的状态与 socket 地址）
+//
+// 5. .\evs.exe --exec [...]
+//    前台运行激活应用：连接当前终端的输入输出，转发 Ctrl+C/SIGTERM，以应用的退出码退出，不启动 socket 服务
+//
+// 6. 启动的应用按优先级选择：环境变量 EVS_VERSION > 当前目录向上查找到的 .evs-version > 配置中的 activate
+//
+// 7. .\evs.exe run --profile debug [...]
+//    以应用的 debug profile 启动（选择写入配置的 profile 字段，之后的启动沿用）；--exec 模式下只用于本次运行
+//
+// 只有内置命令（run/list/add/remove/switch/local/which/help 等）会直接执行并退出，其他参数均作为启动参数传递给激活应用。
 // ========================
 
 package main
 
 import (
-	"bufio"
 	"fmt"
-	"net"
 	"os"
 	"os/signal"
 	"strings"
 	"syscall"
-	"time"
 
 	"github.com/SSwser/exe-version-selector/internal"
 )
 
-var configPath = "config.yaml" // 全局可用
-var extraArgs []string
-var lastFoundArgs []string // 仅记录 FindProcessByPath 找到的参数（不含exe路径）
-var currentAppPid int
-
-var appStatus = internal.NewAppStatus(internal.AppNotStarted, 0, 0, "初始状态")
-
-var (
-	idleTimer  *time.Timer
-	idleTimerC <-chan time.Time
-)
-
-func setCurrentAppPid(pid int) {
-	currentAppPid = pid
-	if pid == 0 {
-		if idleTimer == nil {
-			idleTimer = time.NewTimer(2 * time.Minute)
-			idleTimerC = idleTimer.C
-			go func() {
-				<-idleTimerC
-				if currentAppPid == 0 {
-					fmt.Println("[evs] 2分钟无应用运行，自动退出")
-					os.Exit(0)
-				}
-			}()
-		}
-	} else {
-		if idleTimer != nil {
-			if !idleTimer.Stop() {
-				<-idleTimer.C // drain
-			}
-			idleTimer = nil
-			idleTimerC = nil
-		}
-	}
-}
-
-func internalGetConfig() (*internal.Config, error) {
-	cfg := internal.GetConfig()
-	if cfg == nil {
-		return nil, fmt.Errorf("ERR config not loaded")
-	}
-	return cfg, nil
-}
-
-func internalGetActivate() string {
-	cfg, err := internalGetConfig()
-	if err != nil {
-		return ""
-	}
-	return cfg.Activate
-}
-
-func internalSwitchActivate(name string) error {
-	cfg, err := internalGetConfig()
-	if err != nil {
-		return err
-	}
-
-	if _, ok := cfg.Apps[name]; !ok {
-		return fmt.Errorf("ERR app not found")
-	}
-
-	// 切换前自动 kill 旧进程
-	// ---
-	// 为什么这里要连续两次“杀进程”？
-	// 1. 先用 KillProcessTree（taskkill /F /T）暴力兜底，确保主进程和所有子进程都被 Windows 强杀，防止有顽固子进程残留。
-	//    但 taskkill 只是发出 kill 命令，主进程可能未必马上消失，或有极端情况未被完全杀掉。
-	// 2. 再用 internalKillCurrentApp（KillProcessTreeAndWait）轮询等待主进程彻底退出（最多5秒），保证主进程真的消失，防止竞态问题。
-	//    但它只检测主进程存活，可能有子进程残留，所以两者结合最大程度保证彻底清理。
-	// 这样做是实际工程中最稳妥的“兜底+确认”组合。
-	if currentAppPid != 0 {
-		fmt.Fprintf(os.Stderr, "[切换应用] 兜底强制终止进程树: PID=%d\n", currentAppPid)
-		_ = internal.KillProcessTree(currentAppPid)
-	}
-	err = internalKillCurrentApp()
-	if err != nil {
-		return err
-	}
+const defaultConfigPath = "config.yaml"
 
-	cfg.Activate = name
-	internal.SaveConfig(cfg, configPath)
-
-	// 不要清空 lastFoundArgs，保证参数全程跟随
-	go runAppProxy(nil)
-	return nil
+// flags core 自身的命令行选项
+type flags struct {
+	configPath string
+	foreground bool
+	profile    string
 }
 
-// 参数 name 为空时返回当前激活应用，否则返回指定应用
-// 返回: name, path, args, error
-func internalGetAppInfo(name string) (string, string, string, error) {
-	appName := name
-	if name == "" {
-		appName = internalGetActivate()
-	}
-
-	cfg, err := internalGetConfig()
-	if err != nil {
-		return "", "", "", err
-	}
-	app, ok := cfg.Apps[appName]
-	if !ok {
-		return "", "", "", fmt.Errorf("ERR app not found")
-	}
-	return appName, app.Path, strings.Join(app.Args, " "), nil
+// parseFlags 解析开头的 --config <path> / --config=<path>、--exec 与 --profile <name>（顺序不限），
+// 返回选项与剩余参数
+func parseFlags(args []string) (f flags, rest []string) {
+	f.configPath = defaultConfigPath
+	for len(args) > 0 {
+		switch {
+		case args[0] == "--exec":
+			f.foreground = true
+			args = args[1:]
+		case args[0] == "--config" && len(args) > 1:
+			f.configPath = args[1]
+			args = args[2:]
+		case strings.HasPrefix(args[0], "--config="):
+			f.configPath = strings.TrimPrefix(args[0], "--config=")
+			args = args[1:]
+		case args[0] == "--profile" && len(args) > 1:
+			f.profile = args[1]
+			args = args[2:]
+		case strings.HasPrefix(args[0], "--profile="):
+			f.profile = strings.TrimPrefix(args[0], "--profile=")
+			args = args[1:]
+		default:
+			return f, args
+		}
+	}
+	return f, args
 }
 
-func internalKillCurrentApp() error {
-	if currentAppPid == 0 {
-		return nil
-	}
-	err := internal.KillProcessTreeAndWait(currentAppPid)
-	if err == nil {
-		appStatus = internal.NewAppStatus(internal.AppExited, currentAppPid, 0, "已终止")
-		setCurrentAppPid(0)
-	} else {
-		appStatus = internal.NewAppStatus(internal.AppExited, currentAppPid, 0, "终止失败")
-	}
-	return err
-}
-
-func runAppProxy(args []string) {
-	cfg, err := internalGetConfig()
-	if err != nil {
-		fmt.Println("配置未加载")
-		return
-	}
-	app, ok := cfg.Apps[cfg.Activate]
-	if !ok {
-		fmt.Println("未找到激活应用")
-		return
-	}
-
-	fmt.Printf("[DEBUG] app.Args: %v\n", app.Args)
-	fmt.Printf("[DEBUG] lastFoundArgs: %v\n", lastFoundArgs)
-	fmt.Printf("[DEBUG] extraArgs: %v\n", extraArgs)
-	fmt.Printf("[DEBUG] runAppProxy args: %v\n", args)
-
-	// 参数合并规则：
-	// 1. app.Args：应用配置文件中的默认参数
-	// 2. lastFoundArgs：启动 evs 时检测到的已运行实例参数（不含 exe 路径，且始终合并，无论切换到哪个 app）
-	// 3. extraArgs：命令行参数（evs.exe 启动时的参数）
-	// 4. args：本次 runAppProxy 传入的参数
-	finalArgs := app.Args
-	for _, group := range [][]string{lastFoundArgs, extraArgs, args} {
-		if len(group) > 0 {
-			finalArgs = internal.MergeArgs(finalArgs, group)
-		}
-	}
-	fmt.Printf("[DEBUG] finalArgs: %v\n", finalArgs)
-
-	_, err = internal.StartAppProcess(app.Path, finalArgs, func(status string, pid int, exitErr error) {
-		exitCode := 0
-		if exitErr != nil {
-			if c, ok := internal.ExtractExitCode(exitErr); ok {
-				exitCode = c
-			}
+func main() {
+	opts, args := parseFlags(os.Args[1:])
+	configPath, foreground := opts.configPath, opts.foreground
+	if !foreground && len(args) > 0 && args[0] == "run" {
+		// run [--profile name] [args...]：与直接运行相同，可以选择 profile
+		profile, rest, err := internal.ParseProfileFlag(args[1:])
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
 		}
-
-		switch status {
-		case "start_failed":
-			appStatus = internal.NewAppStatus(internal.AppExited, 0, exitCode, "启动失败")
-			fmt.Printf("启动应用失败: %v\n", exitErr)
-		case "running":
-			setCurrentAppPid(pid)
-			appStatus = internal.NewAppStatus(internal.AppRunning, pid, 0, "运行中")
-			fmt.Printf("已启动应用: %s (PID=%d)\n", app.Path, pid)
-		case "exited":
-			setCurrentAppPid(0)
-			appStatus = internal.NewAppStatus(internal.AppExited, pid, exitCode, "已退出")
-			fmt.Println("应用已正常退出")
-		case "exit_failed":
-			setCurrentAppPid(0)
-			code := 1
-			if exitCode != 0 {
-				code = exitCode
-			}
-			appStatus = internal.NewAppStatus(internal.AppExited, pid, code, "异常退出")
-			fmt.Printf("应用异常退出，返回码非0: %v\n", exitErr)
-		case "killed":
-			setCurrentAppPid(0)
-			code := 1
-			if exitCode != 0 {
-				code = exitCode
-			}
-			appStatus = internal.NewAppStatus(internal.AppExited, pid, code, "被终止")
-			fmt.Printf("应用被信号终止: %v\n", exitErr)
-		case "crashed":
-			setCurrentAppPid(0)
-			code := 1
-			if exitCode != 0 {
-				code = exitCode
-			}
-			appStatus = internal.NewAppStatus(internal.AppCrashed, pid, code, "已崩溃")
-			fmt.Printf("应用崩溃: %v\n", exitErr)
+		if profile != "" {
+			opts.profile = profile
 		}
-	})
-
-	if err != nil {
-		appStatus = internal.NewAppStatus(internal.AppExited, 0, 0, "启动失败")
-		fmt.Printf("启动应用失败: %v\n", err)
+		args = rest
+	} else if !foreground && len(args) > 0 && internal.HandleCliCommand(args, configPath) {
+		// 内置命令自行读取配置，不要求配置能通过 core 的加载校验（如 validate）；前台模式下参数全部传给应用
 		return
 	}
-}
 
-func startConsoleServer(configPath string) {
-	ln, err := net.Listen("tcp", "127.0.0.1:50505")
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "[console] 启动 socket 失败: %v\n", err)
+	inst := newInstance(configPath)
+	if _, err := inst.config.Reload(); err != nil {
+		fmt.Fprintf(os.Stderr, "加载配置失败: %v\n", err)
 		os.Exit(1)
 	}
-	fmt.Println("[console] 业务服务已启动，监听 127.0.0.1:50505")
-	for {
-		conn, err := ln.Accept()
-		if err != nil {
-			continue
-		}
-		go handleConsoleConn(conn, configPath)
-	}
-}
-
-func handleConsoleConn(conn net.Conn, configPath string) {
-	defer conn.Close()
-	r := bufio.NewReader(conn)
-	cmdLine, err := r.ReadString('\n')
-	if err != nil {
-		return
-	}
-	cmdLine = strings.TrimSpace(cmdLine)
-	args := strings.Split(cmdLine, " ")
-	if len(args) == 0 {
-		conn.Write([]byte("ERR empty command\n"))
-		return
+	if err := inst.selectApp(); err != nil {
+		fmt.Fprintf(os.Stderr, "选择应用失败: %v\n", err)
+		os.Exit(1)
 	}
-	fmt.Printf("[SOCKET] 收到命令: %v\n", args)
 
-	// 支持 command:args 格式
-	cmd := args[0]
-	cmdArg := ""
-	if idx := strings.Index(cmd, ":"); idx != -1 {
-		cmdArg = cmd[idx+1:]
-		cmd = cmd[:idx]
+	inst.extraArgs = args
+	inst.profile = opts.profile
+	if foreground {
+		os.Exit(inst.runForeground())
 	}
-
-	switch cmd {
-	case "activate":
-		conn.Write([]byte(internalGetActivate()))
-	case "status":
-		conn.Write(
-			// 返回详细状态字符串
-			fmt.Appendf(nil, "%s | PID=%d | ExitCode=%d",
-				appStatus.Main.String(),
-				appStatus.Pid,
-				appStatus.ExitCode,
-			),
-		)
-	case "list":
-		cfg, err := internalGetConfig()
-		if err != nil {
-			conn.Write([]byte(err.Error()))
-			return
-		}
-		conn.Write([]byte(strings.Join(cfg.AppOrder, "\n")))
-	case "info":
-		appName, appPath, appArgs, err := internalGetAppInfo(cmdArg)
-		if err != nil {
-			conn.Write([]byte(err.Error()))
-			return
+	if opts.profile != "" {
+		if err := inst.selectProfile(opts.profile); err != nil {
+			fmt.Fprintf(os.Stderr, "选择 profile 失败: %v\n", err)
+			os.Exit(1)
 		}
-		appInfoStr := fmt.Sprintf("%s|||%s|||%s\n", appName, appPath, appArgs)
-		conn.Write([]byte(appInfoStr))
-	case "reload":
-		fmt.Println("[reload]")
-		err := internal.ReloadConfig(configPath)
-		if err != nil {
-			conn.Write([]byte(err.Error()))
-			return
-		}
-		internal.RefreshAllMenuItems() // 刷新托盘菜单（递归 OnRefresh）
-		conn.Write([]byte("OK\n"))
-	case "run":
-		var runArgs []string // 运行当前激活应用，参数透传
-		if cmdArg != "" {
-			runArgs = strings.Fields(cmdArg)
-		}
-
-		go runAppProxy(runArgs)
-		conn.Write([]byte("OK\n"))
-	case "switch":
-		if cmdArg == "" {
-			conn.Write([]byte("ERR need app name\n"))
-			return
-		}
-
-		err := internalSwitchActivate(cmdArg)
-		if err != nil {
-			conn.Write([]byte(err.Error()))
-			return
-		}
-		conn.Write([]byte("OK\n"))
-	case "restart":
-		err := internalKillCurrentApp()
-		if err != nil {
-			conn.Write([]byte(err.Error()))
-			return
-		}
-
-		fmt.Println("[restart] 启动新进程...")
-		go runAppProxy(nil)
-		conn.Write([]byte("OK\n"))
-	case "stop":
-		err := internalKillCurrentApp()
-		if err != nil {
-			conn.Write([]byte(err.Error()))
-			return
-		}
-		fmt.Println("[stop] 已终止")
-		conn.Write([]byte("OK\n"))
-	case "exit":
-		if currentAppPid != 0 {
-			_ = internalKillCurrentApp()
-			setCurrentAppPid(0)
-		}
-		conn.Write([]byte("OK\n"))
-		time.Sleep(50 * time.Millisecond)
-		os.Exit(0)
-	default:
-		conn.Write([]byte("ERR unknown command\n"))
-	}
-}
-
-func main() {
-	if err := internal.ReloadConfig(configPath); err != nil {
-		fmt.Fprintf(os.Stderr, "加载配置失败: %v\n", err)
-		os.Exit(1)
 	}
+	inst.openHistory()
 
 	// 捕捉 SIGINT/SIGTERM，主进程退出时自动 kill 子进程
 	c := make(chan os.Signal, 1)
 	signal.Notify(c, syscall.SIGINT, syscall.SIGTERM)
 	go func() {
 		<-c
-		if currentAppPid != 0 {
-			_ = internal.KillProcessTree(currentAppPid)
+		for _, pid := range inst.runningPids() {
+			_ = internal.KillProcessTree(pid)
 		}
-		os.Exit(0)
+		inst.exitCore(0)
 	}()
 
-	if len(os.Args) > 1 {
-		if internal.HandleCliCommand(os.Args[1:], configPath) {
-			return
-		}
-
-		extraArgs = os.Args[1:]
-	} else {
-		extraArgs = nil
-	}
+	// 先监听控制 socket：地址被占用等失败时在启动应用之前退出
+	ln := inst.listenConsole()
 
 	// 启动应用前判断是否已启动
 	shouldStart := true
 
-	appName, appPath, _, err := internalGetAppInfo("")
+	info, err := inst.getAppInfo("", true)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "获取应用信息失败: %v\n", err)
-		os.Exit(1)
+		inst.exitCore(1)
 	}
-	if appName != "" {
+	if info.Name != "" {
 		// 通过进程路径查找是否有已运行实例
-		if pid, args, found := internal.FindProcessByPath(appPath); found {
+		if pid, args, found := internal.FindProcessByPath(info.Path); found {
 			shouldStart = false
-			appStatus = internal.NewAppStatus(internal.AppRunning, pid, 0, "运行中")
 			fmt.Printf("[DEBUG] FindProcessByPath 原始args: %v\n", args)
+			var foundArgs []string
 			if len(args) > 1 {
 				fmt.Printf("[DEBUG] FindProcessByPath 参数部分: %v\n", args[1:])
-				lastFoundArgs = args[1:] // 只记录参数部分，不含exe路径
+				foundArgs = args[1:] // 只记录参数部分，不含exe路径
 			} else {
 				fmt.Printf("[DEBUG] FindProcessByPath 参数部分: []\n")
-				lastFoundArgs = nil
 			}
-			fmt.Printf("[DEBUG] lastFoundArgs 赋值后: %v\n", lastFoundArgs)
-			setCurrentAppPid(pid)
+			// 控制 socket 此时已在监听，经 mu 写入
+			inst.setFoundArgs(info.Name, foundArgs)
+			fmt.Printf("[DEBUG] inst.lastFoundArgs 赋值后: %v\n", foundArgs)
+			m := inst.primaryManaged(info.Name)
+			inst.setRunning(m, runInfo{pid: pid, args: foundArgs})
+			inst.setAppStatus(m, internal.NewAppStatus(internal.AppRunning, pid, 0, "运行中"))
 		}
 
 		if shouldStart {
-			go runAppProxy(nil)
+			go inst.runAppProxy(nil)
 		}
 
-		// 启动 soc
-		startConsoleServer(configPath)
+		// 监听配置文件变化，启动 socket
+		inst.startConfigWatcher()
+		inst.serveConsole(ln)
 	}
+	inst.exitCore(0)
 }
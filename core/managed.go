@@ -0,0 +1,307 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/SSwser/exe-version-selector/internal"
+)
+
+// 托管实例：core 启动（或启动时检测到）的应用进程。同一应用可以同时运行多个（如 A/B 对比两个版本），
+// 以 <应用名>#<序号> 区分，各自记录状态、重启计数与最近一次启动的信息。
+// 不带目标的 status/stop/restart 作用于默认实例（最近一次以激活应用启动的实例），与单实例时的行为一致。
+// managedApp 除 app/id 外的字段都由 inst.mu 保护
+
+// managedApp 一个托管实例
+type managedApp struct {
+	app     string
+	id      int
+	profile string // run 指定应用时选择的 profile，为空时按配置选择；重启沿用
+
+	pid      int
+	starting bool // 已分配给一次启动而进程尚未运行，期间不会被复用或再次启动
+	status   internal.AppStatus
+	run      runInfo // 最近一次启动的进程，用于补全状态历史
+
+	restartTracker internal.RestartTracker
+	restartSeq     int // 每次手动启动/停止递增，用于取消等待中的自动重启
+	stoppedPid     int // 最近一次主动停止的进程 PID，其退出不触发自动重启
+	unhealthyPid   int // 最近一次因健康检查失败而终止的进程 PID，其退出按异常退出重启
+}
+
+// runInfo 一次应用启动的信息
+type runInfo struct {
+	pid     int // 启动前为 0
+	args    []string
+	started time.Time
+}
+
+// target 返回托管实例标识，如 node18#2
+func (m *managedApp) target() string {
+	return fmt.Sprintf("%s#%d", m.app, m.id)
+}
+
+// logSuffix 日志中区分同一应用的多个实例：#1 不加后缀，与单实例时的日志格式一致
+func (m *managedApp) logSuffix() string {
+	if m.id == 1 {
+		return ""
+	}
+	return fmt.Sprintf("#%d", m.id)
+}
+
+// busy 是否有进程在运行或正在启动，调用方须持有 inst.mu
+func (m *managedApp) busy() bool {
+	return m.pid != 0 || m.starting
+}
+
+func (m *managedApp) statusResult() internal.StatusResult {
+	r := internal.NewStatusResult(m.status)
+	r.App, r.Target = m.app, m.target()
+	return r
+}
+
+// acquireManaged 返回用于启动应用 app 的托管实例并标记为启动中：优先复用该应用空闲的实例（序号最小），否则新建
+func (inst *Instance) acquireManaged(app string) *managedApp {
+	inst.mu.Lock()
+	defer inst.mu.Unlock()
+	return inst.acquireLocked(app)
+}
+
+// acquireLocked 同 acquireManaged，调用方须持有 inst.mu；选择与标记在同一次加锁内完成，并发启动不会分到同一个实例
+func (inst *Instance) acquireLocked(app string) *managedApp {
+	used := make(map[int]bool)
+	var free *managedApp
+	for _, m := range inst.managed {
+		if m.app != app {
+			continue
+		}
+		used[m.id] = true
+		if !m.busy() && (free == nil || m.id < free.id) {
+			free = m
+		}
+	}
+	if free != nil {
+		free.starting = true
+		return free
+	}
+	id := 1
+	for used[id] {
+		id++
+	}
+	m := &managedApp{app: app, id: id, starting: true, status: internal.NewAppStatus(internal.AppNotStarted, 0, 0, "初始状态")}
+	inst.managed = append(inst.managed, m)
+	return m
+}
+
+// primaryManaged 返回以激活应用 app 启动时使用的实例（标记为启动中）并设为默认实例：默认实例属于该应用且空闲时直接复用
+func (inst *Instance) primaryManaged(app string) *managedApp {
+	inst.mu.Lock()
+	defer inst.mu.Unlock()
+	m := inst.current
+	if m == nil || m.app != app || m.busy() {
+		m = inst.acquireLocked(app)
+	}
+	m.starting = true
+	m.profile = ""
+	inst.current = m
+	return m
+}
+
+// reserveManaged 把空闲的实例标记为启动中，实例正在运行或启动时返回 false
+func (inst *Instance) reserveManaged(m *managedApp) bool {
+	inst.mu.Lock()
+	defer inst.mu.Unlock()
+	if m.busy() {
+		return false
+	}
+	m.starting = true
+	return true
+}
+
+// currentManaged 返回默认实例，尚未启动过任何应用时为 nil
+func (inst *Instance) currentManaged() *managedApp {
+	inst.mu.Lock()
+	defer inst.mu.Unlock()
+	return inst.current
+}
+
+// currentStatus 返回默认实例的状态
+func (inst *Instance) currentStatus() internal.StatusResult {
+	inst.mu.Lock()
+	defer inst.mu.Unlock()
+	if inst.current == nil {
+		return internal.NewStatusResult(internal.NewAppStatus(internal.AppNotStarted, 0, 0, "初始状态"))
+	}
+	return inst.current.statusResult()
+}
+
+// managedStatuses 返回全部托管实例的状态（按创建顺序）
+func (inst *Instance) managedStatuses() []internal.StatusResult {
+	inst.mu.Lock()
+	defer inst.mu.Unlock()
+	list := make([]internal.StatusResult, 0, len(inst.managed))
+	for _, m := range inst.managed {
+		list = append(list, m.statusResult())
+	}
+	return list
+}
+
+// runningPids 返回托管实例正在运行的进程
+func (inst *Instance) runningPids() []int {
+	inst.mu.Lock()
+	defer inst.mu.Unlock()
+	var pids []int
+	for _, m := range inst.managed {
+		if m.pid != 0 {
+			pids = append(pids, m.pid)
+		}
+	}
+	return pids
+}
+
+// runningManaged 返回有进程在运行的托管实例
+func (inst *Instance) runningManaged() []*managedApp {
+	inst.mu.Lock()
+	defer inst.mu.Unlock()
+	var list []*managedApp
+	for _, m := range inst.managed {
+		if m.pid != 0 {
+			list = append(list, m)
+		}
+	}
+	return list
+}
+
+// findTargets 解析 status/stop/restart 的目标：空为默认实例（可能为 nil）；app#id 为单个实例；
+// 应用名或版本约束为该应用的全部实例
+func (inst *Instance) findTargets(target string) ([]*managedApp, error) {
+	if target == "" {
+		if m := inst.currentManaged(); m != nil {
+			return []*managedApp{m}, nil
+		}
+		return nil, nil
+	}
+	inst.mu.Lock()
+	for _, m := range inst.managed {
+		if m.target() == target {
+			inst.mu.Unlock()
+			return []*managedApp{m}, nil
+		}
+	}
+	inst.mu.Unlock()
+
+	cfg, err := inst.getConfig()
+	if err != nil {
+		return nil, err
+	}
+	res, err := cfg.ResolveApp(target)
+	if err != nil {
+		return nil, internal.NewProtocolError(internal.ErrCodeNotFound, "%v", err)
+	}
+	inst.mu.Lock()
+	defer inst.mu.Unlock()
+	var list []*managedApp
+	for _, m := range inst.managed {
+		if m.app == res.Name {
+			list = append(list, m)
+		}
+	}
+	if len(list) == 0 {
+		return nil, internal.NewProtocolError(internal.ErrCodeNotFound, "应用 %s 没有托管实例", res.Name)
+	}
+	return list, nil
+}
+
+// getStatus 返回目标的状态，目标匹配到多个实例时要求指定序号
+func (inst *Instance) getStatus(target string) (internal.StatusResult, error) {
+	if target == "" {
+		return inst.currentStatus(), nil
+	}
+	list, err := inst.findTargets(target)
+	if err != nil {
+		return internal.StatusResult{}, err
+	}
+	if len(list) > 1 {
+		names := make([]string, len(list))
+		for i, m := range list {
+			names[i] = m.target()
+		}
+		return internal.StatusResult{}, internal.NewProtocolError(internal.ErrCodeBadRequest, "%s 有多个实例（%s），请指定序号", target, strings.Join(names, "、"))
+	}
+	inst.mu.Lock()
+	defer inst.mu.Unlock()
+	return list[0].statusResult(), nil
+}
+
+// setPid 记录实例的进程，没有任何进程运行时开始空闲计时
+func (inst *Instance) setPid(m *managedApp, pid int) {
+	inst.mu.Lock()
+	m.pid = pid
+	inst.mu.Unlock()
+	inst.updateIdleTimer()
+}
+
+// setRunning 记录实例已启动的进程并结束启动中的标记
+func (inst *Instance) setRunning(m *managedApp, run runInfo) {
+	inst.mu.Lock()
+	m.run, m.pid, m.starting = run, run.pid, false
+	inst.mu.Unlock()
+	inst.updateIdleTimer()
+}
+
+// startFailed 进程运行前启动失败：记录状态后结束启动中的标记，实例可以再次启动
+func (inst *Instance) startFailed(m *managedApp, s internal.AppStatus) {
+	inst.setAppStatus(m, s)
+	inst.mu.Lock()
+	m.starting = false
+	inst.mu.Unlock()
+}
+
+// updateIdleTimer 没有任何托管实例在运行时开始计时，2 分钟后自动退出；有实例运行时取消计时
+func (inst *Instance) updateIdleTimer() {
+	inst.idleMu.Lock()
+	defer inst.idleMu.Unlock()
+	if len(inst.runningManaged()) == 0 {
+		if inst.idleTimer == nil {
+			inst.idleTimer = time.AfterFunc(2*time.Minute, func() {
+				if len(inst.runningManaged()) == 0 {
+					fmt.Println("[evs] 2分钟无应用运行，自动退出")
+					inst.exitCore(0)
+				}
+			})
+		}
+	} else if inst.idleTimer != nil {
+		inst.idleTimer.Stop()
+		inst.idleTimer = nil
+	}
+}
+
+// startNamed 在已有实例之外同时启动应用 spec（应用名或版本约束），不改变激活应用；返回新实例的状态
+func (inst *Instance) startNamed(spec string, args []string, profile string) (internal.StatusResult, error) {
+	cfg, err := inst.getConfig()
+	if err != nil {
+		return internal.StatusResult{}, err
+	}
+	res, err := cfg.ResolveApp(spec)
+	if err != nil {
+		return internal.StatusResult{}, internal.NewProtocolError(internal.ErrCodeNotFound, "%v", err)
+	}
+	if _, err := cfg.PrepareApp(res.Name, profile); err != nil {
+		return internal.StatusResult{}, internal.NewProtocolError(internal.ErrCodeBadRequest, "%v", err)
+	}
+	m := inst.acquireManaged(res.Name)
+	inst.mu.Lock()
+	m.profile = profile
+	result := m.statusResult()
+	inst.mu.Unlock()
+	go inst.startManaged(m, args)
+	return result, nil
+}
+
+// stopAll 停止全部托管实例（exit 时）
+func (inst *Instance) stopAll() {
+	for _, m := range inst.runningManaged() {
+		_ = inst.stopManaged(m)
+	}
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/SSwser/exe-version-selector/internal"
+)
+
+func TestAcquireManagedConcurrent(t *testing.T) {
+	inst := newInstance("")
+	const n = 50
+	got := make([]*managedApp, n)
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			got[i] = inst.acquireManaged("a")
+		}(i)
+	}
+	wg.Wait()
+	seen := make(map[int]bool)
+	for _, m := range got {
+		if seen[m.id] {
+			t.Fatalf("%s 被分配给多次启动", m.target())
+		}
+		seen[m.id] = true
+	}
+
+	// 启动失败后实例空闲，可以再次分配
+	inst.startFailed(got[0], got[0].status)
+	if m := inst.acquireManaged("a"); m != got[0] {
+		t.Errorf("期望复用 %s，得到 %s", got[0].target(), m.target())
+	}
+	if inst.reserveManaged(got[1]) {
+		t.Errorf("%s 正在启动，不应再次标记", got[1].target())
+	}
+}
+
+func TestStartNamedTwice(t *testing.T) {
+	sleep, err := exec.LookPath("sleep")
+	if err != nil {
+		t.Skip("需要 sleep 命令")
+	}
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	src := "activate: a\nlog:\n  disable: true\napps:\n  a:\n    path: " + sleep + "\n    args: [\"30\"]\n"
+	if err := os.WriteFile(path, []byte(src), 0644); err != nil {
+		t.Fatal(err)
+	}
+	inst := newInstance(path)
+	if _, err := inst.config.Reload(); err != nil {
+		t.Fatal(err)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 2; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := inst.startNamed("a", nil, ""); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+	waitRunning := func(want int) []int {
+		t.Helper()
+		deadline := time.Now().Add(5 * time.Second)
+		for {
+			pids := inst.runningPids()
+			if len(pids) == want {
+				return pids
+			}
+			if time.Now().After(deadline) {
+				t.Fatalf("运行中的进程 %v，期望 %d 个", pids, want)
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+	pids := waitRunning(2)
+	if pids[0] == pids[1] {
+		t.Errorf("两个实例的 PID 相同: %d", pids[0])
+	}
+	var targets []string
+	for _, s := range inst.managedStatuses() {
+		targets = append(targets, s.Target)
+	}
+	sort.Strings(targets)
+	if len(targets) != 2 || targets[0] != "a#1" || targets[1] != "a#2" {
+		t.Errorf("托管实例 %v，期望 [a#1 a#2]", targets)
+	}
+
+	inst.stopAll()
+	waitRunning(0)
+}
+
+func TestPinConcurrent(t *testing.T) {
+	// 启动检测、手动切换与控制 socket 的读取并发进行（需配合 -race）
+	inst := newInstance("")
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			inst.setPin(&internal.Selection{Source: internal.SelectEnv, Spec: "a"})
+			inst.setFoundArgs("a", []string{"--port", "1"})
+			inst.setPin(nil)
+		}()
+		go func() {
+			defer wg.Done()
+			inst.getActivate()
+			inst.pinSource()
+			inst.argInputs(nil)
+		}()
+	}
+	wg.Wait()
+	if got := inst.argInputs(nil); got.InheritedFrom != "a" || len(got.Inherited) != 2 {
+		t.Errorf("argInputs = %+v", got)
+	}
+}
@@ -0,0 +1,398 @@
+package internal
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 应用输出日志：core 启动的应用的 stdout/stderr 逐行加时间戳写入 <日志目录>/<应用名>.log，
+// 同时照常输出到 core 自身的控制台。文件超过大小上限时轮转为 <应用名>.log.1、.2 …（数字越大越旧）
+
+// 日志默认值
+const (
+	DefaultLogMaxSizeMB = 10
+	DefaultLogMaxFiles  = 5
+)
+
+// 日志行的时间格式
+const logTimeFormat = "2006-01-02 15:04:05.000"
+
+// LogPolicy 应用输出日志配置
+type LogPolicy struct {
+	Disable   bool   `yaml:"disable,omitempty"`     // 不写日志文件，输出只到 core 控制台
+	Dir       string `yaml:"dir,omitempty"`         // 日志目录，相对路径相对于配置文件目录，默认 <用户配置目录>/evs/logs/<实例名>
+	MaxSizeMB int    `yaml:"max_size_mb,omitempty"` // 单个文件大小上限（MB），默认 10
+	MaxFiles  int    `yaml:"max_files,omitempty"`   // 保留的轮转文件数（不含当前文件），默认 5
+}
+
+// MaxSizeOrDefault 返回单个文件的字节数上限
+func (p LogPolicy) MaxSizeOrDefault() int64 {
+	if p.MaxSizeMB <= 0 {
+		return DefaultLogMaxSizeMB << 20
+	}
+	return int64(p.MaxSizeMB) << 20
+}
+
+// MaxFilesOrDefault 返回保留的轮转文件数
+func (p LogPolicy) MaxFilesOrDefault() int {
+	if p.MaxFiles <= 0 {
+		return DefaultLogMaxFiles
+	}
+	return p.MaxFiles
+}
+
+// LogDir 返回日志目录
+func (c *Config) LogDir() (string, error) {
+	if c.Log.Dir != "" {
+		return resolvePath(os.ExpandEnv(c.Log.Dir), c.Dir()), nil
+	}
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("获取用户配置目录失败: %v", err)
+	}
+	return filepath.Join(dir, "evs", "logs", c.InstanceName()), nil
+}
+
+// LogFile 返回应用当前的日志文件路径
+func (c *Config) LogFile(name string) (string, error) {
+	dir, err := c.LogDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, name+".log"), nil
+}
+
+// AppLog 单个应用的日志文件，同一应用的多次启动共用，写入时按大小轮转
+type AppLog struct {
+	mu       sync.Mutex
+	path     string
+	maxSize  int64
+	maxFiles int
+	f        *os.File
+	size     int64
+}
+
+// OpenAppLog 打开（追加）应用的日志文件
+func OpenAppLog(cfg *Config, name string) (*AppLog, error) {
+	path, err := cfg.LogFile(name)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, err
+	}
+	l := &AppLog{path: path, maxSize: cfg.Log.MaxSizeOrDefault(), maxFiles: cfg.Log.MaxFilesOrDefault()}
+	if err := l.open(); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+// Path 返回当前日志文件路径
+func (l *AppLog) Path() string {
+	return l.path
+}
+
+func (l *AppLog) open() error {
+	f, err := os.OpenFile(l.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+	l.f, l.size = f, fi.Size()
+	return nil
+}
+
+// rotate 依次重命名 .log → .log.1 → .log.2 …，超出保留数量的最旧文件被覆盖
+func (l *AppLog) rotate() error {
+	l.f.Close()
+	for i := l.maxFiles - 1; i >= 1; i-- {
+		os.Rename(l.path+"."+strconv.Itoa(i), l.path+"."+strconv.Itoa(i+1))
+	}
+	if err := os.Rename(l.path, l.path+".1"); err != nil {
+		fmt.Printf("[log] 轮转 %s 失败: %v\n", l.path, err)
+	}
+	return l.open()
+}
+
+// writeLine 写入一行带时间戳的日志，stream 为 out/err/evs
+func (l *AppLog) writeLine(stream string, line []byte) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.f == nil {
+		return
+	}
+	buf := make([]byte, 0, len(logTimeFormat)+len(stream)+len(line)+5)
+	buf = time.Now().AppendFormat(buf, logTimeFormat)
+	buf = append(buf, " ["...)
+	buf = append(buf, stream...)
+	buf = append(buf, "] "...)
+	buf = append(buf, line...)
+	buf = append(buf, '\n')
+	if l.size > 0 && l.size+int64(len(buf)) > l.maxSize {
+		if err := l.rotate(); err != nil {
+			fmt.Printf("[log] 重新打开 %s 失败: %v\n", l.path, err)
+			l.f = nil
+			return
+		}
+	}
+	n, _ := l.f.Write(buf)
+	l.size += int64(n)
+}
+
+// Printf 写入一行 evs 自身的记录（如启动、退出），便于在日志中区分每次运行
+func (l *AppLog) Printf(format string, a ...interface{}) {
+	l.writeLine("evs", []byte(fmt.Sprintf(format, a...)))
+}
+
+// Close 关闭日志文件
+func (l *AppLog) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.f == nil {
+		return nil
+	}
+	err := l.f.Close()
+	l.f = nil
+	return err
+}
+
+// Stream 返回写入日志的 io.Writer：按行加时间戳写入日志，原样写入 echo（可为 nil）。
+// 进程退出后须调用 Flush 写出最后不完整的一行
+func (l *AppLog) Stream(stream string, echo io.Writer) *LogStream {
+	return &LogStream{log: l, stream: stream, echo: echo}
+}
+
+// LogStream 应用的一路输出（stdout 或 stderr）
+type LogStream struct {
+	mu      sync.Mutex
+	log     *AppLog
+	stream  string
+	echo    io.Writer
+	pending []byte
+}
+
+func (s *LogStream) Write(p []byte) (int, error) {
+	if s.echo != nil {
+		s.echo.Write(p)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.pending = append(s.pending, p...)
+	for {
+		i := bytes.IndexByte(s.pending, '\n')
+		if i < 0 {
+			break
+		}
+		s.log.writeLine(s.stream, bytes.TrimSuffix(s.pending[:i], []byte("\r")))
+		s.pending = s.pending[i+1:]
+	}
+	return len(p), nil
+}
+
+// Flush 写出缓存的不完整行
+func (s *LogStream) Flush() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.pending) > 0 {
+		s.log.writeLine(s.stream, s.pending)
+		s.pending = nil
+	}
+}
+
+// TailLog 返回日志文件最后 n 行，同时返回文件当前大小，作为 FollowLog 的起始位置
+func TailLog(path string, n int) ([]string, int64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer f.Close()
+	var lines []string
+	var offset int64
+	r := bufio.NewReader(f)
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			break // 不完整的末行留给 FollowLog
+		}
+		offset += int64(len(line))
+		lines = append(lines, strings.TrimRight(line, "\r\n"))
+		if n >= 0 && len(lines) > n {
+			lines = lines[1:]
+		}
+	}
+	return lines, offset, nil
+}
+
+// 跟踪日志时的轮询间隔
+const logFollowInterval = 300 * time.Millisecond
+
+// FollowLog 从 offset 开始持续读取新写入的行并回调，文件被轮转后读完旧文件剩余内容再从新文件开头继续；
+// stop 关闭或 onLine 返回错误时结束
+func FollowLog(path string, offset int64, stop <-chan struct{}, onLine func(line string) error) error {
+	var f *os.File
+	var fi os.FileInfo
+	defer func() {
+		if f != nil {
+			f.Close()
+		}
+	}()
+	var pending []byte
+	buf := make([]byte, 32*1024)
+	for {
+		if f == nil {
+			if nf, err := os.Open(path); err == nil {
+				f = nf
+				fi, _ = f.Stat()
+				if _, err := f.Seek(offset, io.SeekStart); err != nil {
+					return err
+				}
+			}
+		}
+		// 路径已指向新文件说明发生了轮转
+		rotated := false
+		if cur, err := os.Stat(path); f != nil && err == nil && fi != nil && !os.SameFile(fi, cur) {
+			rotated = true
+		}
+		for f != nil {
+			n, err := f.Read(buf)
+			pending = append(pending, buf[:n]...)
+			for {
+				i := bytes.IndexByte(pending, '\n')
+				if i < 0 {
+					break
+				}
+				if err := onLine(strings.TrimRight(string(pending[:i]), "\r")); err != nil {
+					return err
+				}
+				pending = pending[i+1:]
+			}
+			if n == 0 || err != nil {
+				break
+			}
+		}
+		if rotated {
+			f.Close()
+			f, offset, pending = nil, 0, nil
+			continue
+		}
+		select {
+		case <-stop:
+			return nil
+		case <-time.After(logFollowInterval):
+		}
+	}
+}
+
+// logs 命令默认显示的行数
+const DefaultLogTail = 50
+
+// LogsOptions logs 命令选项：logs [name] [-f] [--tail N]
+type LogsOptions struct {
+	Name   string // 应用名或版本约束，空表示当前应用
+	Follow bool   // 持续输出新写入的行
+	Tail   int    // 先输出最后 N 行
+}
+
+// ParseLogsArgs 解析 logs 命令参数，CLI 与 socket 共用
+func ParseLogsArgs(args []string) (LogsOptions, error) {
+	opts := LogsOptions{Tail: DefaultLogTail}
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		switch {
+		case arg == "-f" || arg == "--follow":
+			opts.Follow = true
+		case arg == "--tail" || arg == "-n":
+			if i+1 >= len(args) {
+				return opts, fmt.Errorf("%s 缺少行数", arg)
+			}
+			i++
+			arg = "--tail=" + args[i]
+			fallthrough
+		case strings.HasPrefix(arg, "--tail="):
+			n, err := strconv.Atoi(strings.TrimPrefix(arg, "--tail="))
+			if err != nil || n < 0 {
+				return opts, fmt.Errorf("无效的行数: %s", strings.TrimPrefix(arg, "--tail="))
+			}
+			opts.Tail = n
+		case strings.HasPrefix(arg, "-"):
+			return opts, fmt.Errorf("未知选项: %s", arg)
+		case opts.Name == "":
+			opts.Name = arg
+		default:
+			return opts, fmt.Errorf("多余的参数: %s", arg)
+		}
+	}
+	return opts, nil
+}
+
+// LogsCommand 执行 logs 命令：直接读取日志文件，不需要 core 在运行。
+// 未指定应用时按 环境变量 > 目录版本文件 > activate 选择
+func LogsCommand(cfg *Config, args []string) bool {
+	opts, err := ParseLogsArgs(args)
+	if err != nil {
+		fmt.Println(err)
+		fmt.Println("用法: logs [name] [-f] [--tail N]")
+		return false
+	}
+	name := opts.Name
+	if name == "" {
+		wd, _ := os.Getwd()
+		sel, err := cfg.SelectApp(wd)
+		if err != nil {
+			fmt.Println(err)
+			return false
+		}
+		name = sel.Name
+	} else {
+		res, err := cfg.ResolveApp(name)
+		if err != nil {
+			fmt.Println(err)
+			return false
+		}
+		name = res.Name
+	}
+	if cfg.Log.Disable {
+		fmt.Println("配置中已关闭日志（log.disable）")
+		return false
+	}
+	path, err := cfg.LogFile(name)
+	if err != nil {
+		fmt.Println(err)
+		return false
+	}
+	lines, offset, err := TailLog(path, opts.Tail)
+	if err != nil {
+		if os.IsNotExist(err) {
+			fmt.Printf("应用 %s 还没有日志（%s）\n", name, path)
+		} else {
+			fmt.Printf("读取日志失败: %v\n", err)
+		}
+		if !opts.Follow {
+			return false
+		}
+	}
+	for _, line := range lines {
+		fmt.Println(line)
+	}
+	if !opts.Follow {
+		return true
+	}
+	err = FollowLog(path, offset, nil, func(line string) error {
+		_, err := fmt.Println(line)
+		return err
+	})
+	return err == nil
+}
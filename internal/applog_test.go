@@ -0,0 +1,66 @@
+package internal
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestAppLogRotate(t *testing.T) {
+	dir := t.TempDir()
+	cfg := &Config{Source: filepath.Join(dir, "config.yaml"), Log: LogPolicy{Dir: "logs", MaxFiles: 2}}
+	lg, err := OpenAppLog(cfg, "app")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer lg.Close()
+	lg.maxSize = 120 // 每个文件 3 行
+
+	out := lg.Stream("out", nil)
+	for i := 0; i < 10; i++ {
+		fmt.Fprintf(out, "line %d\n", i)
+	}
+	fmt.Fprint(out, "partial")
+	out.Flush()
+
+	path := filepath.Join(dir, "logs", "app.log")
+	if lg.Path() != path {
+		t.Fatalf("path = %s", lg.Path())
+	}
+	for _, p := range []string{path, path + ".1", path + ".2"} {
+		if fi, err := os.Stat(p); err != nil || fi.Size() > 120 {
+			t.Fatalf("%s: %v", p, err)
+		}
+	}
+	if _, err := os.Stat(path + ".3"); !os.IsNotExist(err) {
+		t.Fatalf("only 2 rotated files should be kept")
+	}
+
+	lines, offset, err := TailLog(path, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(lines) != 2 || !strings.HasSuffix(lines[0], "[out] line 9") || !strings.HasSuffix(lines[1], "[out] partial") {
+		t.Fatalf("tail = %q", lines)
+	}
+	if fi, _ := os.Stat(path); offset != fi.Size() {
+		t.Fatalf("offset = %d, size %d", offset, fi.Size())
+	}
+}
+
+func TestParseLogsArgs(t *testing.T) {
+	opts, err := ParseLogsArgs([]string{"node18", "-f", "--tail", "5"})
+	if err != nil || opts.Name != "node18" || !opts.Follow || opts.Tail != 5 {
+		t.Fatalf("%+v %v", opts, err)
+	}
+	if opts, _ := ParseLogsArgs(nil); opts.Tail != DefaultLogTail || opts.Follow {
+		t.Fatalf("defaults: %+v", opts)
+	}
+	for _, args := range [][]string{{"--tail"}, {"--tail=-1"}, {"-x"}, {"a", "b"}} {
+		if _, err := ParseLogsArgs(args); err == nil {
+			t.Fatalf("%v: expected error", args)
+		}
+	}
+}
@@ -15,8 +15,16 @@ const (
 	AppExited                          // 已退出
 	AppCrashed                         // 已崩溃
 	AppUnknown                         // 未知
+	AppGaveUp                          // 反复崩溃，已放弃自动重启（终态）
+	AppStarting                        // 进程已启动，健康检查尚未通过
+	AppUnhealthy                       // 进程仍在运行，但健康检查连续失败
 )
 
+// Alive 进程是否仍在运行（运行中、启动中或不健康）
+func (s AppMainStatus) Alive() bool {
+	return s == AppRunning || s == AppStarting || s == AppUnhealthy
+}
+
 func (s AppMainStatus) String() string {
 	switch s {
 	case AppNotStarted:
@@ -29,6 +37,12 @@ func (s AppMainStatus) String() string {
 		return "已崩溃"
 	case AppUnknown:
 		return "未知"
+	case AppGaveUp:
+		return "已放弃"
+	case AppStarting:
+		return "启动中"
+	case AppUnhealthy:
+		return "不健康"
 	default:
 		return "未知"
 	}
@@ -40,6 +54,7 @@ type AppStatus struct {
 	ExitCode  int           // 退出码
 	Detail    string        // 详细描述
 	Timestamp time.Time     // 状态变更时间
+	LogFile   string        // 应用输出的日志文件，未记录日志时为空
 }
 
 // NewAppStatus 构建带当前时间戳的 AppStatus
@@ -64,6 +79,12 @@ func ParseAppStatus(status string) AppMainStatus {
 		return AppCrashed
 	case strings.Contains(status, "运行中"):
 		return AppRunning
+	case strings.Contains(status, "已放弃"):
+		return AppGaveUp
+	case strings.Contains(status, "启动中"):
+		return AppStarting
+	case strings.Contains(status, "不健康"):
+		return AppUnhealthy
 	case strings.Contains(status, "未知"):
 		return AppUnknown
 	default:
@@ -71,23 +92,39 @@ func ParseAppStatus(status string) AppMainStatus {
 	}
 }
 
-// 示例公共业务逻辑（需根据 main.go 实际内容完善）
+// ListApps 打印全部应用，扫描到的应用在手动添加的应用之后单独分组
 func ListApps(cfg *Config) {
 	// 先计算所有 name 的最大宽度
 	maxNameLen := 0
-	for _, name := range cfg.AppOrder {
+	for _, name := range cfg.AllApps() {
 		if l := DisplayWidth(name); l > maxNameLen {
 			maxNameLen = l
 		}
 	}
-	for _, name := range cfg.AppOrder {
-		app := cfg.Apps[name]
-		marker := "   "
-		if name == cfg.Activate {
-			marker = "[*]"
+	printApps := func(names []string) {
+		for _, name := range names {
+			app, _ := cfg.LookupApp(name)
+			marker := "   "
+			if name == cfg.Activate {
+				marker = "[*]"
+			}
+			pad := maxNameLen - DisplayWidth(name)
+			fmt.Printf("%s %s%s  %s\n", marker, name, Spaces(pad), app.Path)
+		}
+	}
+	// 两组均按版本排序（AllApps 的顺序）
+	var manual, scanned []string
+	for _, name := range cfg.AllApps() {
+		if cfg.IsScanned(name) {
+			scanned = append(scanned, name)
+		} else {
+			manual = append(manual, name)
 		}
-		pad := maxNameLen - DisplayWidth(name)
-		fmt.Printf("%s %s%s  %s\n", marker, name, Spaces(pad), app.Path)
+	}
+	printApps(manual)
+	if len(scanned) > 0 {
+		fmt.Println("扫描到的版本:")
+		printApps(scanned)
 	}
 }
 
@@ -115,6 +152,10 @@ func RemoveApp(cfg *Config, args []string) {
 	}
 	name := args[0]
 	if _, ok := cfg.Apps[name]; !ok {
+		if cfg.IsScanned(name) {
+			fmt.Printf("%s 由扫描生成，无法删除，请修改配置中的 scan\n", name)
+			return
+		}
 		fmt.Printf("未找到应用: %s\n", name)
 		return
 	}
@@ -130,26 +171,86 @@ func RemoveApp(cfg *Config, args []string) {
 	fmt.Printf("已删除应用: %s\n", name)
 }
 
-// ShowAppInfo 根据 app name 打印详细信息
+// ShowAppInfo 根据 app name 或版本约束打印详细信息
 func ShowAppInfo(cfg *Config, args []string) {
-	name := ""
+	resolved := false
+	var rest []string
+	for _, arg := range args {
+		if arg == "--resolved" {
+			resolved = true
+		} else {
+			rest = append(rest, arg)
+		}
+	}
+	args = rest
+	spec := ""
 	if len(args) < 1 || args[0] == "" {
-		name = cfg.Activate
-		if name == "" {
+		spec = cfg.Activate
+		if spec == "" {
 			fmt.Println("无激活应用且未指定 name")
 			return
 		}
 	} else {
-		name = args[0]
+		spec = args[0]
 	}
-	app, ok := cfg.Apps[name]
-	if !ok {
-		fmt.Printf("未找到应用: %s\n", name)
+	res, err := cfg.ResolveApp(spec)
+	if err != nil {
+		fmt.Println(err)
 		return
 	}
+	name := res.Name
+	app, _ := cfg.LookupApp(name)
 	fmt.Printf("名称: %s\n", name)
+	if cfg.IsScanned(name) {
+		fmt.Println("来源: 扫描")
+	}
+	if res.Version != "" {
+		fmt.Printf("版本: %s\n", res.Version)
+	}
+	if line := cfg.ResolutionLine(res, len(args) < 1 || args[0] == ""); line != "" {
+		fmt.Printf("解析: %s\n", line)
+	}
 	fmt.Printf("路径: %s\n", app.Path)
 	fmt.Printf("参数: %s\n", joinArgs(app.Args))
+	if names := app.ProfileNames(); len(names) > 0 {
+		current := cfg.ProfileFor(name)
+		if current == "" {
+			current = DefaultProfile
+		}
+		fmt.Printf("profile: %s（当前: %s）\n", strings.Join(names, "、"), current)
+	}
+	if resolved && (app.HasTemplate() || cfg.ProfileFor(name) != "") {
+		expanded, err := cfg.PrepareApp(name, "")
+		if err != nil {
+			fmt.Printf("展开: 失败: %v\n", err)
+		} else {
+			fmt.Printf("展开路径: %s\n", expanded.Path)
+			fmt.Printf("展开参数: %s\n", joinArgs(expanded.Args))
+			app = expanded // 环境与工作目录同样显示应用 profile 后的值
+		}
+	}
+
+	env, err := ResolveAppEnv(app, cfg.Dir())
+	if err != nil {
+		fmt.Printf("环境: 解析失败: %v\n", err)
+		return
+	}
+	cwd := env.Cwd
+	if cwd == "" {
+		cwd = "(当前目录)"
+	}
+	fmt.Printf("工作目录: %s\n", cwd)
+	if env.Inherit {
+		fmt.Println("继承环境: 是")
+	} else {
+		fmt.Println("继承环境: 否")
+	}
+	if len(env.Vars) > 0 {
+		fmt.Println("环境变量:")
+		for _, k := range env.SortedKeys() {
+			fmt.Printf("  %s=%s\n", k, env.Vars[k])
+		}
+	}
 }
 
 func SwitchApp(cfg *Config, args []string) {
@@ -157,11 +258,15 @@ func SwitchApp(cfg *Config, args []string) {
 		fmt.Println("用法: switch <name>")
 		return
 	}
-	name := args[0]
-	if _, ok := cfg.Apps[name]; !ok {
-		fmt.Printf("未找到应用: %s\n", name)
+	res, err := cfg.ResolveApp(args[0])
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	cfg.SetActivate(res)
+	if res.IsConstraint() {
+		fmt.Printf("已切换到应用: %s\n", res)
 		return
 	}
-	cfg.Activate = name
-	fmt.Printf("已切换到应用: %s\n", name)
+	fmt.Printf("已切换到应用: %s\n", res.Name)
 }
@@ -0,0 +1,285 @@
+package internal
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// 启动参数合并：最终参数依次来自以下来源
+//  1. app：配置中的 args
+//  2. inherited：evs 启动时检测到的已运行实例的参数（不含 exe 路径）
+//  3. cli：启动 evs 时命令行传入的参数
+//  4. run：本次 run 请求传入的参数
+// 每个应用可以用 args_policy 控制是否继承、追加还是覆盖、是否按 flag 去重以及继承哪些 flag
+
+// 参数来源
+const (
+	ArgSourceApp       = "app"
+	ArgSourceInherited = "inherited"
+	ArgSourceCLI       = "cli"
+	ArgSourceRun       = "run"
+)
+
+// 继承已运行实例参数的方式
+const (
+	ArgInheritSame   = "same"   // 仅当检测到的实例就是该应用时继承（默认）
+	ArgInheritAlways = "always" // 无论切换到哪个应用都继承
+	ArgInheritNever  = "never"  // 不继承
+)
+
+// 参数合并方式
+const (
+	ArgModeAppend   = "append"   // 依次追加各来源的参数（默认）
+	ArgModeOverride = "override" // 后面的来源非空时替换之前全部来源的参数
+)
+
+// ArgPolicy 应用的参数合并策略
+type ArgPolicy struct {
+	Inherit    string   `yaml:"inherit,omitempty"`     // same（默认）/ always / never
+	Mode       string   `yaml:"mode,omitempty"`        // append（默认）/ override
+	Dedupe     bool     `yaml:"dedupe,omitempty"`      // 同名 flag 只保留最后一次出现
+	Allow      []string `yaml:"allow,omitempty"`       // 只继承这些 flag（如 --port），为空表示不限制
+	Deny       []string `yaml:"deny,omitempty"`        // 不继承这些 flag
+	TakesValue []string `yaml:"takes_value,omitempty"` // 带值的 flag：--key value 中的 value 与 --key 视为一段
+}
+
+// InheritOrDefault 返回继承方式，未配置时为 same
+func (p ArgPolicy) InheritOrDefault() string {
+	if p.Inherit == "" {
+		return ArgInheritSame
+	}
+	return p.Inherit
+}
+
+// ModeOrDefault 返回合并方式，未配置时为 append
+func (p ArgPolicy) ModeOrDefault() string {
+	if p.Mode == "" {
+		return ArgModeAppend
+	}
+	return p.Mode
+}
+
+// ArgInputs 参与合并的运行时参数
+type ArgInputs struct {
+	Inherited     []string // 检测到的已运行实例参数
+	InheritedFrom string   // 检测到的实例对应的应用名
+	CLI           []string
+	Run           []string
+}
+
+// ArgPart 最终参数中的一段：一个 flag（连同其值）或一个位置参数
+type ArgPart struct {
+	Args   []string `json:"args"`
+	Key    string   `json:"key,omitempty"` // flag 键，如 --port；位置参数为空
+	Source string   `json:"source"`        // app / inherited / cli / run
+	Reason string   `json:"reason,omitempty"`
+}
+
+// ArgPlan 参数合并结果：Parts 为最终参数的来源明细，Dropped 为被策略丢弃的部分及原因
+type ArgPlan struct {
+	Argv    []string  `json:"argv"`
+	Parts   []ArgPart `json:"parts"`
+	Dropped []ArgPart `json:"dropped,omitempty"`
+}
+
+// splitArgs 把参数切分为 flag 与位置参数：--key=value 为一段；只有 takesValue 中的 flag
+// 会带上紧跟的下一个参数作为值（--key value），其余 flag 与位置参数各为一段
+func splitArgs(args []string, source string, takesValue []string) []ArgPart {
+	var parts []ArgPart
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if !isFlag(arg) {
+			parts = append(parts, ArgPart{Args: []string{arg}, Source: source})
+			continue
+		}
+		key, _, hasValue := strings.Cut(arg, "=")
+		part := ArgPart{Args: []string{arg}, Key: key, Source: source}
+		if !hasValue && i+1 < len(args) && containsString(takesValue, key) {
+			part.Args = append(part.Args, args[i+1])
+			i++
+		}
+		parts = append(parts, part)
+	}
+	return parts
+}
+
+// isFlag 以 - 开头且不是负数或单独的 -
+func isFlag(arg string) bool {
+	if len(arg) < 2 || arg[0] != '-' {
+		return false
+	}
+	return arg[1] < '0' || arg[1] > '9'
+}
+
+// BuildArgs 按应用的参数策略合并各来源的参数
+func BuildArgs(name string, app App, in ArgInputs) ArgPlan {
+	p := app.ArgsPolicy
+	var plan ArgPlan
+	drop := func(parts []ArgPart, format string, a ...interface{}) {
+		reason := fmt.Sprintf(format, a...)
+		for _, part := range parts {
+			part.Reason = reason
+			plan.Dropped = append(plan.Dropped, part)
+		}
+	}
+
+	inherited := splitArgs(in.Inherited, ArgSourceInherited, p.TakesValue)
+	switch {
+	case len(inherited) == 0:
+	case p.InheritOrDefault() == ArgInheritNever:
+		drop(inherited, "inherit: never")
+		inherited = nil
+	case p.InheritOrDefault() == ArgInheritSame && in.InheritedFrom != name:
+		drop(inherited, "检测到的实例属于应用 %s（inherit: same）", in.InheritedFrom)
+		inherited = nil
+	default:
+		var kept []ArgPart
+		for _, part := range inherited {
+			switch {
+			case part.Key == "" && len(p.Allow) > 0:
+				drop([]ArgPart{part}, "位置参数，不在 allow 列表中")
+			case part.Key != "" && len(p.Allow) > 0 && !containsString(p.Allow, part.Key):
+				drop([]ArgPart{part}, "不在 allow 列表中")
+			case part.Key != "" && containsString(p.Deny, part.Key):
+				drop([]ArgPart{part}, "在 deny 列表中")
+			default:
+				kept = append(kept, part)
+			}
+		}
+		inherited = kept
+	}
+
+	groups := [][]ArgPart{
+		splitArgs(app.Args, ArgSourceApp, p.TakesValue),
+		inherited,
+		splitArgs(in.CLI, ArgSourceCLI, p.TakesValue),
+		splitArgs(in.Run, ArgSourceRun, p.TakesValue),
+	}
+	var parts []ArgPart
+	for _, group := range groups {
+		if len(group) == 0 {
+			continue
+		}
+		if p.ModeOrDefault() == ArgModeOverride && len(parts) > 0 {
+			drop(parts, "被 %s 的参数覆盖（mode: override）", group[0].Source)
+			parts = nil
+		}
+		parts = append(parts, group...)
+	}
+
+	if p.Dedupe {
+		last := make(map[string]int)
+		for i, part := range parts {
+			if part.Key != "" {
+				last[part.Key] = i
+			}
+		}
+		var kept []ArgPart
+		for i, part := range parts {
+			if part.Key != "" && last[part.Key] != i {
+				drop([]ArgPart{part}, "与 %s 的同名 flag 重复（dedupe）", parts[last[part.Key]].Source)
+				continue
+			}
+			kept = append(kept, part)
+		}
+		parts = kept
+	}
+
+	plan.Parts = parts
+	plan.Argv = []string{}
+	for _, part := range parts {
+		plan.Argv = append(plan.Argv, part.Args...)
+	}
+	return plan
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// PrintArgPlan 打印参数合并结果及每一段参数的来源
+func PrintArgPlan(path string, plan ArgPlan) {
+	fmt.Printf("argv: %s\n", strings.TrimSpace(path+" "+joinArgs(plan.Argv)))
+	rows := func(parts []ArgPart) {
+		width := 0
+		for _, part := range parts {
+			if w := DisplayWidth(joinArgs(part.Args)); w > width {
+				width = w
+			}
+		}
+		for _, part := range parts {
+			text := joinArgs(part.Args)
+			line := fmt.Sprintf("  %s%s  %-9s", text, Spaces(width-DisplayWidth(text)), part.Source)
+			if part.Reason != "" {
+				line += "  " + part.Reason
+			}
+			fmt.Println(strings.TrimRight(line, " "))
+		}
+	}
+	if len(plan.Parts) > 0 {
+		fmt.Println("来源:")
+		rows(plan.Parts)
+	}
+	if len(plan.Dropped) > 0 {
+		fmt.Println("已丢弃:")
+		rows(plan.Dropped)
+	}
+}
+
+// DryRunCommand 执行 dry-run 命令：dry-run [name] [-- args...]，显示启动应用时的最终参数及每一段的来源。
+// 与 core 启动时一样检测已运行的实例作为继承参数，-- 之后的参数视为启动 evs 时的命令行参数
+func DryRunCommand(cfg *Config, args []string) bool {
+	spec := ""
+	var cli []string
+	for i, arg := range args {
+		if arg == "--" {
+			cli = args[i+1:]
+			break
+		}
+		if spec != "" {
+			fmt.Println("用法: dry-run [name] [-- args...]")
+			return false
+		}
+		spec = arg
+	}
+	var name string
+	if spec == "" {
+		wd, _ := os.Getwd()
+		sel, err := cfg.SelectApp(wd)
+		if err != nil {
+			fmt.Println(err)
+			return false
+		}
+		name = sel.Name
+	} else {
+		res, err := cfg.ResolveApp(spec)
+		if err != nil {
+			fmt.Println(err)
+			return false
+		}
+		name = res.Name
+	}
+	app, err := cfg.PrepareApp(name, "")
+	if err != nil {
+		fmt.Println(err)
+		return false
+	}
+	in := ArgInputs{CLI: cli}
+	if pid, found, ok := FindProcessByPath(app.Path); ok {
+		fmt.Printf("检测到已运行的实例: PID=%d\n", pid)
+		if len(found) > 1 {
+			in.Inherited, in.InheritedFrom = found[1:], name
+		}
+	}
+	if profile := cfg.ProfileFor(name); profile != "" {
+		fmt.Printf("profile: %s\n", profile)
+	}
+	PrintArgPlan(app.Path, BuildArgs(name, app, in))
+	return true
+}
@@ -0,0 +1,70 @@
+package internal
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSplitArgs(t *testing.T) {
+	cases := []struct {
+		name  string
+		args  []string
+		takes []string
+		want  [][]string
+	}{
+		{name: "flag 后的位置参数独立", args: []string{"--debug", "serve"}, want: [][]string{{"--debug"}, {"serve"}}},
+		{name: "takes_value", args: []string{"--port", "8080", "serve"}, takes: []string{"--port"}, want: [][]string{{"--port", "8080"}, {"serve"}}},
+		{name: "带值的 flag 取负数", args: []string{"-n", "-1"}, takes: []string{"-n"}, want: [][]string{{"-n", "-1"}}},
+		{name: "--key=value", args: []string{"--port=8080", "serve"}, takes: []string{"--port"}, want: [][]string{{"--port=8080"}, {"serve"}}},
+		{name: "末尾缺少值", args: []string{"serve", "--port"}, takes: []string{"--port"}, want: [][]string{{"serve"}, {"--port"}}},
+	}
+	for _, c := range cases {
+		var got [][]string
+		for _, part := range splitArgs(c.args, ArgSourceCLI, c.takes) {
+			got = append(got, part.Args)
+		}
+		if !reflect.DeepEqual(got, c.want) {
+			t.Errorf("%s: got %q; want %q", c.name, got, c.want)
+		}
+	}
+}
+
+func TestBuildArgs(t *testing.T) {
+	// 只有 --port 带值，--debug 后的 serve 是独立的位置参数
+	detected := []string{"--port", "8080", "--debug", "serve", "--log=info"}
+	takes := []string{"--port"}
+	cases := []struct {
+		name    string
+		policy  ArgPolicy
+		from    string
+		cli     []string
+		run     []string
+		want    []string
+		dropped int
+	}{
+		// 默认只继承同一应用的参数，其它应用的参数不会带到切换后的应用
+		{name: "same app", from: "app", want: []string{"-v", "--port", "8080", "--debug", "serve", "--log=info"}},
+		{name: "other app", from: "other", want: []string{"-v"}, dropped: 4},
+		{name: "always", policy: ArgPolicy{Inherit: ArgInheritAlways}, from: "other", want: []string{"-v", "--port", "8080", "--debug", "serve", "--log=info"}},
+		{name: "never", policy: ArgPolicy{Inherit: ArgInheritNever}, from: "app", want: []string{"-v"}, dropped: 4},
+		{name: "allow", policy: ArgPolicy{Allow: []string{"--port"}}, from: "app", want: []string{"-v", "--port", "8080"}, dropped: 3},
+		// deny 只丢弃 flag 本身，不会连带丢弃后面的位置参数
+		{name: "deny", policy: ArgPolicy{Deny: []string{"--debug", "--log"}}, from: "app", want: []string{"-v", "--port", "8080", "serve"}, dropped: 2},
+		{
+			name: "dedupe", policy: ArgPolicy{Dedupe: true}, from: "app",
+			cli: []string{"--port=9090", "-v"}, run: []string{"-n", "-1"},
+			want:    []string{"--debug", "serve", "--log=info", "--port=9090", "-v", "-n", "-1"},
+			dropped: 2,
+		},
+		{name: "override", policy: ArgPolicy{Mode: ArgModeOverride}, from: "app", cli: []string{"--port", "1"}, want: []string{"--port", "1"}, dropped: 5},
+	}
+	app := App{Args: []string{"-v"}}
+	for _, c := range cases {
+		app.ArgsPolicy = c.policy
+		app.ArgsPolicy.TakesValue = takes
+		plan := BuildArgs("app", app, ArgInputs{Inherited: detected, InheritedFrom: c.from, CLI: c.cli, Run: c.run})
+		if !reflect.DeepEqual(plan.Argv, c.want) || len(plan.Dropped) != c.dropped {
+			t.Errorf("%s: argv %q, dropped %+v; want %q, %d dropped", c.name, plan.Argv, plan.Dropped, c.want, c.dropped)
+		}
+	}
+}
@@ -0,0 +1,72 @@
+package internal
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// 控制 socket 认证：core 启动时生成随机令牌写入仅当前用户可读的文件，
+// 每个连接的第一帧必须携带该令牌（JSON: {"cmd":"auth","token":"..."}，旧文本协议: auth:<token>）
+const tokenFileName = "token"
+
+// DefaultTokenPath 返回默认地址的令牌文件路径：<用户配置目录>/evs/token（其它地址见 SocketAddr.TokenPath）
+// Windows 为 %AppData%\evs\token（目录 ACL 仅当前用户可访问），Linux 为 ~/.config/evs/token（0600）
+func DefaultTokenPath() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("获取用户配置目录失败: %v", err)
+	}
+	return filepath.Join(dir, "evs", tokenFileName), nil
+}
+
+// GenerateToken 生成 32 字节随机令牌（hex 编码）
+func GenerateToken() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+// WriteTokenFile 以 0600 权限写入令牌文件（先写临时文件再重命名，避免读到半截内容）
+func WriteTokenFile(path, token string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), tokenFileName+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if err := tmp.Chmod(0600); err != nil {
+		tmp.Close()
+		return err
+	}
+	if _, err := tmp.WriteString(token); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// ReadTokenFile 读取令牌文件
+func ReadTokenFile(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("读取令牌文件失败（core 是否已启动？）: %v", err)
+	}
+	return strings.TrimSpace(string(data)), nil
+}
+
+// TokenEqual 常量时间比较令牌
+func TokenEqual(a, b string) bool {
+	return a != "" && subtle.ConstantTimeCompare([]byte(a), []byte(b)) == 1
+}
@@ -0,0 +1,61 @@
+package internal
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func TestTokenFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "evs", tokenFileName)
+	for _, token := range []string{"first", "second"} { // 第二次覆盖已有的令牌文件
+		if err := WriteTokenFile(path, token); err != nil {
+			t.Fatal(err)
+		}
+		got, err := ReadTokenFile(path)
+		if err != nil || got != token {
+			t.Fatalf("ReadTokenFile = %q, %v; want %q", got, err, token)
+		}
+	}
+	if runtime.GOOS != "windows" { // Windows 依靠目录 ACL，不检查权限位
+		fi, err := os.Stat(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if mode := fi.Mode().Perm(); mode != 0600 {
+			t.Errorf("令牌文件权限 %o，期望 600", mode)
+		}
+	}
+	if _, err := ReadTokenFile(filepath.Join(t.TempDir(), "missing")); err == nil {
+		t.Error("令牌文件不存在: 期望报错")
+	}
+}
+
+func TestTokenEqual(t *testing.T) {
+	token, err := GenerateToken()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(token) != 64 {
+		t.Errorf("令牌长度 %d，期望 64", len(token))
+	}
+	cases := []struct {
+		got  string
+		want bool
+	}{
+		{token, true},
+		{token[:63] + "x", false},
+		{token[:32], false},   // 前缀（长度不同）
+		{token + "00", false}, // 更长
+		{"", false},           // 缺少令牌
+	}
+	for _, c := range cases {
+		if TokenEqual(c.got, token) != c.want {
+			t.Errorf("TokenEqual(%q) = %v", c.got, !c.want)
+		}
+	}
+	if TokenEqual("", "") {
+		t.Error("空令牌不应通过")
+	}
+}
@@ -1,19 +1,35 @@
 package internal
 
-import "fmt"
+import (
+	"fmt"
+	"os"
+)
 
 // 打印帮助信息
 func PrintHelp() {
 	fmt.Println("使用方法：")
-	fmt.Println("  exe-version-selector <command> [args...]")
-	fmt.Println("\n如果不指定命令，将直接运行当前激活的应用")
+	fmt.Println("  exe-version-selector [--config <path>] <command> [args...]")
+	fmt.Println("\n如果不指定命令，将直接运行选中的应用（环境变量 > 目录版本文件 > 激活应用）")
+	fmt.Println("--config 指定配置文件（默认 config.yaml），每个配置文件对应一个独立实例")
+	fmt.Println("--exec 前台运行选中的应用：使用当前终端的输入输出，转发 Ctrl+C/SIGTERM，以应用的退出码退出，不启动 socket 服务")
+	fmt.Println("--profile <name> 使用应用的 profile 启动，选择写入配置（--exec 模式下只用于本次运行），default 恢复默认")
 	fmt.Println("\n可用命令：")
-	fmt.Printf("  %-26s %s\n", "list", "列出所有已配置的应用")
-	fmt.Printf("  %-26s %s\n", "add <name> <path> [args]", "添加新应用，可选指定默认启动参数")
-	fmt.Printf("  %-26s %s\n", "remove <name>", "删除指定应用")
-	fmt.Printf("  %-26s %s\n", "switch <name>", "切换到指定应用")
-	fmt.Printf("  %-26s %s\n", "info <name>", "显示指定应用的详细信息")
-	fmt.Printf("  %-26s %s\n", "help", "显示此帮助信息")
+	fmt.Printf("  %-32s %s\n", "run [--profile name] [args]", "运行选中的应用，与不带命令直接运行相同，可以选择 profile")
+	fmt.Printf("  %-32s %s\n", "list", "列出所有已配置的应用")
+	fmt.Printf("  %-32s %s\n", "add <name> <path> [args]", "添加新应用，可选指定默认启动参数")
+	fmt.Printf("  %-32s %s\n", "remove <name>", "删除指定应用")
+	fmt.Printf("  %-32s %s\n", "switch <name|约束>", "切换到指定应用，或满足版本约束（^18、~3.11、latest）的最高版本")
+	fmt.Printf("  %-32s %s\n", "info <name|约束> [--resolved]", "显示指定应用的详细信息及约束的解析结果，--resolved 同时显示应用 profile 与模板展开后的路径与参数")
+	fmt.Printf("  %-32s %s\n", "local [name|--unset]", "在当前目录写入/删除版本文件（默认 .evs-version），不带参数时显示生效的版本文件")
+	fmt.Printf("  %-32s %s\n", "which [tool]", "显示当前目录下将启动的应用（或工具）及选择来源")
+	fmt.Printf("  %-32s %s\n", "shims [--dir <dir>]", "为应用提供的工具（path 与 tools）生成 shim 脚本")
+	fmt.Printf("  %-32s %s\n", "exec <tool> [args]", "以当前版本运行组内工具（shim 调用此命令）")
+	fmt.Printf("  %-32s %s\n", "logs [name] [-f] [--tail N]", "显示应用的输出日志（默认最后 50 行），-f 持续输出新内容")
+	fmt.Printf("  %-32s %s\n", "history [name] [-n N] [--json]", "显示应用状态变化历史（启动、退出、崩溃、重启等）")
+	fmt.Printf("  %-32s %s\n", "dry-run [name] [-- args]", "显示启动应用时的最终参数及每一段参数的来源，不实际启动")
+	fmt.Printf("  %-32s %s\n", "validate", "校验配置文件（失败时退出码为 1）")
+	fmt.Printf("  %-32s %s\n", "instances", "列出本机正在运行的实例")
+	fmt.Printf("  %-32s %s\n", "help", "显示此帮助信息")
 }
 
 // HandleCliCommand 处理主程序的命令行参数
@@ -24,32 +40,88 @@ func HandleCliCommand(args []string, configPath string) bool {
 	}
 	switch args[0] {
 	case "info":
-		cfg, _ := LoadConfig(configPath)
+		cfg := mustLoadConfig(configPath)
 		ShowAppInfo(cfg, args[1:])
-		return true
 	case "list":
-		cfg, _ := LoadConfig(configPath)
+		cfg := mustLoadConfig(configPath)
 		ListApps(cfg)
-		return true
 	case "add":
-		cfg, _ := LoadConfig(configPath)
-		AddApp(cfg, args[1:])
-		SaveConfig(cfg, configPath)
-		return true
+		updateConfig(configPath, func(cfg *Config) { AddApp(cfg, args[1:]) })
 	case "remove":
-		cfg, _ := LoadConfig(configPath)
-		RemoveApp(cfg, args[1:])
-		SaveConfig(cfg, configPath)
-		return true
+		updateConfig(configPath, func(cfg *Config) { RemoveApp(cfg, args[1:]) })
 	case "switch":
-		cfg, _ := LoadConfig(configPath)
-		SwitchApp(cfg, args[1:])
-		SaveConfig(cfg, configPath)
-		return true
+		updateConfig(configPath, func(cfg *Config) { SwitchApp(cfg, args[1:]) })
+	case "local":
+		cfg := mustLoadConfig(configPath)
+		LocalCommand(cfg, args[1:])
+	case "which":
+		cfg := mustLoadConfig(configPath)
+		if !WhichCommand(cfg, args[1:]) {
+			os.Exit(1)
+		}
+	case "shims":
+		cfg := mustLoadConfig(configPath)
+		if !ShimsCommand(cfg, args[1:]) {
+			os.Exit(1)
+		}
+	case "exec":
+		if len(args) < 2 {
+			fmt.Fprintln(os.Stderr, "用法: exec <tool> [args...]")
+			os.Exit(1)
+		}
+		// shim 调用的命令，错误输出到 stderr 并带 evs 前缀，不混入工具的标准输出
+		cfg, err := LoadConfig(configPath)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "evs: 加载配置失败: %v\n", err)
+			os.Exit(1)
+		}
+		os.Exit(ExecTool(cfg, args[1], args[2:]))
+	case "logs":
+		cfg := mustLoadConfig(configPath)
+		if !LogsCommand(cfg, args[1:]) {
+			os.Exit(1)
+		}
+	case "history":
+		cfg := mustLoadConfig(configPath)
+		if !HistoryCommand(cfg, args[1:]) {
+			os.Exit(1)
+		}
+	case "dry-run":
+		cfg := mustLoadConfig(configPath)
+		if !DryRunCommand(cfg, args[1:]) {
+			os.Exit(1)
+		}
+	case "validate":
+		if !RunValidate(configPath) {
+			os.Exit(1)
+		}
+	case "instances":
+		ListRunningInstances()
 	case "help":
 		PrintHelp()
-		return true
 	default:
 		return false
 	}
+	return true
+}
+
+// mustLoadConfig 加载配置文件，失败时打印原因并以退出码 1 退出
+func mustLoadConfig(configPath string) *Config {
+	cfg, err := LoadConfig(configPath)
+	if err != nil {
+		fmt.Printf("加载配置失败: %v\n", err)
+		os.Exit(1)
+	}
+	return cfg
+}
+
+// updateConfig 加锁修改配置文件（与运行中的 core 互斥），失败时打印原因
+func updateConfig(configPath string, mutate func(cfg *Config)) {
+	_, err := UpdateConfig(configPath, func(cfg *Config) error {
+		mutate(cfg)
+		return nil
+	})
+	if err != nil {
+		fmt.Printf("保存配置失败: %v\n", err)
+	}
 }
@@ -1,38 +1,141 @@
 package internal
 
 import (
+	"bytes"
 	"fmt"
 	"os"
+	"path/filepath"
+	"reflect"
 	"sync"
+	"time"
 
 	"gopkg.in/yaml.v3"
 )
 
 type App struct {
-	Path string   `yaml:"path"`
-	Args []string `yaml:"args"`
+	Path       string             `yaml:"path"`
+	Args       []string           `yaml:"args"`
+	ArgsPolicy ArgPolicy          `yaml:"args_policy,omitempty"` // 可选：与检测到的/命令行传入的参数如何合并
+	Version    string             `yaml:"version,omitempty"`     // 可选：语义化版本，用于排序与 switch ^18 等版本约束
+	Tools      map[string]string  `yaml:"tools,omitempty"`       // 可选：同组的其它工具（工具名 → 可执行文件，相对路径相对于 path 所在目录），用于 shim
+	Stop       StopPolicy         `yaml:"stop,omitempty"`
+	Restart    RestartPolicy      `yaml:"restart,omitempty"`
+	Health     HealthCheck        `yaml:"health,omitempty"`   // 可选：健康检查，见 HealthCheck
+	Profiles   map[string]Profile `yaml:"profiles,omitempty"` // 可选：命名的启动配置，见 Profile
+
+	Env        map[string]string `yaml:"env,omitempty"`         // 额外环境变量，支持 ${VAR} 展开（按父进程环境）
+	EnvFile    string            `yaml:"env_file,omitempty"`    // KEY=VALUE 格式的环境变量文件
+	InheritEnv *bool             `yaml:"inherit_env,omitempty"` // 是否继承父进程环境，默认 true
+	Cwd        string            `yaml:"cwd,omitempty"`         // 工作目录，相对路径相对于配置文件目录
+}
+
+// 停止信号：term/int 先软停止，kill 跳过软停止直接强制终止
+const (
+	StopSignalTerm = "term"
+	StopSignalInt  = "int"
+	StopSignalKill = "kill"
+)
+
+// 默认宽限时间
+const DefaultStopGrace = 5 * time.Second
+
+// StopPolicy 停止策略：先发送软信号（Windows 为关闭请求），等待 Grace 后仍未退出再强制终止进程树
+type StopPolicy struct {
+	Signal string        `yaml:"signal,omitempty"` // term（默认）/ int / kill
+	Grace  time.Duration `yaml:"grace,omitempty"`  // 宽限时间，如 "10s"，默认 5s
+}
+
+// SignalOrDefault 返回软停止信号，未配置时为 term
+func (p StopPolicy) SignalOrDefault() string {
+	if p.Signal == "" {
+		return StopSignalTerm
+	}
+	return p.Signal
+}
+
+// GraceOrDefault 返回宽限时间，未配置时为 DefaultStopGrace
+func (p StopPolicy) GraceOrDefault() time.Duration {
+	if p.Grace <= 0 {
+		return DefaultStopGrace
+	}
+	return p.Grace
 }
 
 type Config struct {
-	Activate string         `yaml:"activate"`
-	Apps     map[string]App `yaml:"apps"`
-	AppOrder []string       `yaml:"-"`
+	Name          string         `yaml:"name,omitempty"` // 实例名，多个 evs 实例（如 node/python）各自一个配置文件时用于区分
+	Activate      string         `yaml:"activate"`
+	ActivateRange string         `yaml:"activate_range,omitempty"` // 通过版本约束切换时记录的约束，如 ^18
+	Profile       string         `yaml:"profile,omitempty"`        // 当前选择的启动配置（应用 profiles 中的名称），空为默认
+	Socket        string         `yaml:"socket,omitempty"`         // 控制 socket 地址，见 ParseSocketAddr
+	LocalFile     string         `yaml:"local_file,omitempty"`     // 目录版本文件名，默认 .evs-version
+	VersionEnv    string         `yaml:"version_env,omitempty"`    // 指定版本的环境变量名，默认 EVS_VERSION
+	ShimsDirPath  string         `yaml:"shims_dir,omitempty"`      // shim 目录，默认 <用户配置目录>/evs/shims
+	Log           LogPolicy      `yaml:"log,omitempty"`            // 应用输出日志
+	HistorySize   int            `yaml:"history_size,omitempty"`   // 保留的状态历史条数，默认 200
+	Apps          map[string]App `yaml:"apps"`
+	Scan          []ScanSource   `yaml:"scan,omitempty"` // 扫描来源，自动发现已安装的版本
+	AppOrder      []string       `yaml:"-"`
+	Source        string         `yaml:"-"` // 实际加载的配置文件路径
+
+	Scanned      map[string]App `yaml:"-"` // 扫描生成的应用，不写回配置文件
+	ScannedOrder []string       `yaml:"-"` // 扫描生成的应用名，按来源顺序、版本号升序
+	scanResults  []scanResult   // 每个扫描来源的结果（与 Scan 一一对应），校验时复用，不再重复扫描
+
+	node   *yaml.Node  // 加载时的文档树，SaveConfig 在其上原地修改以保留注释与格式
+	raw    []byte      // 加载时的原始内容，用于恢复空行
+	indent int         // 原文件缩进宽度
+	crlf   bool        // 原文件是否使用 CRLF 换行
+	base   *configBase // 加载时的文件基线，写回前用于检测并发修改
 }
 
+// 默认实例名
+const DefaultInstanceName = "evs"
 
-var (
-	cachedConfig *Config
-	configMtime  int64
-	configLock   sync.RWMutex
-)
+// InstanceName 返回实例名，未配置时为 evs
+func (c *Config) InstanceName() string {
+	if c.Name == "" {
+		return DefaultInstanceName
+	}
+	return c.Name
+}
 
-func GetConfig() *Config {
-	configLock.RLock()
-	defer configLock.RUnlock()
-	return cachedConfig
+// Dir 返回配置文件所在目录（绝对路径），用于解析相对路径
+func (c *Config) Dir() string {
+	if c.Source == "" {
+		return ""
+	}
+	abs, err := filepath.Abs(c.Source)
+	if err != nil {
+		return filepath.Dir(c.Source)
+	}
+	return filepath.Dir(abs)
 }
 
-func LoadConfig(configPath string) (*Config, error) {
+// ConfigStore 缓存单个配置文件的加载结果，每个 evs 实例持有自己的 ConfigStore
+type ConfigStore struct {
+	path string
+	mu   sync.RWMutex
+	cfg  *Config
+}
+
+// NewConfigStore 创建指定配置文件的缓存（尚未加载，需调用 Reload）
+func NewConfigStore(path string) *ConfigStore {
+	return &ConfigStore{path: path}
+}
+
+// Path 返回配置文件路径
+func (s *ConfigStore) Path() string {
+	return s.path
+}
+
+func (s *ConfigStore) Get() *Config {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.cfg
+}
+
+// configCandidates 配置文件的查找顺序：指定路径，其次为上级目录中的同名文件
+func configCandidates(configPath string) []string {
 	paths := []string{configPath}
 	if wd, err := os.Getwd(); err == nil {
 		parent := wd
@@ -43,54 +146,162 @@ func LoadConfig(configPath string) (*Config, error) {
 			paths = append(paths, parent+string(os.PathSeparator)+configPath)
 		}
 	}
+	return paths
+}
+
+func LoadConfig(configPath string) (*Config, error) {
+	paths := configCandidates(configPath)
 	var data []byte
+	var base *configBase
 	var err error
+	var source string
 	for _, p := range paths {
-		data, err = os.ReadFile(p)
+		data, base, err = readConfigFile(p)
 		if err == nil {
+			source = p
 			break
 		}
 	}
 	if err != nil {
 		return nil, err
 	}
+	// yaml.v3 对 CRLF 下的注释归属处理有误，统一按 LF 解析，保存时再还原
+	crlf := bytes.Contains(data, []byte("\r\n"))
+	data = bytes.ReplaceAll(data, []byte("\r\n"), []byte("\n"))
+	var root yaml.Node
 	var cfg Config
-	err = yaml.Unmarshal(data, &cfg)
+	if err = yaml.Unmarshal(data, &root); err == nil && root.Kind != 0 {
+		err = root.Decode(&cfg)
+	}
 	if err != nil {
 		return nil, fmt.Errorf("配置文件格式错误（%v）: %v", paths, err)
 	}
 	if cfg.Apps == nil {
 		cfg.Apps = make(map[string]App)
 	}
-	cfg.AppOrder = parseAppOrderNode(data)
+	cfg.AppOrder = appOrderFromNode(&root)
+	cfg.Source = source
+	cfg.base = base
+	cfg.scanApps()
+	if root.Kind == yaml.DocumentNode && len(root.Content) > 0 && root.Content[0].Kind == yaml.MappingNode {
+		cfg.node = &root
+		cfg.raw = data
+		cfg.indent = detectIndent(data)
+		cfg.crlf = crlf
+	}
 	return &cfg, nil
 }
 
-func ReloadConfig(configPath string) error {
-	configLock.Lock()
-	defer configLock.Unlock()
-	fi, err := os.Stat(configPath)
+// Reload 配置文件内容自上次加载后有变化时重新加载，校验通过才替换缓存；
+// 未变化时只重新扫描（reload 命令）。返回与旧配置的差异，应用均无变化时返回 nil
+func (s *ConfigStore) Reload() (*ConfigDiff, error) {
+	return s.reload(true)
+}
+
+// reload 同 Reload；rescan 为 false 时文件未变化直接返回，供监听文件变化使用（扫描另按 WatchRescanInterval 执行）
+func (s *ConfigStore) reload(rescan bool) (*ConfigDiff, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.cfg != nil && !s.cfg.Changed() {
+		if !rescan {
+			return nil, nil
+		}
+		return s.rescan(), nil
+	}
+	cfg, err := LoadConfig(s.path)
 	if err != nil {
-		return err
+		return nil, err
+	}
+	findings := ValidateConfig(cfg)
+	if err := findings.Err(); err != nil {
+		return nil, fmt.Errorf("配置校验失败: %v", err)
+	}
+	for _, f := range findings {
+		fmt.Printf("[config] %s: %s\n", cfg.Source, f)
+	}
+	diff := DiffConfig(s.cfg, cfg)
+	s.cfg = cfg
+	return &diff, nil
+}
+
+// Rescan 重新执行扫描（安装或卸载了版本），扫描结果有变化时替换缓存并返回差异
+func (s *ConfigStore) Rescan() *ConfigDiff {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.cfg == nil {
+		return nil
+	}
+	return s.rescan()
+}
+
+// rescan 配置文件未变化时重新执行扫描（安装或卸载了版本），扫描结果有变化时替换缓存。
+// 调用方需持有 s.mu
+func (s *ConfigStore) rescan() *ConfigDiff {
+	if len(s.cfg.Scan) == 0 {
+		return nil
 	}
-	if cachedConfig != nil && fi.ModTime().Unix() == configMtime {
-		return nil // 未变更
+	cfg := *s.cfg
+	cfg.scanApps()
+	diff := DiffConfig(s.cfg, &cfg)
+	if diff.Empty() {
+		return nil
 	}
-	cfg, err := LoadConfig(configPath)
+	s.cfg = &cfg
+	return &diff
+}
+
+// Update 通过 UpdateConfig 加锁修改配置文件，成功后缓存写回后的配置
+func (s *ConfigStore) Update(mutate func(cfg *Config) error) (*Config, error) {
+	cfg, err := UpdateConfig(s.path, mutate)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	cachedConfig = cfg
-	configMtime = fi.ModTime().Unix()
-	return nil
+	s.mu.Lock()
+	s.cfg = cfg
+	s.mu.Unlock()
+	return cfg, nil
 }
 
+// SaveConfig 写回配置文件：在加载时的 yaml.Node 树上只修改有变化的字段，
+// 注释、键顺序（apps 按 AppOrder）、锚点/别名与未知字段保持原样。
+// 写入持有 <config>.lock 并以 rename 原子替换；文件在加载后被其他进程修改时返回 ErrConfigConflict。
+// configPath 与 LoadConfig 一样按候选位置解析，锁定并写入实际找到的文件
 func SaveConfig(cfg *Config, configPath string) error {
-	data, err := yaml.Marshal(cfg)
+	configPath = resolveConfigPath(configPath)
+	unlock, err := lockConfig(configPath)
 	if err != nil {
 		return err
 	}
-	return os.WriteFile(configPath, data, 0644)
+	defer unlock()
+	return saveConfigLocked(cfg, configPath)
+}
+
+// marshal 将当前配置同步到文档树并序列化，未从文件加载的配置从空文档开始
+func (c *Config) marshal() ([]byte, error) {
+	var old Config
+	if c.node == nil {
+		c.node = &yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{{Kind: yaml.MappingNode, Tag: "!!map"}}}
+		c.raw = nil
+	} else if err := c.node.Decode(&old); err != nil {
+		return nil, err
+	}
+	if old.Apps == nil {
+		old.Apps = make(map[string]App)
+	}
+	cur := *c
+	if cur.Apps == nil {
+		cur.Apps = make(map[string]App)
+	}
+
+	root := c.node.Content[0]
+	e := &nodeEditor{root: c.node}
+	if err := e.syncStruct(root, reflect.ValueOf(old), reflect.ValueOf(cur)); err != nil {
+		return nil, err
+	}
+	if idx := mappingIndex(root, "apps"); idx != -1 {
+		reorderMapping(root.Content[idx+1], c.AppOrder)
+	}
+	return encodeDocument(c.node, c.raw, c.indent, c.crlf)
 }
 
 func lastIndexAny(s, chars string) int {
@@ -104,9 +315,8 @@ func lastIndexAny(s, chars string) int {
 	return -1
 }
 
-func parseAppOrderNode(yamlData []byte) []string {
-	var root yaml.Node
-	yaml.Unmarshal(yamlData, &root)
+// appOrderFromNode 按文档中的书写顺序返回 apps 的键
+func appOrderFromNode(root *yaml.Node) []string {
 	if root.Kind != yaml.DocumentNode || len(root.Content) == 0 {
 		return nil
 	}
@@ -0,0 +1,350 @@
+package internal
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// writeTempConfig 写入临时配置文件并加载
+func writeTempConfig(t *testing.T, content string) (*Config, string) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := LoadConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cfg, path
+}
+
+func saveAndRead(t *testing.T, cfg *Config, path string) string {
+	t.Helper()
+	if err := SaveConfig(cfg, path); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+const commentedConfig = `# evs 配置
+name: node
+
+# 当前激活应用
+activate: node18 # 由 switch 修改
+socket: user
+
+apps:
+  # LTS 版本
+  node18:
+    path: /opt/node18/bin/node
+    args: ["--max-old-space-size=4096"]
+
+  # 最新版本
+  node20:
+    path: "/opt/node20/bin/node"
+    args:
+      - --inspect
+    env:
+      NODE_ENV: development
+    my_note: 保留未知字段
+`
+
+func TestSaveConfigUnchangedRoundTrip(t *testing.T) {
+	cfg, path := writeTempConfig(t, commentedConfig)
+	if got := saveAndRead(t, cfg, path); got != commentedConfig {
+		t.Fatalf("round trip changed file:\n%s", got)
+	}
+}
+
+func TestSaveConfigSwitchKeepsComments(t *testing.T) {
+	cfg, path := writeTempConfig(t, commentedConfig)
+	SwitchApp(cfg, []string{"node20"})
+	got := saveAndRead(t, cfg, path)
+	want := strings.Replace(commentedConfig, "activate: node18", "activate: node20", 1)
+	if got != want {
+		t.Fatalf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestSaveConfigEditsOnlyChangedField(t *testing.T) {
+	cfg, path := writeTempConfig(t, commentedConfig)
+	app := cfg.Apps["node18"]
+	app.Args = []string{"--max-old-space-size=8192"}
+	cfg.Apps["node18"] = app
+	got := saveAndRead(t, cfg, path)
+	// 流式列表保持流式，其余内容不变
+	want := strings.Replace(commentedConfig, `args: ["--max-old-space-size=4096"]`, `args: ["--max-old-space-size=8192"]`, 1)
+	if got != want {
+		t.Fatalf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestSaveConfigCustomOrder(t *testing.T) {
+	const src = `activate: zeta
+apps:
+  zeta:
+    path: /bin/zeta
+    args: []
+  # alpha 的注释随条目一起删除
+  alpha:
+    path: /bin/alpha
+    args: []
+  mid:
+    path: /bin/mid
+    args: []
+`
+	cfg, path := writeTempConfig(t, src)
+	RemoveApp(cfg, []string{"alpha"})
+	AddApp(cfg, []string{"beta", "/bin/beta", "-x"})
+	got := saveAndRead(t, cfg, path)
+	want := `activate: zeta
+apps:
+  zeta:
+    path: /bin/zeta
+    args: []
+  mid:
+    path: /bin/mid
+    args: []
+  beta:
+    path: /bin/beta
+    args:
+      - -x
+`
+	if got != want {
+		t.Fatalf("got:\n%s\nwant:\n%s", got, want)
+	}
+
+	reloaded, err := LoadConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if order := []string{"zeta", "mid", "beta"}; !reflect.DeepEqual(reloaded.AppOrder, order) {
+		t.Fatalf("AppOrder = %v, want %v", reloaded.AppOrder, order)
+	}
+}
+
+const anchoredConfig = `activate: a
+defaults: &defaults
+  path: /bin/default
+  args: ["--shared"]
+apps:
+  a:
+    <<: *defaults
+    path: /bin/a
+  b: *defaults
+  c: &c
+    path: /bin/c
+    args: []
+  d: *c
+`
+
+func TestSaveConfigKeepsAnchors(t *testing.T) {
+	cfg, path := writeTempConfig(t, anchoredConfig)
+	SwitchApp(cfg, []string{"b"})
+	got := saveAndRead(t, cfg, path)
+	want := strings.Replace(anchoredConfig, "activate: a", "activate: b", 1)
+	if got != want {
+		t.Fatalf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestSaveConfigEditMergedApp(t *testing.T) {
+	cfg, path := writeTempConfig(t, anchoredConfig)
+	app := cfg.Apps["a"]
+	app.Args = []string{"--own"}
+	cfg.Apps["a"] = app
+	got := saveAndRead(t, cfg, path)
+	// 合并来的字段被覆盖时显式写出，锚点本身不变
+	want := strings.Replace(anchoredConfig, "    path: /bin/a\n", "    path: /bin/a\n    args:\n      - --own\n", 1)
+	if got != want {
+		t.Fatalf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestSaveConfigEditAnchoredApp(t *testing.T) {
+	cfg, path := writeTempConfig(t, anchoredConfig)
+	app := cfg.Apps["c"]
+	app.Path = "/bin/c2"
+	cfg.Apps["c"] = app
+	saveAndRead(t, cfg, path)
+
+	reloaded, err := LoadConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 修改带锚点的条目不能波及引用它的别名
+	if got := reloaded.Apps["c"].Path; got != "/bin/c2" {
+		t.Errorf("c.path = %q, want /bin/c2", got)
+	}
+	if got := reloaded.Apps["d"].Path; got != "/bin/c" {
+		t.Errorf("d.path = %q, want /bin/c", got)
+	}
+	if got := reloaded.Apps["b"].Args; !reflect.DeepEqual(got, []string{"--shared"}) {
+		t.Errorf("b.args = %v, want [--shared]", got)
+	}
+}
+
+func TestSaveConfigNewFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	cfg := &Config{
+		Activate: "b",
+		Apps: map[string]App{
+			"b": {Path: "/bin/b", Args: []string{}},
+			"a": {Path: "/bin/a", Args: []string{"-v"}},
+		},
+		AppOrder: []string{"b", "a"},
+	}
+	saveAndRead(t, cfg, path)
+
+	reloaded, err := LoadConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(reloaded.AppOrder, cfg.AppOrder) {
+		t.Errorf("AppOrder = %v, want %v", reloaded.AppOrder, cfg.AppOrder)
+	}
+	if !reflect.DeepEqual(reloaded.Apps, cfg.Apps) || reloaded.Activate != "b" {
+		t.Errorf("reloaded = %+v", reloaded)
+	}
+}
+
+func TestSaveConfigKeepsCRLF(t *testing.T) {
+	src := strings.ReplaceAll(commentedConfig, "\n", "\r\n")
+	cfg, path := writeTempConfig(t, src)
+	SwitchApp(cfg, []string{"node20"})
+	got := saveAndRead(t, cfg, path)
+	want := strings.Replace(src, "activate: node18", "activate: node20", 1)
+	if got != want {
+		t.Fatalf("got:\n%q\nwant:\n%q", got, want)
+	}
+}
+
+func TestSaveConfigRejectsStaleBase(t *testing.T) {
+	cfg, path := writeTempConfig(t, commentedConfig)
+	// 加载之后被其他进程修改
+	edited := strings.Replace(commentedConfig, "socket: user", "socket: 127.0.0.1:50506", 1)
+	if err := os.WriteFile(path, []byte(edited), 0644); err != nil {
+		t.Fatal(err)
+	}
+	SwitchApp(cfg, []string{"node20"})
+	err := SaveConfig(cfg, path)
+	if !errors.Is(err, ErrConfigConflict) || !IsConfigRetryable(err) {
+		t.Fatalf("err = %v, want ErrConfigConflict", err)
+	}
+	data, _ := os.ReadFile(path)
+	if string(data) != edited {
+		t.Fatalf("stale save overwrote file:\n%s", data)
+	}
+
+	// 保存成功后基线随之更新，可以继续保存
+	cfg, _ = LoadConfig(path)
+	SwitchApp(cfg, []string{"node20"})
+	if err := SaveConfig(cfg, path); err != nil {
+		t.Fatal(err)
+	}
+	SwitchApp(cfg, []string{"node18"})
+	if err := SaveConfig(cfg, path); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestUpdateConfigConcurrent(t *testing.T) {
+	_, path := writeTempConfig(t, commentedConfig)
+	const n = 8
+	var wg sync.WaitGroup
+	errs := make(chan error, n)
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, err := UpdateConfig(path, func(cfg *Config) error {
+				name := fmt.Sprintf("app%d", i)
+				cfg.Apps[name] = App{Path: "/bin/" + name, Args: []string{}}
+				cfg.AppOrder = append(cfg.AppOrder, name)
+				return nil
+			})
+			errs <- err
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cfg, err := LoadConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cfg.Apps) != n+2 {
+		t.Fatalf("got %d apps, want %d (lost update): %v", len(cfg.Apps), n+2, cfg.AppOrder)
+	}
+	// 临时文件都已 rename 或清理
+	entries, _ := os.ReadDir(filepath.Dir(path))
+	for _, e := range entries {
+		if strings.Contains(e.Name(), ".tmp-") {
+			t.Errorf("temp file left behind: %s", e.Name())
+		}
+	}
+}
+
+func TestWriteConfigLocksResolvedFile(t *testing.T) {
+	writers := map[string]func(configPath string) error{
+		"UpdateConfig": func(configPath string) error {
+			_, err := UpdateConfig(configPath, func(cfg *Config) error {
+				SwitchApp(cfg, []string{"node20"})
+				return nil
+			})
+			return err
+		},
+		"SaveConfig": func(configPath string) error {
+			cfg, err := LoadConfig(configPath)
+			if err != nil {
+				return err
+			}
+			SwitchApp(cfg, []string{"node20"})
+			return SaveConfig(cfg, configPath)
+		},
+	}
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+	for name, write := range writers {
+		_, path := writeTempConfig(t, commentedConfig)
+		sub := filepath.Join(filepath.Dir(path), "sub")
+		if err := os.Mkdir(sub, 0755); err != nil {
+			t.Fatal(err)
+		}
+		// 当前目录没有配置文件，LoadConfig 回退到上级目录
+		if err := os.Chdir(sub); err != nil {
+			t.Fatal(err)
+		}
+		if err := write(filepath.Base(path)); err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if _, err := os.Stat(path + ".lock"); err != nil {
+			t.Errorf("%s: 锁文件应位于实际的配置文件旁: %v", name, err)
+		}
+		if entries, _ := os.ReadDir(sub); len(entries) != 0 {
+			t.Errorf("%s: 当前目录下不应产生文件: %v", name, entries)
+		}
+		if cfg, err := LoadConfig(path); err != nil || cfg.Activate != "node20" {
+			t.Errorf("%s: activate 未写回上级目录的配置: %v", name, err)
+		}
+	}
+}
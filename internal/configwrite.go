@@ -0,0 +1,212 @@
+package internal
+
+import (
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// 配置文件写入：<config>.lock 咨询锁 + 基线校验 + 临时文件 rename 原子替换
+// CLI 与 core 都通过 UpdateConfig 做“加锁 → 读取 → 修改 → 写回”，并发修改不会互相覆盖
+
+var (
+	// ErrConfigConflict 配置文件在读取之后被其他进程修改，重新加载后重试即可
+	ErrConfigConflict = errors.New("配置文件已被其他进程修改，请重新加载后重试")
+	// ErrConfigLocked 等待写锁超时，稍后重试即可
+	ErrConfigLocked = errors.New("配置文件正被其他进程写入，请稍后重试")
+)
+
+const (
+	configLockTimeout   = 5 * time.Second
+	configUpdateRetries = 3
+)
+
+// IsConfigRetryable 判断写配置失败是否可以重试（冲突或锁超时）
+func IsConfigRetryable(err error) bool {
+	return errors.Is(err, ErrConfigConflict) || errors.Is(err, ErrConfigLocked)
+}
+
+// configBase 加载时的文件基线（内容哈希），写回前与磁盘上的文件比对
+// 不依赖 mtime：部分文件系统精度只有 1 秒，同一秒内的修改无法区分
+type configBase struct {
+	hash [sha256.Size]byte
+}
+
+func newConfigBase(data []byte) *configBase {
+	return &configBase{hash: sha256.Sum256(data)}
+}
+
+// matches 判断磁盘上的文件内容是否仍与基线一致
+func (b *configBase) matches(path string) (bool, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	return sha256.Sum256(data) == b.hash, nil
+}
+
+// Changed 判断配置文件自加载以来是否被修改（未从文件加载的配置视为已修改）
+func (c *Config) Changed() bool {
+	if c.base == nil || c.Source == "" {
+		return true
+	}
+	ok, err := c.base.matches(c.Source)
+	return err != nil || !ok
+}
+
+// lockConfig 获取配置文件的咨询锁，超时返回 ErrConfigLocked
+func lockConfig(path string) (func(), error) {
+	f, err := os.OpenFile(path+".lock", os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("打开锁文件失败: %v", err)
+	}
+	deadline := time.Now().Add(configLockTimeout)
+	for {
+		ok, err := tryLockFile(f)
+		if err != nil {
+			f.Close()
+			return nil, fmt.Errorf("锁定配置文件失败: %v", err)
+		}
+		if ok {
+			return func() {
+				unlockFile(f)
+				f.Close()
+			}, nil
+		}
+		if time.Now().After(deadline) {
+			f.Close()
+			return nil, fmt.Errorf("%s: %w", path, ErrConfigLocked)
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
+
+// writeFileAtomic 写入同目录下的临时文件并 rename 覆盖目标，读者不会看到写了一半的文件
+func writeFileAtomic(path string, data []byte, perm os.FileMode) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	tmpName := tmp.Name()
+	defer os.Remove(tmpName) // rename 成功后为空操作
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmpName, perm); err != nil {
+		return err
+	}
+	return os.Rename(tmpName, path)
+}
+
+// saveConfigLocked 在已持有锁的前提下写回配置：
+// 目标是加载来源且磁盘内容已变化时返回 ErrConfigConflict；内容未变化时不写文件
+func saveConfigLocked(cfg *Config, path string) error {
+	sameSource := cfg.Source != "" && samePath(path, cfg.Source)
+	if sameSource && cfg.base != nil {
+		ok, err := cfg.base.matches(path)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return fmt.Errorf("%s: %w", path, ErrConfigConflict)
+		}
+	} else if _, err := os.Stat(path); err == nil && cfg.base == nil {
+		// 未从该文件加载的配置不能覆盖已有文件
+		return fmt.Errorf("%s: %w", path, ErrConfigConflict)
+	}
+
+	data, err := cfg.marshal()
+	if err != nil {
+		return err
+	}
+	if sameSource && cfg.base != nil && sha256.Sum256(data) == cfg.base.hash {
+		return nil
+	}
+	perm := os.FileMode(0644)
+	if fi, err := os.Stat(path); err == nil {
+		perm = fi.Mode().Perm()
+	}
+	if err := writeFileAtomic(path, data, perm); err != nil {
+		return err
+	}
+	// 更新基线，同一份配置可以继续修改并保存
+	cfg.base = newConfigBase(data)
+	cfg.Source = path
+	return nil
+}
+
+// UpdateConfig 持有写锁完成“读取 → mutate → 写回”，返回写回后的配置
+// 锁外的修改（如编辑器保存）导致冲突时重新读取并重试，mutate 可能被调用多次。
+// 先按 LoadConfig 的查找顺序确定实际的配置文件再加锁，从不同目录运行的 CLI 与 core 锁同一个文件
+func UpdateConfig(configPath string, mutate func(cfg *Config) error) (*Config, error) {
+	configPath = resolveConfigPath(configPath)
+	unlock, err := lockConfig(configPath)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	for attempt := 0; ; attempt++ {
+		cfg, err := LoadConfig(configPath)
+		if err != nil {
+			return nil, err
+		}
+		if err := mutate(cfg); err != nil {
+			return nil, err
+		}
+		err = saveConfigLocked(cfg, cfg.Source)
+		if errors.Is(err, ErrConfigConflict) && attempt < configUpdateRetries {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		return cfg, nil
+	}
+}
+
+// resolveConfigPath 返回 LoadConfig 实际会读取的配置文件（绝对路径）：configCandidates 中第一个存在的文件，
+// 都不存在时原样返回 configPath
+func resolveConfigPath(configPath string) string {
+	for _, p := range configCandidates(configPath) {
+		if fi, err := os.Stat(p); err == nil && !fi.IsDir() {
+			if abs, err := filepath.Abs(p); err == nil {
+				return abs
+			}
+			return p
+		}
+	}
+	return configPath
+}
+
+func samePath(a, b string) bool {
+	absA, errA := filepath.Abs(a)
+	absB, errB := filepath.Abs(b)
+	if errA != nil || errB != nil {
+		return a == b
+	}
+	return absA == absB
+}
+
+// readConfigFile 读取配置文件内容与基线
+func readConfigFile(path string) ([]byte, *configBase, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	return data, newConfigBase(data), nil
+}
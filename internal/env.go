@@ -0,0 +1,138 @@
+package internal
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+)
+
+// AppEnv 应用解析后的运行环境
+type AppEnv struct {
+	Vars    map[string]string // env_file + env 展开后的变量（不含继承的父进程环境）
+	Inherit bool              // 是否继承父进程环境
+	Cwd     string            // 工作目录，为空表示沿用 evs 当前目录
+}
+
+// ResolveAppEnv 解析应用的 env_file / env / inherit_env / cwd
+// 优先级：父进程环境 < env_file < env；值中的 ${VAR} 按父进程环境展开
+// 相对路径（env_file、cwd）相对于配置文件所在目录
+func ResolveAppEnv(app App, baseDir string) (*AppEnv, error) {
+	res := &AppEnv{
+		Vars:    map[string]string{},
+		Inherit: app.InheritEnv == nil || *app.InheritEnv,
+	}
+	if app.EnvFile != "" {
+		envFile := resolvePath(os.ExpandEnv(app.EnvFile), baseDir)
+		vars, err := ReadEnvFile(envFile)
+		if err != nil {
+			return nil, err
+		}
+		for k, v := range vars {
+			res.Vars[k] = os.ExpandEnv(v)
+		}
+	}
+	for k, v := range app.Env {
+		res.Vars[k] = os.ExpandEnv(v)
+	}
+	if app.Cwd != "" {
+		res.Cwd = resolvePath(os.ExpandEnv(app.Cwd), baseDir)
+	}
+	return res, nil
+}
+
+// Environ 返回传给子进程的完整环境（KEY=VALUE 列表）
+// 未配置任何变量且继承父环境时返回 nil，即 exec 默认行为；不继承时总是返回非 nil（可能为空），
+// 否则 nil 会被当作继承父进程环境
+func (e *AppEnv) Environ() []string {
+	if e.Inherit && len(e.Vars) == 0 {
+		return nil
+	}
+	env := []string{}
+	if e.Inherit {
+		for _, kv := range os.Environ() {
+			key := kv
+			if idx := strings.Index(kv, "="); idx > 0 {
+				key = kv[:idx]
+			}
+			if _, overridden := e.lookup(key); !overridden {
+				env = append(env, kv)
+			}
+		}
+	}
+	for _, k := range e.SortedKeys() {
+		env = append(env, k+"="+e.Vars[k])
+	}
+	return env
+}
+
+// SortedKeys 返回按字母排序的变量名，便于稳定输出
+func (e *AppEnv) SortedKeys() []string {
+	keys := make([]string, 0, len(e.Vars))
+	for k := range e.Vars {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// lookup 查找变量，Windows 下变量名大小写不敏感
+func (e *AppEnv) lookup(key string) (string, bool) {
+	if v, ok := e.Vars[key]; ok {
+		return v, true
+	}
+	if runtime.GOOS == "windows" {
+		for k, v := range e.Vars {
+			if strings.EqualFold(k, key) {
+				return v, true
+			}
+		}
+	}
+	return "", false
+}
+
+// ReadEnvFile 读取 KEY=VALUE 格式的环境变量文件，支持 # 注释、export 前缀与引号
+func ReadEnvFile(path string) (map[string]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("读取 env_file 失败: %v", err)
+	}
+	defer f.Close()
+
+	vars := map[string]string{}
+	scanner := bufio.NewScanner(f)
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		line = strings.TrimPrefix(line, "export ")
+		idx := strings.Index(line, "=")
+		if idx <= 0 {
+			return nil, fmt.Errorf("env_file 格式错误（%s:%d）: %s", path, lineNo, line)
+		}
+		key := strings.TrimSpace(line[:idx])
+		val := strings.TrimSpace(line[idx+1:])
+		if len(val) >= 2 && (val[0] == '"' || val[0] == '\'') && val[len(val)-1] == val[0] {
+			val = val[1 : len(val)-1]
+		}
+		vars[key] = val
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("读取 env_file 失败: %v", err)
+	}
+	return vars, nil
+}
+
+// resolvePath 将相对路径解析为相对 baseDir 的路径
+func resolvePath(p, baseDir string) string {
+	if p == "" || filepath.IsAbs(p) || baseDir == "" {
+		return p
+	}
+	return filepath.Join(baseDir, p)
+}
@@ -0,0 +1,44 @@
+package internal
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestAppEnvEnviron(t *testing.T) {
+	t.Setenv("EVS_TEST_PARENT", "1")
+	no := false
+	cases := []struct {
+		name string
+		app  App
+		want []string // nil 表示继承父进程环境
+	}{
+		{name: "inherit without vars", app: App{}, want: nil},
+		{name: "no inherit without vars", app: App{InheritEnv: &no}, want: []string{}},
+		{name: "no inherit with vars", app: App{InheritEnv: &no, Env: map[string]string{"B": "2", "A": "${EVS_TEST_PARENT}"}}, want: []string{"A=1", "B=2"}},
+	}
+	for _, c := range cases {
+		env, err := ResolveAppEnv(c.app, t.TempDir())
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := env.Environ()
+		if (got == nil) != (c.want == nil) || !reflect.DeepEqual(got, c.want) {
+			t.Errorf("%s: got %#v; want %#v", c.name, got, c.want)
+		}
+	}
+
+	// 继承并覆盖：父进程的同名变量被替换
+	env, _ := ResolveAppEnv(App{Env: map[string]string{"EVS_TEST_PARENT": "2"}}, t.TempDir())
+	count := 0
+	for _, kv := range env.Environ() {
+		if kv == "EVS_TEST_PARENT=2" {
+			count++
+		} else if kv == "EVS_TEST_PARENT=1" {
+			t.Errorf("父进程的 EVS_TEST_PARENT 未被覆盖")
+		}
+	}
+	if count != 1 {
+		t.Errorf("EVS_TEST_PARENT=2 出现 %d 次", count)
+	}
+}
@@ -0,0 +1,114 @@
+package internal
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// 事件类型
+const (
+	EventStatus      = "status"   // 默认托管实例的状态变化，data 为 StatusResult
+	EventActivate    = "activate" // 激活应用变化，data 为 ActivateResult
+	EventReload      = "reload"   // 配置重载，data 为 ReloadEvent
+	EventProcessExit = "exit"     // 应用进程退出，data 为 ProcessExitEvent
+	EventManaged     = "managed"  // 任一托管实例状态变化，data 为 ManagedResult
+	EventLog         = "log"      // logs -f 跟踪到的新日志行，data 为 LogLineEvent，只推送给发起 logs -f 的连接
+)
+
+// Event 服务端推送的事件，与 Response 的区别是包含 event 字段而无 ok 字段
+type Event struct {
+	V     int             `json:"v"`
+	Event string          `json:"event"`
+	Data  json.RawMessage `json:"data,omitempty"`
+	Time  time.Time       `json:"time"`
+}
+
+// ProcessExitEvent 应用进程退出事件
+type ProcessExitEvent struct {
+	Name     string `json:"name"`
+	Pid      int    `json:"pid"`
+	ExitCode int    `json:"exit_code"`
+	Reason   string `json:"reason"`           // exited / exit_failed / killed / crashed
+	Target   string `json:"target,omitempty"` // 托管实例标识
+}
+
+// LogLineEvent log 事件数据
+type LogLineEvent struct {
+	Name string `json:"name"`
+	Line string `json:"line"`
+}
+
+// ReloadEvent reload 事件数据：重载后的应用列表及变化的应用（兼容 ListResult）
+type ReloadEvent struct {
+	Apps     []string `json:"apps"`
+	Scanned  []string `json:"scanned,omitempty"`
+	Activate string   `json:"activate"`
+	Added    []string `json:"added,omitempty"`
+	Removed  []string `json:"removed,omitempty"`
+	Changed  []string `json:"changed,omitempty"`
+}
+
+// SubscribeResult subscribe 命令结果：订阅时刻的状态快照
+type SubscribeResult struct {
+	Status   StatusResult   `json:"status"`
+	Activate string         `json:"activate"`
+	Instance string         `json:"instance,omitempty"` // 实例名（配置中的 name）
+	Managed  []StatusResult `json:"managed,omitempty"`  // 全部托管实例的状态
+}
+
+// 每个订阅者的事件缓冲，写满说明客户端过慢，直接断开由其重连后重新同步
+const eventBufferSize = 64
+
+// EventHub 事件发布/订阅中心
+type EventHub struct {
+	mu   sync.Mutex
+	subs map[chan Event]struct{}
+}
+
+func NewEventHub() *EventHub {
+	return &EventHub{subs: make(map[chan Event]struct{})}
+}
+
+// Subscribe 订阅事件，返回事件通道与取消函数；通道关闭表示订阅已结束
+func (h *EventHub) Subscribe() (<-chan Event, func()) {
+	ch := make(chan Event, eventBufferSize)
+	h.mu.Lock()
+	h.subs[ch] = struct{}{}
+	h.mu.Unlock()
+	return ch, func() { h.remove(ch) }
+}
+
+func (h *EventHub) remove(ch chan Event) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if _, ok := h.subs[ch]; ok {
+		delete(h.subs, ch)
+		close(ch)
+	}
+}
+
+// Publish 向所有订阅者广播事件，缓冲已满的订阅者会被断开
+func (h *EventHub) Publish(event string, data interface{}) {
+	ev := Event{V: ProtocolVersion, Event: event, Time: time.Now()}
+	if data != nil {
+		raw, err := json.Marshal(data)
+		if err != nil {
+			fmt.Printf("[events] 编码事件失败: %v\n", err)
+			return
+		}
+		ev.Data = raw
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for ch := range h.subs {
+		select {
+		case ch <- ev:
+		default:
+			fmt.Println("[events] 订阅者处理过慢，已断开")
+			delete(h.subs, ch)
+			close(ch)
+		}
+	}
+}
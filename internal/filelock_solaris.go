@@ -0,0 +1,25 @@
+package internal
+
+import (
+	"io"
+	"os"
+	"syscall"
+)
+
+// tryLockFile 以非阻塞方式获取排他 fcntl 锁（Solaris 没有 flock），已被占用时返回 false
+func tryLockFile(f *os.File) (bool, error) {
+	lk := syscall.Flock_t{Type: syscall.F_WRLCK, Whence: io.SeekStart}
+	err := syscall.FcntlFlock(f.Fd(), syscall.F_SETLK, &lk)
+	if err == nil {
+		return true, nil
+	}
+	if err == syscall.EAGAIN || err == syscall.EACCES {
+		return false, nil
+	}
+	return false, err
+}
+
+func unlockFile(f *os.File) error {
+	lk := syscall.Flock_t{Type: syscall.F_UNLCK, Whence: io.SeekStart}
+	return syscall.FcntlFlock(f.Fd(), syscall.F_SETLK, &lk)
+}
@@ -0,0 +1,24 @@
+//go:build unix && !solaris
+
+package internal
+
+import (
+	"os"
+	"syscall"
+)
+
+// tryLockFile 以非阻塞方式获取排他 flock，已被占用时返回 false
+func tryLockFile(f *os.File) (bool, error) {
+	err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX|syscall.LOCK_NB)
+	if err == nil {
+		return true, nil
+	}
+	if err == syscall.EWOULDBLOCK {
+		return false, nil
+	}
+	return false, err
+}
+
+func unlockFile(f *os.File) error {
+	return syscall.Flock(int(f.Fd()), syscall.LOCK_UN)
+}
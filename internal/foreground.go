@@ -0,0 +1,68 @@
+package internal
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"os/exec"
+	"os/signal"
+	"syscall"
+)
+
+// 前台（exec）模式：evs 像被代理的程序本身一样运行，供脚本调用。
+// 子进程直接使用当前终端的 stdin/stdout/stderr，evs 收到的 SIGINT/SIGTERM 转发给子进程，
+// 退出码与子进程一致；不启动控制 socket，也不做进程查找与自动重启
+
+// 启动失败时的退出码，与 shell 的约定一致
+const (
+	ExitNotFound      = 127 // 可执行文件不存在
+	ExitCannotExecute = 126 // 存在但无法执行
+)
+
+// RunForeground 前台运行程序直到其退出，返回应使用的退出码；启动失败时同时返回错误
+func RunForeground(path string, args []string, opts ProcessOptions) (int, error) {
+	cmd := exec.Command(path, args...)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	cmd.Env = opts.Env
+	cmd.Dir = opts.Dir
+	// 不调用 PrepareCommand：子进程留在当前进程组，才能作为前台进程读写终端
+
+	sigs := make(chan os.Signal, 4)
+	signal.Notify(sigs, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(sigs)
+
+	if err := cmd.Start(); err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return ExitNotFound, err
+		}
+		return ExitCannotExecute, err
+	}
+
+	done := make(chan error, 1)
+	go func() { done <- cmd.Wait() }()
+	for {
+		select {
+		case sig := <-sigs:
+			forwardSignal(cmd.Process, sig)
+		case err := <-done:
+			return foregroundExitCode(err), nil
+		}
+	}
+}
+
+// foregroundExitCode 子进程的退出码；被信号终止时按 shell 约定返回 128+信号值
+func foregroundExitCode(err error) int {
+	if err == nil {
+		return 0
+	}
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		if status, ok := exitErr.Sys().(syscall.WaitStatus); ok && status.Signaled() {
+			return 128 + int(status.Signal())
+		}
+		if code := exitErr.ExitCode(); code >= 0 {
+			return code
+		}
+	}
+	return 1
+}
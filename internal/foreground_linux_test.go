@@ -0,0 +1,25 @@
+package internal
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRunForegroundExitCode(t *testing.T) {
+	cases := []struct {
+		path string
+		args []string
+		want int
+	}{
+		{"/bin/sh", []string{"-c", "exit 7"}, 7},
+		{"/bin/sh", []string{"-c", "kill -TERM $$"}, 128 + 15},
+		{filepath.Join(t.TempDir(), "missing"), nil, ExitNotFound},
+	}
+	for _, c := range cases {
+		code, _ := RunForeground(c.path, c.args, ProcessOptions{Env: os.Environ()})
+		if code != c.want {
+			t.Errorf("%s %v: exit code %d, want %d", c.path, c.args, code, c.want)
+		}
+	}
+}
@@ -0,0 +1,328 @@
+package internal
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os/exec"
+	"regexp"
+	"sync"
+	"time"
+)
+
+// 健康检查：进程存活不代表应用可用（本地服务可能卡死而进程仍在）。应用的 health 配置一种探测方式：
+//
+//	tcp   能建立到 host:port 的 TCP 连接
+//	http  GET 返回期望的状态码（status，默认 200）
+//	exec  命令在超时前以退出码 0 结束（工作目录与环境变量同应用）
+//	log   应用输出中出现过匹配正则的行（如 "listening on"），适合只会打印“就绪”的应用
+//
+// 配置了 health 的应用启动后为“启动中”，连续 healthy_threshold 次探测成功后为“运行中”；
+// 连续 unhealthy_threshold 次失败后为“不健康”，之后连续成功同样次数恢复“运行中”。
+// start_period 内“启动中”的失败不计数。restart 为 true 时变为“不健康”后终止进程并按重启策略重启
+// （重启模式为 never 时按 on-failure 处理，max_retries 与退避时间照常生效）
+
+// 健康检查默认值
+const (
+	DefaultHealthInterval           = 10 * time.Second
+	DefaultHealthTimeout            = 3 * time.Second
+	DefaultHealthHealthyThreshold   = 1
+	DefaultHealthUnhealthyThreshold = 3
+)
+
+// 健康检查的探测方式
+const (
+	HealthProbeTCP  = "tcp"
+	HealthProbeHTTP = "http"
+	HealthProbeExec = "exec"
+	HealthProbeLog  = "log"
+)
+
+// HealthCheck 应用的健康检查，tcp/http/exec/log 中只能配置一种
+type HealthCheck struct {
+	TCP    string   `yaml:"tcp,omitempty"`    // host:port
+	HTTP   string   `yaml:"http,omitempty"`   // 完整 URL
+	Status int      `yaml:"status,omitempty"` // http 期望的状态码，默认 200
+	Exec   []string `yaml:"exec,omitempty"`   // 命令及参数
+	Log    string   `yaml:"log,omitempty"`    // 正则，匹配应用输出的一行
+
+	Interval           time.Duration `yaml:"interval,omitempty"`            // 探测间隔，默认 10s
+	Timeout            time.Duration `yaml:"timeout,omitempty"`             // 单次探测超时，默认 3s
+	StartPeriod        time.Duration `yaml:"start_period,omitempty"`        // 启动宽限期，期间的失败不计数
+	HealthyThreshold   int           `yaml:"healthy_threshold,omitempty"`   // 连续成功次数，默认 1
+	UnhealthyThreshold int           `yaml:"unhealthy_threshold,omitempty"` // 连续失败次数，默认 3
+	Restart            bool          `yaml:"restart,omitempty"`             // 不健康时重启
+}
+
+// Probes 返回配置了的探测方式
+func (h HealthCheck) Probes() []string {
+	var kinds []string
+	if h.TCP != "" {
+		kinds = append(kinds, HealthProbeTCP)
+	}
+	if h.HTTP != "" {
+		kinds = append(kinds, HealthProbeHTTP)
+	}
+	if len(h.Exec) > 0 {
+		kinds = append(kinds, HealthProbeExec)
+	}
+	if h.Log != "" {
+		kinds = append(kinds, HealthProbeLog)
+	}
+	return kinds
+}
+
+// Enabled 是否配置了健康检查
+func (h HealthCheck) Enabled() bool {
+	return len(h.Probes()) > 0
+}
+
+// IntervalOrDefault 返回探测间隔，未配置时为 DefaultHealthInterval
+func (h HealthCheck) IntervalOrDefault() time.Duration {
+	if h.Interval <= 0 {
+		return DefaultHealthInterval
+	}
+	return h.Interval
+}
+
+// TimeoutOrDefault 返回单次探测超时，未配置时为 DefaultHealthTimeout
+func (h HealthCheck) TimeoutOrDefault() time.Duration {
+	if h.Timeout <= 0 {
+		return DefaultHealthTimeout
+	}
+	return h.Timeout
+}
+
+// StatusOrDefault 返回 http 期望的状态码，未配置时为 200
+func (h HealthCheck) StatusOrDefault() int {
+	if h.Status == 0 {
+		return http.StatusOK
+	}
+	return h.Status
+}
+
+// HealthyThresholdOrDefault 返回判定为健康所需的连续成功次数
+func (h HealthCheck) HealthyThresholdOrDefault() int {
+	if h.HealthyThreshold <= 0 {
+		return DefaultHealthHealthyThreshold
+	}
+	return h.HealthyThreshold
+}
+
+// UnhealthyThresholdOrDefault 返回判定为不健康所需的连续失败次数
+func (h HealthCheck) UnhealthyThresholdOrDefault() int {
+	if h.UnhealthyThreshold <= 0 {
+		return DefaultHealthUnhealthyThreshold
+	}
+	return h.UnhealthyThreshold
+}
+
+// Probe 一次健康探测，返回 nil 表示通过
+type Probe func(ctx context.Context) error
+
+// NewProbe 按健康检查配置构建探测函数；exec 使用 opts 的环境变量与工作目录，log 读取 output 的匹配结果
+func NewProbe(h HealthCheck, opts ProcessOptions, output *OutputMatcher) (Probe, error) {
+	kinds := h.Probes()
+	if len(kinds) != 1 {
+		return nil, fmt.Errorf("健康检查需要且只能配置一种探测方式（tcp/http/exec/log），当前为 %d 种", len(kinds))
+	}
+	switch kinds[0] {
+	case HealthProbeTCP:
+		return func(ctx context.Context) error {
+			var d net.Dialer
+			conn, err := d.DialContext(ctx, "tcp", h.TCP)
+			if err != nil {
+				return err
+			}
+			return conn.Close()
+		}, nil
+	case HealthProbeHTTP:
+		want := h.StatusOrDefault()
+		return func(ctx context.Context) error {
+			req, err := http.NewRequestWithContext(ctx, http.MethodGet, h.HTTP, nil)
+			if err != nil {
+				return err
+			}
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				return err
+			}
+			io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+			resp.Body.Close()
+			if resp.StatusCode != want {
+				return fmt.Errorf("HTTP 状态码 %d，期望 %d", resp.StatusCode, want)
+			}
+			return nil
+		}, nil
+	case HealthProbeExec:
+		return func(ctx context.Context) error {
+			cmd := exec.CommandContext(ctx, h.Exec[0], h.Exec[1:]...)
+			cmd.Env = opts.Env
+			cmd.Dir = opts.Dir
+			out, err := cmd.CombinedOutput()
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			if err != nil {
+				if msg := bytes.TrimSpace(out); len(msg) > 0 {
+					return fmt.Errorf("%v: %s", err, truncateBytes(msg, 200))
+				}
+				return err
+			}
+			return nil
+		}, nil
+	default:
+		if output == nil {
+			return nil, fmt.Errorf("log 探测缺少应用输出")
+		}
+		return func(context.Context) error {
+			if !output.Matched() {
+				return fmt.Errorf("输出中尚未出现匹配 %s 的行", output.re)
+			}
+			return nil
+		}, nil
+	}
+}
+
+// truncateBytes 截断过长的输出，用于状态详情
+func truncateBytes(b []byte, n int) string {
+	if len(b) <= n {
+		return string(b)
+	}
+	return string(b[:n]) + "..."
+}
+
+// OutputMatcher 检查应用输出中是否出现过匹配正则的行，供 log 探测使用
+type OutputMatcher struct {
+	re      *regexp.Regexp
+	mu      sync.Mutex
+	matched bool
+}
+
+// NewOutputMatcher 编译正则，pattern 无效时报错
+func NewOutputMatcher(pattern string) (*OutputMatcher, error) {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("无效的正则 %s: %v", pattern, err)
+	}
+	return &OutputMatcher{re: re}, nil
+}
+
+// Matched 是否已出现匹配的行
+func (o *OutputMatcher) Matched() bool {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return o.matched
+}
+
+// Writer 返回按行匹配后原样写入 next 的 io.Writer，每一路输出（stdout/stderr）各用一个
+func (o *OutputMatcher) Writer(next io.Writer) io.Writer {
+	return &matchWriter{matcher: o, next: next}
+}
+
+func (o *OutputMatcher) matchLine(line []byte) {
+	if o.re.Match(line) {
+		o.mu.Lock()
+		o.matched = true
+		o.mu.Unlock()
+	}
+}
+
+type matchWriter struct {
+	matcher *OutputMatcher
+	next    io.Writer
+	mu      sync.Mutex
+	pending []byte
+}
+
+func (w *matchWriter) Write(p []byte) (int, error) {
+	if w.next != nil {
+		w.next.Write(p)
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.matcher.Matched() {
+		return len(p), nil
+	}
+	w.pending = append(w.pending, p...)
+	for {
+		i := bytes.IndexByte(w.pending, '\n')
+		if i < 0 {
+			break
+		}
+		w.matcher.matchLine(bytes.TrimSuffix(w.pending[:i], []byte("\r")))
+		w.pending = w.pending[i+1:]
+	}
+	// 不完整的行也参与匹配（如不换行的提示符），避免等待永远不会到来的换行
+	if len(w.pending) > 0 {
+		w.matcher.matchLine(w.pending)
+	}
+	return len(p), nil
+}
+
+// Flush 转发给 next（如 LogStream），进程退出后写出最后不完整的一行
+func (w *matchWriter) Flush() {
+	if f, ok := w.next.(interface{ Flush() }); ok {
+		f.Flush()
+	}
+}
+
+// HealthMonitor 按健康检查配置周期探测，健康状态变化时回调 onChange（启动中/运行中/不健康）
+type HealthMonitor struct {
+	check    HealthCheck
+	probe    Probe
+	onChange func(main AppMainStatus, detail string)
+}
+
+// NewHealthMonitor 创建健康监控，初始状态为“启动中”
+func NewHealthMonitor(h HealthCheck, probe Probe, onChange func(main AppMainStatus, detail string)) *HealthMonitor {
+	return &HealthMonitor{check: h, probe: probe, onChange: onChange}
+}
+
+// Run 立即探测一次，之后每隔 interval 探测，直到 stop 关闭
+func (m *HealthMonitor) Run(stop <-chan struct{}) {
+	started := time.Now()
+	state := AppStarting
+	successes, failures := 0, 0
+	healthy, unhealthy := m.check.HealthyThresholdOrDefault(), m.check.UnhealthyThresholdOrDefault()
+
+	ticker := time.NewTicker(m.check.IntervalOrDefault())
+	defer ticker.Stop()
+	for {
+		ctx, cancel := context.WithTimeout(context.Background(), m.check.TimeoutOrDefault())
+		err := m.probe(ctx)
+		cancel()
+		select {
+		case <-stop:
+			return // 探测期间进程已退出，结果作废
+		default:
+		}
+
+		if err == nil {
+			failures = 0
+			successes++
+			if state != AppRunning && successes >= healthy {
+				state = AppRunning
+				m.onChange(state, "健康检查通过")
+			}
+		} else {
+			successes = 0
+			if state != AppStarting || time.Since(started) >= m.check.StartPeriod {
+				failures++
+			}
+			if state != AppUnhealthy && failures >= unhealthy {
+				state = AppUnhealthy
+				m.onChange(state, fmt.Sprintf("健康检查连续 %d 次失败: %v", failures, err))
+			}
+		}
+
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
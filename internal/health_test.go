@@ -0,0 +1,201 @@
+package internal
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func runProbe(t *testing.T, h HealthCheck, output *OutputMatcher) error {
+	t.Helper()
+	probe, err := NewProbe(h, ProcessOptions{}, output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	return probe(ctx)
+}
+
+func TestHealthProbes(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := ln.Addr().String()
+	if err := runProbe(t, HealthCheck{TCP: addr}, nil); err != nil {
+		t.Errorf("tcp 监听中: %v", err)
+	}
+	ln.Close()
+	if err := runProbe(t, HealthCheck{TCP: addr}, nil); err == nil {
+		t.Error("tcp 已关闭: 期望失败")
+	}
+
+	var code atomic.Int32
+	code.Store(http.StatusOK)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(int(code.Load()))
+	}))
+	defer srv.Close()
+	if err := runProbe(t, HealthCheck{HTTP: srv.URL + "/healthz"}, nil); err != nil {
+		t.Errorf("http 200: %v", err)
+	}
+	code.Store(http.StatusServiceUnavailable)
+	if err := runProbe(t, HealthCheck{HTTP: srv.URL}, nil); err == nil {
+		t.Error("http 503: 期望失败")
+	}
+	if err := runProbe(t, HealthCheck{HTTP: srv.URL, Status: http.StatusServiceUnavailable}, nil); err != nil {
+		t.Errorf("http 期望 503: %v", err)
+	}
+
+	// exec 以测试二进制本身作为命令：不匹配任何测试时退出码为 0，未知 flag 时非 0
+	exe, err := os.Executable()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := runProbe(t, HealthCheck{Exec: []string{exe, "-test.run=^$"}}, nil); err != nil {
+		t.Errorf("exec 成功: %v", err)
+	}
+	if err := runProbe(t, HealthCheck{Exec: []string{exe, "-no-such-flag"}}, nil); err == nil {
+		t.Error("exec 失败: 期望失败")
+	}
+
+	output, err := NewOutputMatcher(`listening on :\d+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := output.Writer(nil)
+	fmt.Fprint(w, "starting...\nlisten")
+	if err := runProbe(t, HealthCheck{Log: `listening`}, output); err == nil {
+		t.Error("log 未匹配: 期望失败")
+	}
+	fmt.Fprint(w, "ing on :8080\n")
+	if err := runProbe(t, HealthCheck{Log: `listening`}, output); err != nil {
+		t.Errorf("log 跨多次写入的行: %v", err)
+	}
+
+	if _, err := NewProbe(HealthCheck{TCP: addr, HTTP: srv.URL}, ProcessOptions{}, nil); err == nil {
+		t.Error("配置两种探测方式: 期望报错")
+	}
+}
+
+func TestHealthMonitor(t *testing.T) {
+	var healthy atomic.Bool
+	probe := func(context.Context) error {
+		if healthy.Load() {
+			return nil
+		}
+		return errors.New("down")
+	}
+	changes := make(chan AppMainStatus, 10)
+	h := HealthCheck{Log: "x", Interval: 5 * time.Millisecond, HealthyThreshold: 2, UnhealthyThreshold: 3}
+	m := NewHealthMonitor(h, probe, func(main AppMainStatus, detail string) { changes <- main })
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		m.Run(stop)
+		close(done)
+	}()
+
+	expect := func(want AppMainStatus) {
+		t.Helper()
+		select {
+		case got := <-changes:
+			if got != want {
+				t.Fatalf("状态 = %s，期望 %s", got, want)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatalf("等待状态 %s 超时", want)
+		}
+	}
+	expect(AppUnhealthy) // 启动后一直失败
+	healthy.Store(true)
+	expect(AppRunning)
+	healthy.Store(false)
+	expect(AppUnhealthy)
+	close(stop)
+	<-done
+}
+
+func TestHealthMonitorStartPeriod(t *testing.T) {
+	changes := make(chan AppMainStatus, 10)
+	h := HealthCheck{Log: "x", Interval: 5 * time.Millisecond, UnhealthyThreshold: 1, StartPeriod: time.Hour}
+	m := NewHealthMonitor(h, func(context.Context) error { return errors.New("down") }, func(main AppMainStatus, detail string) { changes <- main })
+	stop := make(chan struct{})
+	go m.Run(stop)
+	defer close(stop)
+	select {
+	case got := <-changes:
+		t.Fatalf("启动宽限期内状态变为 %s", got)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestValidateHealth(t *testing.T) {
+	exe, err := os.Executable()
+	if err != nil {
+		t.Fatal(err)
+	}
+	src := `activate: a
+apps:
+  a:
+    path: ` + exe + `
+    health:
+      http: localhost:8080/healthz
+      status: 42
+  b:
+    path: ` + exe + `
+    health:
+      tcp: 127.0.0.1:80
+      log: "("
+      interval: 1s
+      timeout: 2s
+  c:
+    path: ` + exe + `
+    health:
+      timeout: 1s
+  d:
+    path: ` + exe + `
+    health:
+      exec: [curl, -f, http://127.0.0.1/]
+      restart: true
+`
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(src), 0644); err != nil {
+		t.Fatal(err)
+	}
+	findings, err := ValidateConfigFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := make(map[string]Severity)
+	for _, f := range findings {
+		got[f.Field] = f.Severity
+	}
+	want := map[string]Severity{
+		"apps.a.health.http":    SeverityError,
+		"apps.a.health.status":  SeverityError,
+		"apps.b.health":         SeverityError,
+		"apps.b.health.log":     SeverityError,
+		"apps.b.health.timeout": SeverityWarning,
+		"apps.c.health":         SeverityError,
+	}
+	for field, sev := range want {
+		if got[field] != sev {
+			t.Errorf("%s: got %q; want %s", field, got[field], sev)
+		}
+	}
+	for field := range got {
+		if _, ok := want[field]; !ok {
+			t.Errorf("意外的问题: %s", field)
+		}
+	}
+}
@@ -0,0 +1,256 @@
+package internal
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 状态历史：core 记录每一次 AppStatus 变化，保留最近 history_size 条（环形），
+// 每次变化后写入 <用户配置目录>/evs/history/<实例名>.json，core 重启或崩溃后仍可查看
+
+// 默认保留的历史条数
+const DefaultHistorySize = 200
+
+// HistoryEntry 一次状态变化
+type HistoryEntry struct {
+	Time       time.Time     `json:"time"`
+	App        string        `json:"app"`
+	Target     string        `json:"target,omitempty"` // 托管实例标识（app#id）
+	Main       AppMainStatus `json:"main"`
+	Status     string        `json:"status"` // 主状态文本
+	Pid        int           `json:"pid"`
+	ExitCode   int           `json:"exit_code"`
+	DurationMs int64         `json:"duration_ms"` // 进程自启动以来的运行时长，未启动时为 0
+	Args       []string      `json:"args"`        // 本次启动最终使用的参数
+	Detail     string        `json:"detail,omitempty"`
+}
+
+// Duration 返回运行时长
+func (e HistoryEntry) Duration() time.Duration {
+	return time.Duration(e.DurationMs) * time.Millisecond
+}
+
+// HistorySizeOrDefault 返回保留的历史条数
+func (c *Config) HistorySizeOrDefault() int {
+	if c.HistorySize <= 0 {
+		return DefaultHistorySize
+	}
+	return c.HistorySize
+}
+
+// HistoryPath 返回实例的状态历史文件
+func (c *Config) HistoryPath() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("获取用户配置目录失败: %v", err)
+	}
+	return filepath.Join(dir, "evs", "history", c.InstanceName()+".json"), nil
+}
+
+// History 容量固定的状态历史，最旧的记录被覆盖
+type History struct {
+	mu      sync.Mutex
+	path    string // 持久化文件，空表示只保存在内存中
+	size    int
+	entries []HistoryEntry // 按时间升序
+}
+
+// NewHistory 创建状态历史并载入 path 中已有的记录
+func NewHistory(path string, size int) *History {
+	h := &History{path: path, size: size}
+	if path != "" {
+		if entries, err := ReadHistory(path); err == nil {
+			h.entries = entries
+			h.trim()
+		} else if !os.IsNotExist(err) {
+			fmt.Printf("[history] 读取 %s 失败: %v\n", path, err)
+		}
+	}
+	return h
+}
+
+func (h *History) trim() {
+	if over := len(h.entries) - h.size; over > 0 {
+		h.entries = append([]HistoryEntry(nil), h.entries[over:]...)
+	}
+}
+
+// Resize 调整容量（配置重载时）
+func (h *History) Resize(size int) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.size = size
+	h.trim()
+}
+
+// Add 追加一条记录并写入文件
+func (h *History) Add(e HistoryEntry) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.entries = append(h.entries, e)
+	h.trim()
+	if h.path == "" {
+		return
+	}
+	data, err := json.MarshalIndent(h.entries, "", "  ")
+	if err == nil {
+		if err = os.MkdirAll(filepath.Dir(h.path), 0700); err == nil {
+			err = writeFileAtomic(h.path, data, 0600)
+		}
+	}
+	if err != nil {
+		fmt.Printf("[history] 写入 %s 失败: %v\n", h.path, err)
+	}
+}
+
+// Entries 返回记录的副本（按时间升序）
+func (h *History) Entries() []HistoryEntry {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return append([]HistoryEntry(nil), h.entries...)
+}
+
+// ReadHistory 读取持久化的状态历史
+func ReadHistory(path string) ([]HistoryEntry, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var entries []HistoryEntry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, fmt.Errorf("%s 格式错误: %v", path, err)
+	}
+	return entries, nil
+}
+
+// HistoryOptions history 命令选项：history [name] [-n N] [--json]
+type HistoryOptions struct {
+	Name  string // 只显示该应用（或托管实例 app#id）的记录
+	Limit int    // 只显示最近 N 条，0 为全部
+	JSON  bool   // 输出 JSON
+}
+
+// ParseHistoryArgs 解析 history 命令参数，CLI 与 socket 共用
+func ParseHistoryArgs(args []string) (HistoryOptions, error) {
+	var opts HistoryOptions
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		switch {
+		case arg == "--json":
+			opts.JSON = true
+		case arg == "-n":
+			if i+1 >= len(args) {
+				return opts, fmt.Errorf("-n 缺少条数")
+			}
+			i++
+			n, err := strconv.Atoi(args[i])
+			if err != nil || n < 0 {
+				return opts, fmt.Errorf("无效的条数: %s", args[i])
+			}
+			opts.Limit = n
+		case strings.HasPrefix(arg, "-"):
+			return opts, fmt.Errorf("未知选项: %s", arg)
+		case opts.Name == "":
+			opts.Name = arg
+		default:
+			return opts, fmt.Errorf("多余的参数: %s", arg)
+		}
+	}
+	return opts, nil
+}
+
+// FilterHistory 按应用名与条数筛选记录
+func FilterHistory(entries []HistoryEntry, opts HistoryOptions) []HistoryEntry {
+	out := []HistoryEntry{}
+	for _, e := range entries {
+		if opts.Name == "" || e.App == opts.Name || e.Target == opts.Name {
+			out = append(out, e)
+		}
+	}
+	if opts.Limit > 0 && len(out) > opts.Limit {
+		out = out[len(out)-opts.Limit:]
+	}
+	return out
+}
+
+// PrintHistory 以表格输出状态历史
+func PrintHistory(entries []HistoryEntry) {
+	if len(entries) == 0 {
+		fmt.Println("没有状态历史")
+		return
+	}
+	header := []string{"时间", "应用", "状态", "PID", "退出码", "时长", "参数", "详情"}
+	rows := [][]string{header}
+	for _, e := range entries {
+		app := e.App
+		if e.Target != "" {
+			app = e.Target // 同一应用可能同时运行多个实例
+		}
+		duration := "-"
+		if e.DurationMs > 0 {
+			duration = e.Duration().Round(time.Second / 10).String()
+		}
+		rows = append(rows, []string{
+			e.Time.Local().Format("01-02 15:04:05"),
+			app,
+			e.Status,
+			strconv.Itoa(e.Pid),
+			strconv.Itoa(e.ExitCode),
+			duration,
+			joinArgs(e.Args),
+			e.Detail,
+		})
+	}
+	widths := make([]int, len(header))
+	for _, row := range rows {
+		for i, cell := range row {
+			if w := DisplayWidth(cell); w > widths[i] {
+				widths[i] = w
+			}
+		}
+	}
+	for _, row := range rows {
+		var b strings.Builder
+		for i, cell := range row {
+			b.WriteString(cell)
+			if i < len(row)-1 {
+				b.WriteString(Spaces(widths[i] - DisplayWidth(cell) + 2))
+			}
+		}
+		fmt.Println(strings.TrimRight(b.String(), " "))
+	}
+}
+
+// HistoryCommand 执行 history 命令：直接读取持久化的历史文件，core 未运行时也可用
+func HistoryCommand(cfg *Config, args []string) bool {
+	opts, err := ParseHistoryArgs(args)
+	if err != nil {
+		fmt.Println(err)
+		fmt.Println("用法: history [name] [-n N] [--json]")
+		return false
+	}
+	path, err := cfg.HistoryPath()
+	if err != nil {
+		fmt.Println(err)
+		return false
+	}
+	entries, err := ReadHistory(path)
+	if err != nil && !os.IsNotExist(err) {
+		fmt.Printf("读取状态历史失败: %v\n", err)
+		return false
+	}
+	entries = FilterHistory(entries, opts)
+	if opts.JSON {
+		data, _ := json.MarshalIndent(entries, "", "  ")
+		fmt.Println(string(data))
+		return true
+	}
+	PrintHistory(entries)
+	return true
+}
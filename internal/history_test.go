@@ -0,0 +1,44 @@
+package internal
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestHistoryRing(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history", "evs.json")
+	h := NewHistory(path, 3)
+	start := time.Now()
+	for i := 1; i <= 5; i++ {
+		app := "a"
+		if i%2 == 0 {
+			app = "b"
+		}
+		target := app + "#1"
+		if i == 5 {
+			target = app + "#2"
+		}
+		h.Add(HistoryEntry{Time: start.Add(time.Duration(i) * time.Second), App: app, Target: target, Pid: i, Args: []string{"-x"}})
+	}
+	entries := h.Entries()
+	if len(entries) != 3 || entries[0].Pid != 3 || entries[2].Pid != 5 {
+		t.Fatalf("entries = %+v", entries)
+	}
+
+	// 重新载入持久化的记录，容量变小时丢弃最旧的
+	h2 := NewHistory(path, 2)
+	if got := h2.Entries(); len(got) != 2 || got[0].Pid != 4 || got[1].Args[0] != "-x" {
+		t.Fatalf("reloaded = %+v", got)
+	}
+
+	if got := FilterHistory(entries, HistoryOptions{Name: "a"}); len(got) != 2 || got[1].Pid != 5 {
+		t.Fatalf("filter by app = %+v", got)
+	}
+	if got := FilterHistory(entries, HistoryOptions{Name: "a#2"}); len(got) != 1 || got[0].Pid != 5 {
+		t.Fatalf("filter by target = %+v", got)
+	}
+	if got := FilterHistory(entries, HistoryOptions{Limit: 1}); len(got) != 1 || got[0].Pid != 5 {
+		t.Fatalf("limit = %+v", got)
+	}
+}
@@ -0,0 +1,139 @@
+package internal
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// InstanceInfo 实例注册信息，每个运行中的 core 在 <用户配置目录>/evs/instances/<name>.json 登记一份
+// 托盘据此列出并连接多个 core
+type InstanceInfo struct {
+	Name    string    `json:"name"`
+	Config  string    `json:"config"` // 配置文件路径
+	Socket  string    `json:"socket"` // 控制 socket 地址（SocketAddr.String()）
+	Pid     int       `json:"pid"`
+	Started time.Time `json:"started"`
+}
+
+// Addr 解析注册信息中的 socket 地址
+func (i InstanceInfo) Addr() (SocketAddr, error) {
+	return ParseSocketAddr(i.Socket, i.Name)
+}
+
+// InstancesDir 返回实例注册目录
+func InstancesDir() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("获取用户配置目录失败: %v", err)
+	}
+	return filepath.Join(dir, "evs", "instances"), nil
+}
+
+// RegisterInstance 登记实例，返回注销函数；同名实例仍可连接时返回错误
+func RegisterInstance(info InstanceInfo) (func(), error) {
+	dir, err := InstancesDir()
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+	if abs, err := filepath.Abs(info.Config); err == nil {
+		info.Config = abs
+	}
+	path := filepath.Join(dir, info.Name+".json")
+	if old, err := readInstanceFile(path); err == nil && old.Pid != info.Pid && old.reachable() {
+		return nil, fmt.Errorf("实例名 %s 已被运行中的 core 使用（PID=%d，配置 %s），请在配置文件中设置不同的 name", info.Name, old.Pid, old.Config)
+	}
+	data, err := json.MarshalIndent(info, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		return nil, err
+	}
+	return func() {
+		// 只删除自己登记的文件，避免误删同名新实例
+		if cur, err := readInstanceFile(path); err == nil && cur.Pid == info.Pid {
+			os.Remove(path)
+		}
+	}, nil
+}
+
+// ListInstances 列出已登记且 socket 可连接的实例（按名称排序），无法连接的残留登记会被清理
+func ListInstances() ([]InstanceInfo, error) {
+	dir, err := InstancesDir()
+	if err != nil {
+		return nil, err
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var list []InstanceInfo
+	for _, e := range entries {
+		if e.IsDir() || !strings.HasSuffix(e.Name(), ".json") {
+			continue
+		}
+		path := filepath.Join(dir, e.Name())
+		info, err := readInstanceFile(path)
+		if err != nil {
+			continue
+		}
+		if !info.reachable() {
+			os.Remove(path)
+			continue
+		}
+		list = append(list, info)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list, nil
+}
+
+// ListRunningInstances 打印正在运行的实例
+func ListRunningInstances() {
+	list, err := ListInstances()
+	if err != nil {
+		fmt.Printf("读取实例列表失败: %v\n", err)
+		return
+	}
+	if len(list) == 0 {
+		fmt.Println("没有正在运行的实例")
+		return
+	}
+	for _, info := range list {
+		fmt.Printf("%-12s PID=%-7d %-40s %s\n", info.Name, info.Pid, info.Socket, info.Config)
+	}
+}
+
+func readInstanceFile(path string) (InstanceInfo, error) {
+	var info InstanceInfo
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return info, err
+	}
+	err = json.Unmarshal(data, &info)
+	return info, err
+}
+
+// reachable 通过连接 socket 判断实例是否仍在运行
+func (i InstanceInfo) reachable() bool {
+	addr, err := i.Addr()
+	if err != nil {
+		return false
+	}
+	conn, err := addr.Dial(300 * time.Millisecond)
+	if err != nil {
+		return false
+	}
+	conn.Close()
+	return true
+}
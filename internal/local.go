@@ -0,0 +1,241 @@
+package internal
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// 目录级版本固定：从当前目录向上查找版本文件（默认 .evs-version），文件内容为应用名或版本约束。
+// 优先级：环境变量 > 目录版本文件 > 配置中的 activate
+
+const (
+	DefaultLocalFile  = ".evs-version"
+	DefaultVersionEnv = "EVS_VERSION"
+)
+
+// ErrNoActivate 各级来源都没有指定应用
+var ErrNoActivate = errors.New("无激活应用")
+
+// 选择来源
+const (
+	SelectEnv    = "env"    // 环境变量
+	SelectLocal  = "local"  // 目录版本文件
+	SelectGlobal = "global" // 配置中的 activate
+)
+
+// LocalFileName 返回目录版本文件名，未配置时为 .evs-version
+func (c *Config) LocalFileName() string {
+	if c.LocalFile == "" {
+		return DefaultLocalFile
+	}
+	return c.LocalFile
+}
+
+// VersionEnvName 返回指定版本的环境变量名，未配置时为 EVS_VERSION
+func (c *Config) VersionEnvName() string {
+	if c.VersionEnv == "" {
+		return DefaultVersionEnv
+	}
+	return c.VersionEnv
+}
+
+// Selection 应用选择结果及其来源
+type Selection struct {
+	Source string // env / local / global
+	Spec   string // 来源中写的应用名或版本约束
+	File   string // Source 为 local 时的版本文件路径
+	Resolution
+}
+
+// Describe 说明选择来源，用于 which 与日志
+func (s Selection) Describe(c *Config) string {
+	switch s.Source {
+	case SelectEnv:
+		return fmt.Sprintf("环境变量 %s=%s", c.VersionEnvName(), s.Spec)
+	case SelectLocal:
+		return fmt.Sprintf("版本文件 %s", s.File)
+	default:
+		return fmt.Sprintf("配置文件 %s 中的 activate", c.Source)
+	}
+}
+
+// FindLocalVersion 从 dir 向上查找版本文件，返回文件路径与其中的应用名/约束；找不到时 path 为空
+func FindLocalVersion(dir, name string) (path, spec string, err error) {
+	dir, err = filepath.Abs(dir)
+	if err != nil {
+		return "", "", err
+	}
+	for {
+		p := filepath.Join(dir, name)
+		if fi, statErr := os.Stat(p); statErr == nil && !fi.IsDir() {
+			spec, err := readLocalVersion(p)
+			return p, spec, err
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", "", nil
+		}
+		dir = parent
+	}
+}
+
+// readLocalVersion 读取版本文件中第一个非空、非 # 注释的行
+func readLocalVersion(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		if line != "" && !strings.HasPrefix(line, "#") {
+			return line, nil
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return "", err
+	}
+	return "", fmt.Errorf("%s 为空", path)
+}
+
+// SelectApp 按优先级选择要启动的应用：环境变量 > dir 及其上级目录中的版本文件 > activate。
+// 高优先级来源中的应用不存在时返回错误，不会静默回退
+func (c *Config) SelectApp(dir string) (Selection, error) {
+	if spec := strings.TrimSpace(os.Getenv(c.VersionEnvName())); spec != "" {
+		return c.selectFrom(Selection{Source: SelectEnv, Spec: spec})
+	}
+	if dir != "" {
+		path, spec, err := FindLocalVersion(dir, c.LocalFileName())
+		if err != nil {
+			return Selection{}, fmt.Errorf("读取版本文件失败: %v", err)
+		}
+		if path != "" {
+			return c.selectFrom(Selection{Source: SelectLocal, Spec: spec, File: path})
+		}
+	}
+	if c.Activate == "" {
+		return Selection{}, ErrNoActivate
+	}
+	return c.selectFrom(Selection{Source: SelectGlobal, Spec: c.Activate})
+}
+
+func (c *Config) selectFrom(s Selection) (Selection, error) {
+	res, err := c.ResolveApp(s.Spec)
+	if err != nil {
+		return s, fmt.Errorf("%s: %v", s.Describe(c), err)
+	}
+	s.Resolution = res
+	return s, nil
+}
+
+// WriteLocalVersion 在 dir 中写入版本文件
+func WriteLocalVersion(dir, name, spec string) (string, error) {
+	path := filepath.Join(dir, name)
+	return path, os.WriteFile(path, []byte(spec+"\n"), 0644)
+}
+
+// LocalCommand 执行 local 命令：
+//
+//	local           显示当前目录生效的版本文件
+//	local <name>    在当前目录写入版本文件（应用名或版本约束）
+//	local --unset   删除当前目录的版本文件
+func LocalCommand(cfg *Config, args []string) {
+	wd, err := os.Getwd()
+	if err != nil {
+		fmt.Printf("获取当前目录失败: %v\n", err)
+		return
+	}
+	name := cfg.LocalFileName()
+	switch {
+	case len(args) == 0:
+		path, spec, err := FindLocalVersion(wd, name)
+		switch {
+		case err != nil:
+			fmt.Printf("读取版本文件失败: %v\n", err)
+		case path == "":
+			fmt.Printf("未找到 %s\n", name)
+		default:
+			fmt.Printf("%s (%s)\n", spec, path)
+		}
+	case args[0] == "--unset":
+		path := filepath.Join(wd, name)
+		if err := os.Remove(path); err != nil {
+			fmt.Printf("删除版本文件失败: %v\n", err)
+			return
+		}
+		fmt.Printf("已删除 %s\n", path)
+	default:
+		res, err := cfg.ResolveApp(args[0])
+		if err != nil {
+			fmt.Println(err)
+			return
+		}
+		path, err := WriteLocalVersion(wd, name, args[0])
+		if err != nil {
+			fmt.Printf("写入版本文件失败: %v\n", err)
+			return
+		}
+		if res.IsConstraint() {
+			fmt.Printf("已写入 %s: %s\n", path, res)
+		} else {
+			fmt.Printf("已写入 %s: %s\n", path, res.Name)
+		}
+	}
+}
+
+// WhichCommand 执行 which [tool] 命令：显示当前目录下将启动的应用（或其提供的工具）及其来源，
+// 并列出被覆盖的低优先级来源
+func WhichCommand(cfg *Config, args []string) bool {
+	wd, _ := os.Getwd()
+	sel, err := cfg.SelectApp(wd)
+	if err != nil {
+		fmt.Println(err)
+		return false
+	}
+	app, _ := cfg.LookupApp(sel.Name)
+	path := app.Path
+	if len(args) > 0 {
+		if path, err = cfg.ToolPath(sel.Name, args[0]); err != nil {
+			fmt.Println(err)
+			return false
+		}
+	}
+	fmt.Printf("%s\n", path)
+	fmt.Printf("应用: %s\n", sel.Name)
+	if sel.IsConstraint() {
+		fmt.Printf("解析: %s\n", sel.Resolution)
+	}
+	fmt.Printf("来源: %s\n", sel.Describe(cfg))
+
+	// 依次说明各级来源，标出实际生效的一级
+	fmt.Println("优先级:")
+	mark := func(source string) string {
+		if source == sel.Source {
+			return "*"
+		}
+		return " "
+	}
+	env := os.Getenv(cfg.VersionEnvName())
+	if env == "" {
+		env = "(未设置)"
+	}
+	fmt.Printf(" %s 1. 环境变量 %s: %s\n", mark(SelectEnv), cfg.VersionEnvName(), env)
+	local := "(未找到)"
+	if path, spec, err := FindLocalVersion(wd, cfg.LocalFileName()); err != nil {
+		local = "读取失败: " + err.Error()
+	} else if path != "" {
+		local = fmt.Sprintf("%s (%s)", spec, path)
+	}
+	fmt.Printf(" %s 2. 版本文件 %s: %s\n", mark(SelectLocal), cfg.LocalFileName(), local)
+	global := cfg.Activate
+	if global == "" {
+		global = "(未设置)"
+	}
+	fmt.Printf(" %s 3. 配置 activate: %s\n", mark(SelectGlobal), global)
+	return true
+}
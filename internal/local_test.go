@@ -0,0 +1,68 @@
+package internal
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSelectAppPrecedence(t *testing.T) {
+	cfg := &Config{
+		Activate: "node16",
+		Apps: map[string]App{
+			"node16": {Path: "/opt/node16/bin/node", Version: "16.20.0"},
+			"node18": {Path: "/opt/node18/bin/node", Version: "18.20.2"},
+			"node20": {Path: "/opt/node20/bin/node", Version: "20.11.0"},
+		},
+		AppOrder: []string{"node16", "node18", "node20"},
+	}
+	root := t.TempDir()
+	sub := filepath.Join(root, "pkg", "src")
+	if err := os.MkdirAll(sub, 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv(DefaultVersionEnv, "")
+
+	check := func(source, name string) {
+		t.Helper()
+		sel, err := cfg.SelectApp(sub)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if sel.Source != source || sel.Name != name {
+			t.Fatalf("selected %s from %s, want %s from %s", sel.Name, sel.Source, name, source)
+		}
+	}
+
+	check(SelectGlobal, "node16")
+
+	// 上级目录中的版本文件，支持注释与版本约束
+	if err := os.WriteFile(filepath.Join(root, DefaultLocalFile), []byte("# pinned\n^18\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	check(SelectLocal, "node18")
+
+	// 更近的版本文件优先
+	if _, err := WriteLocalVersion(filepath.Join(root, "pkg"), DefaultLocalFile, "node20"); err != nil {
+		t.Fatal(err)
+	}
+	check(SelectLocal, "node20")
+
+	// 环境变量优先于版本文件
+	t.Setenv(DefaultVersionEnv, "node16")
+	check(SelectEnv, "node16")
+
+	// 高优先级来源指定的应用不存在时报错，而不是回退
+	t.Setenv(DefaultVersionEnv, "^22")
+	if _, err := cfg.SelectApp(sub); err == nil {
+		t.Fatal("expected error for unsatisfiable env constraint")
+	}
+
+	// 自定义文件名与环境变量名
+	cfg.LocalFile = ".node-version"
+	cfg.VersionEnv = "NODE_PIN"
+	if _, err := WriteLocalVersion(root, ".node-version", "node18"); err != nil {
+		t.Fatal(err)
+	}
+	check(SelectLocal, "node18")
+}
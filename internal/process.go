@@ -2,17 +2,33 @@ package internal
 
 import (
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
-	"path/filepath"
-	"strings"
 	"syscall"
 	"time"
-	"unsafe"
-
-	"github.com/StackExchange/wmi"
 )
 
+// ProcessBackend 封装与平台相关的进程操作（查找、进程树、终止等）
+// Windows 使用 taskkill + WMI，Linux 使用 /proc + 进程组信号，其它 Unix 使用 ps + 进程组信号
+type ProcessBackend interface {
+	// FindProcessByPath 查找可执行文件路径匹配的首个进程，返回 pid、完整命令行（含 exe）
+	FindProcessByPath(path string) (pid int, args []string, found bool)
+	// FindAllDescendantPids 返回 rootPid 及其所有子孙进程 PID
+	FindAllDescendantPids(rootPid int) []int
+	// KillProcessTree 强制终止整个进程树
+	KillProcessTree(pid int) error
+	// TerminateProcessTree 向进程树发送软停止请求（signal 取值见 StopSignal*），不等待退出
+	TerminateProcessTree(pid int, signal string) error
+	// IsProcessAlive 检查单个进程是否存活
+	IsProcessAlive(pid int) bool
+	// PrepareCommand 在启动前调整 cmd（如进程组、隐藏窗口）
+	PrepareCommand(cmd *exec.Cmd)
+}
+
+// 当前平台的进程后端，由 process_windows.go / process_linux.go / process_ps.go 提供
+var processBackend ProcessBackend = newPlatformBackend()
+
 // ExtractExitCode 提取 error 中的退出码（如有），否则返回 false
 func ExtractExitCode(err error) (int, bool) {
 	if err == nil {
@@ -31,28 +47,17 @@ func ExtractExitCode(err error) (int, bool) {
 	return 0, false
 }
 
-// 检查进程是否存活（windows 适用）
+// 检查进程是否存活
 func IsProcessAlive(pid int) bool {
 	if pid == 0 {
 		return false
 	}
-	proc, err := os.FindProcess(pid)
-	if err != nil {
-		return false
-	}
-	err = proc.Signal(syscall.Signal(0))
-	return err == nil
+	return processBackend.IsProcessAlive(pid)
 }
 
 // 终止进程树
 func KillProcessTree(pid int) error {
-	cmd := exec.Command("taskkill", "/F", "/T", "/PID", fmt.Sprintf("%d", pid))
-	cmd.SysProcAttr = &syscall.SysProcAttr{HideWindow: true} // 隐藏控制台窗口
-	output, err := cmd.CombinedOutput()
-	if err != nil {
-		return fmt.Errorf("终止进程树失败: %v, 输出: %s", err, output)
-	}
-	return nil
+	return processBackend.KillProcessTree(pid)
 }
 
 // 终止进程树并等待主进程彻底退出（注意：只检测主进程存活，可能有子进程残留）
@@ -75,13 +80,81 @@ func KillProcessTreeAndWait(pid int) error {
 	return nil
 }
 
+// StopProcessTree 按停止策略终止进程树：
+// 1. 发送软停止请求（Linux: SIGTERM/SIGINT，Windows: taskkill 不带 /F，即关闭请求）
+// 2. 在宽限时间内轮询进程树是否退出
+// 3. 超时或软停止失败时强制终止并等待
+// onPhase 用于上报每个阶段的描述（可为 nil）
+func StopProcessTree(pid int, policy StopPolicy, onPhase func(detail string)) error {
+	phase := func(detail string) {
+		fmt.Printf("[stop] PID=%d %s\n", pid, detail)
+		if onPhase != nil {
+			onPhase(detail)
+		}
+	}
+
+	signal := policy.SignalOrDefault()
+	if signal != StopSignalKill {
+		grace := policy.GraceOrDefault()
+		phase(fmt.Sprintf("正在请求退出（%s，宽限 %s）", signal, grace))
+		if err := processBackend.TerminateProcessTree(pid, signal); err != nil {
+			phase(fmt.Sprintf("软停止失败: %v", err))
+		} else {
+			deadline := time.Now().Add(grace)
+			for time.Now().Before(deadline) {
+				if !IsProcessTreeAlive(pid) {
+					return nil
+				}
+				time.Sleep(100 * time.Millisecond)
+			}
+			phase("宽限时间已到")
+		}
+	}
+
+	phase("正在强制终止")
+	return KillProcessTreeAndWait(pid)
+}
+
+// ProcessOptions 启动子进程的附加选项
+type ProcessOptions struct {
+	Env []string // 完整环境变量，nil 表示继承父进程
+	Dir string   // 工作目录，空表示沿用当前目录
+
+	Stdout io.Writer // 标准输出，nil 表示使用 core 自身的输出
+	Stderr io.Writer // 标准错误，nil 表示使用 core 自身的输出
+}
+
 // 启动应用进程并异步监控退出，所有状态通过回调返回
-func StartAppProcess(path string, args []string, onStatus func(status string, pid int, exitErr error)) (int, error) {
+func StartAppProcess(path string, args []string, opts ProcessOptions, onStatus func(status string, pid int, exitErr error)) (int, error) {
 	cmd := exec.Command(path, args...)
 	cmd.Stdout = os.Stdout
 	cmd.Stderr = os.Stderr
+	var outputs []*outputPipe
+	for _, o := range []struct {
+		dst *io.Writer
+		w   io.Writer
+	}{{&cmd.Stdout, opts.Stdout}, {&cmd.Stderr, opts.Stderr}} {
+		if o.w == nil {
+			continue
+		}
+		p, err := newOutputPipe(o.w)
+		if err != nil {
+			onStatus("start_failed", 0, err)
+			return 0, err
+		}
+		defer p.started()
+		*o.dst = p.child
+		outputs = append(outputs, p)
+	}
+	cmd.Env = opts.Env
+	cmd.Dir = opts.Dir
+	processBackend.PrepareCommand(cmd)
 	err := cmd.Start()
 	if err != nil {
+		for _, p := range outputs {
+			p.started()
+			<-p.done
+		}
 		onStatus("start_failed", 0, err)
 		return 0, err
 	}
@@ -89,6 +162,9 @@ func StartAppProcess(path string, args []string, onStatus func(status string, pi
 	onStatus("running", pid, nil)
 	go func() {
 		err := cmd.Wait()
+		for _, p := range outputs {
+			p.wait(outputDrainTimeout)
+		}
 		if err == nil {
 			onStatus("exited", pid, nil)
 			return
@@ -117,96 +193,82 @@ func StartAppProcess(path string, args []string, onStatus func(status string, pi
 	return pid, nil
 }
 
-type wmiProc struct {
-	ProcessId      uint32  `wmi:"ProcessId"`
-	ExecutablePath *string `wmi:"ExecutablePath"`
-	CommandLine    *string `wmi:"CommandLine"`
-}
+// 进程退出后等待输出写完的最长时间（子孙进程可能仍持有管道）
+const outputDrainTimeout = time.Second
 
-// FindProcessByPath 在系统进程中查找与指定路径匹配的进程（仅查首个匹配，区分大小写）。
-// 返回 pid、命令行参数、是否找到。
-// Windows 需用 WMI 查询进程信息。
-func FindProcessByPath(path string) (pid int, args []string, found bool) {
-	var (
-		wmiQuery = "SELECT ProcessId, ExecutablePath, CommandLine FROM Win32_Process"
-	)
+// outputPipe 把子进程的一路输出经管道复制到 io.Writer。
+// 不直接把 io.Writer 交给 exec.Cmd：那样 Wait 会一直等到所有继承了管道的子孙进程退出才返回
+type outputPipe struct {
+	child *os.File      // 子进程使用的写端
+	done  chan struct{} // 复制结束（所有写端关闭）
+}
 
-	var procs []wmiProc
-	if err := wmiQueryAll(wmiQuery, &procs); err != nil {
-		return 0, nil, false
+func newOutputPipe(w io.Writer) (*outputPipe, error) {
+	r, child, err := os.Pipe()
+	if err != nil {
+		return nil, err
 	}
-	for _, p := range procs {
-		if p.ExecutablePath != nil {
-			// fmt.Printf("[DEBUG] PID=%d, ExecutablePath=%q\n", p.ProcessId, *p.ExecutablePath)
-			// 路径归一化并大小写不敏感比较
-			normExe, err2 := filepath.Abs(filepath.Clean(*p.ExecutablePath))
-			normTarget, err1 := filepath.Abs(filepath.Clean(path))
-			if err1 == nil && err2 == nil && strings.EqualFold(normExe, normTarget) {
-				fmt.Printf("[DEBUG] MATCH: PID=%d\n", p.ProcessId)
-				pid := int(p.ProcessId)
-				args := parseCmdlineWin32(p.CommandLine)
-				fmt.Printf("[DEBUG] MATCHED CMDLINE: %v\n", args)
-				return pid, args, true
-			}
+	p := &outputPipe{child: child, done: make(chan struct{})}
+	go func() {
+		defer close(p.done)
+		io.Copy(w, r)
+		r.Close()
+		if f, ok := w.(interface{ Flush() }); ok {
+			f.Flush()
 		}
-	}
-	fmt.Println("[DEBUG] No matching process found.")
-	return 0, nil, false
+	}()
+	return p, nil
 }
 
-// wmiQueryAll 封装 WMI 查询
-func wmiQueryAll(query string, dst interface{}) error {
-	return wmi.Query(query, dst)
+// started 子进程启动（或启动失败）后关闭父进程持有的写端，可重复调用
+func (p *outputPipe) started() {
+	p.child.Close()
 }
 
-// parseCmdlineWin32 将 Win32_Process.CommandLine 拆分为参数
-func parseCmdlineWin32(cmd *string) []string {
-	if cmd == nil {
-		return nil
-	}
-	argv, err := CommandLineToArgv(*cmd)
-	if err == nil {
-		return argv
+// wait 等待输出复制结束，最多 timeout
+func (p *outputPipe) wait(timeout time.Duration) {
+	select {
+	case <-p.done:
+	case <-time.After(timeout):
 	}
-	return strings.Fields(*cmd)
 }
 
-// CommandLineToArgv 封装 Windows API
-func CommandLineToArgv(cmd string) ([]string, error) {
-	shell32 := syscall.NewLazyDLL("shell32.dll")
-	proc := shell32.NewProc("CommandLineToArgvW")
-	cmd16, _ := syscall.UTF16PtrFromString(cmd)
-	var argc int32
-	argv, _, err := proc.Call(uintptr(unsafe.Pointer(cmd16)), uintptr(unsafe.Pointer(&argc)))
-	if argv == 0 {
-		return nil, err
-	}
-	defer syscall.LocalFree(syscall.Handle(argv))
-	var args []string
-	for i := 0; i < int(argc); i++ {
-		p := (*[1 << 16]*uint16)(unsafe.Pointer(argv))[i]
-		args = append(args, syscall.UTF16ToString((*[1 << 16]uint16)(unsafe.Pointer(p))[:]))
-	}
-	return args, nil
+// FindProcessByPath 在系统进程中查找与指定路径匹配的进程（仅查首个匹配）。
+// 返回 pid、命令行参数（含 exe 路径）、是否找到。
+func FindProcessByPath(path string) (pid int, args []string, found bool) {
+	return processBackend.FindProcessByPath(path)
 }
 
 // FindAllDescendantPids 递归查找所有子进程 PID（含自身）
-type wmiProcTree struct {
-	ProcessId       uint32 `wmi:"ProcessId"`
-	ParentProcessId uint32 `wmi:"ParentProcessId"`
+func FindAllDescendantPids(rootPid int) []int {
+	return processBackend.FindAllDescendantPids(rootPid)
 }
 
-func FindAllDescendantPids(rootPid int) []int {
-	var procs []wmiProcTree
-	_ = wmi.Query("SELECT ProcessId, ParentProcessId FROM Win32_Process", &procs)
+// HideWindow 启动子进程时隐藏控制台窗口（仅 Windows 生效）
+func HideWindow(cmd *exec.Cmd) {
+	hideWindow(cmd)
+}
+
+// IsProcessTreeAlive 检查进程树是否有存活
+func IsProcessTreeAlive(rootPid int) bool {
+	for _, pid := range FindAllDescendantPids(rootPid) {
+		if IsProcessAlive(pid) {
+			return true
+		}
+	}
+	return false
+}
+
+// collectDescendants 根据 pid -> ppid 关系计算 rootPid 的所有子孙（含自身）
+func collectDescendants(rootPid int, parents map[int]int) []int {
 	pidSet := map[int]struct{}{rootPid: {}}
 	changed := true
 	for changed {
 		changed = false
-		for _, p := range procs {
-			if _, ok := pidSet[int(p.ParentProcessId)]; ok {
-				if _, exist := pidSet[int(p.ProcessId)]; !exist {
-					pidSet[int(p.ProcessId)] = struct{}{}
+		for pid, ppid := range parents {
+			if _, ok := pidSet[ppid]; ok {
+				if _, exist := pidSet[pid]; !exist {
+					pidSet[pid] = struct{}{}
 					changed = true
 				}
 			}
@@ -218,13 +280,3 @@ func FindAllDescendantPids(rootPid int) []int {
 	}
 	return pids
 }
-
-// IsProcessTreeAlive 检查进程树是否有存活
-func IsProcessTreeAlive(rootPid int) bool {
-	for _, pid := range FindAllDescendantPids(rootPid) {
-		if IsProcessAlive(pid) {
-			return true
-		}
-	}
-	return false
-}
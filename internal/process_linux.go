@@ -0,0 +1,133 @@
+//go:build linux
+
+package internal
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+// linuxBackend 基于 /proc 与进程组信号的进程后端
+type linuxBackend struct{}
+
+func newPlatformBackend() ProcessBackend {
+	return linuxBackend{}
+}
+
+// PrepareCommand 让子进程成为新进程组组长，便于按组发送信号
+func (linuxBackend) PrepareCommand(cmd *exec.Cmd) {
+	setProcessGroup(cmd)
+}
+
+// IsProcessAlive 僵尸进程视为已退出
+func (linuxBackend) IsProcessAlive(pid int) bool {
+	state, _, err := readProcStat(pid)
+	if err != nil {
+		return false
+	}
+	return state != 'Z' && state != 'X'
+}
+
+// TerminateProcessTree 向进程组及脱离进程组的子孙进程发送 SIGTERM/SIGINT
+func (b linuxBackend) TerminateProcessTree(pid int, signal string) error {
+	return signalProcessTree(pid, b.FindAllDescendantPids(pid), stopSignal(signal))
+}
+
+// KillProcessTree 先向进程组发送 SIGKILL，再逐个清理脱离进程组的子孙进程
+func (b linuxBackend) KillProcessTree(pid int) error {
+	return signalProcessTree(pid, b.FindAllDescendantPids(pid), syscall.SIGKILL)
+}
+
+// FindProcessByPath 比较 /proc/<pid>/exe 链接目标，区分大小写
+func (linuxBackend) FindProcessByPath(path string) (pid int, args []string, found bool) {
+	target, err := filepath.Abs(filepath.Clean(path))
+	if err != nil {
+		return 0, nil, false
+	}
+	if resolved, err := filepath.EvalSymlinks(target); err == nil {
+		target = resolved
+	}
+	for _, p := range listProcPids() {
+		exe, err := os.Readlink(fmt.Sprintf("/proc/%d/exe", p))
+		if err != nil {
+			continue
+		}
+		if exe != target {
+			continue
+		}
+		return p, readProcCmdline(p), true
+	}
+	return 0, nil, false
+}
+
+func (linuxBackend) FindAllDescendantPids(rootPid int) []int {
+	parents := make(map[int]int)
+	for _, p := range listProcPids() {
+		if _, ppid, err := readProcStat(p); err == nil {
+			parents[p] = ppid
+		}
+	}
+	return collectDescendants(rootPid, parents)
+}
+
+// listProcPids 列出 /proc 下所有数字目录
+func listProcPids() []int {
+	entries, err := os.ReadDir("/proc")
+	if err != nil {
+		return nil
+	}
+	var pids []int
+	for _, e := range entries {
+		if pid, err := strconv.Atoi(e.Name()); err == nil {
+			pids = append(pids, pid)
+		}
+	}
+	return pids
+}
+
+// readProcStat 解析 /proc/<pid>/stat，返回进程状态与父进程 PID
+// comm 字段可能含空格和括号，因此从最后一个 ')' 之后开始解析
+func readProcStat(pid int) (state byte, ppid int, err error) {
+	data, err := os.ReadFile(fmt.Sprintf("/proc/%d/stat", pid))
+	if err != nil {
+		return 0, 0, err
+	}
+	state, ppid, err = parseProcStat(data)
+	if err != nil {
+		return 0, 0, fmt.Errorf("%v: %d", err, pid)
+	}
+	return state, ppid, nil
+}
+
+// parseProcStat 解析 /proc/<pid>/stat 的内容
+func parseProcStat(data []byte) (state byte, ppid int, err error) {
+	idx := bytes.LastIndexByte(data, ')')
+	if idx == -1 {
+		return 0, 0, fmt.Errorf("stat 格式错误")
+	}
+	fields := strings.Fields(string(data[idx+1:]))
+	if len(fields) < 2 {
+		return 0, 0, fmt.Errorf("stat 格式错误")
+	}
+	ppid, err = strconv.Atoi(fields[1])
+	if err != nil {
+		return 0, 0, err
+	}
+	return fields[0][0], ppid, nil
+}
+
+// readProcCmdline 读取 /proc/<pid>/cmdline（\0 分隔）
+func readProcCmdline(pid int) []string {
+	data, err := os.ReadFile(fmt.Sprintf("/proc/%d/cmdline", pid))
+	if err != nil || len(data) == 0 {
+		return nil
+	}
+	data = bytes.TrimRight(data, "\x00")
+	return strings.Split(string(data), "\x00")
+}
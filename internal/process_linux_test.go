@@ -0,0 +1,31 @@
+package internal
+
+import "testing"
+
+func TestParseProcStat(t *testing.T) {
+	cases := []struct {
+		data  string
+		state byte
+		ppid  int
+		err   bool
+	}{
+		{data: "1234 (node) S 1 1234 1234 0 -1", state: 'S', ppid: 1},
+		{data: "42 (my app (v2)) R 7 42 42", state: 'R', ppid: 7}, // comm 含空格和括号
+		{data: "43 () ) Z 9 43", state: 'Z', ppid: 9},
+		{data: "44 node S 1", err: true},
+		{data: "45 (node) S", err: true},
+		{data: "46 (node) S x", err: true},
+	}
+	for _, c := range cases {
+		state, ppid, err := parseProcStat([]byte(c.data))
+		if c.err {
+			if err == nil {
+				t.Errorf("%q: 期望报错", c.data)
+			}
+			continue
+		}
+		if err != nil || state != c.state || ppid != c.ppid {
+			t.Errorf("%q: got %c %d %v; want %c %d", c.data, state, ppid, err, c.state, c.ppid)
+		}
+	}
+}
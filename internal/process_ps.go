@@ -0,0 +1,92 @@
+//go:build unix && !linux
+
+package internal
+
+import (
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+// psBackend 没有 /proc 的 Unix（macOS、BSD 等）使用的进程后端：进程列表来自 ps，终止同样按进程组发送信号
+type psBackend struct{}
+
+func newPlatformBackend() ProcessBackend {
+	return psBackend{}
+}
+
+// PrepareCommand 让子进程成为新进程组组长，便于按组发送信号
+func (psBackend) PrepareCommand(cmd *exec.Cmd) {
+	setProcessGroup(cmd)
+}
+
+// IsProcessAlive 用 0 号信号探测，僵尸进程同样视为存活
+func (psBackend) IsProcessAlive(pid int) bool {
+	err := syscall.Kill(pid, 0)
+	return err == nil || err == syscall.EPERM
+}
+
+// TerminateProcessTree 向进程组及脱离进程组的子孙进程发送 SIGTERM/SIGINT
+func (b psBackend) TerminateProcessTree(pid int, signal string) error {
+	return signalProcessTree(pid, b.FindAllDescendantPids(pid), stopSignal(signal))
+}
+
+// KillProcessTree 先向进程组发送 SIGKILL，再逐个清理脱离进程组的子孙进程
+func (b psBackend) KillProcessTree(pid int) error {
+	return signalProcessTree(pid, b.FindAllDescendantPids(pid), syscall.SIGKILL)
+}
+
+// FindProcessByPath 比较 ps 输出的命令行第一项；ps 不提供带空格路径的参数边界，按空白切分
+func (psBackend) FindProcessByPath(path string) (pid int, args []string, found bool) {
+	target, err := filepath.Abs(filepath.Clean(path))
+	if err != nil {
+		return 0, nil, false
+	}
+	for _, fields := range psList("pid=", "args=") {
+		if len(fields) < 2 || fields[1] != target {
+			continue
+		}
+		p, err := strconv.Atoi(fields[0])
+		if err != nil {
+			continue
+		}
+		return p, fields[1:], true
+	}
+	return 0, nil, false
+}
+
+func (psBackend) FindAllDescendantPids(rootPid int) []int {
+	parents := make(map[int]int)
+	for _, fields := range psList("pid=", "ppid=") {
+		if len(fields) < 2 {
+			continue
+		}
+		p, err1 := strconv.Atoi(fields[0])
+		ppid, err2 := strconv.Atoi(fields[1])
+		if err1 == nil && err2 == nil {
+			parents[p] = ppid
+		}
+	}
+	return collectDescendants(rootPid, parents)
+}
+
+// psList 列出所有进程的指定字段（POSIX ps -A -o），每行按空白切分
+func psList(columns ...string) [][]string {
+	args := []string{"-A"}
+	for _, c := range columns {
+		args = append(args, "-o", c)
+	}
+	out, err := exec.Command("ps", args...).Output()
+	if err != nil {
+		return nil
+	}
+	var rows [][]string
+	for _, line := range strings.Split(string(out), "\n") {
+		if fields := strings.Fields(line); len(fields) > 0 {
+			rows = append(rows, fields)
+		}
+	}
+	return rows
+}
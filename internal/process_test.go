@@ -0,0 +1,146 @@
+package internal
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCollectDescendants(t *testing.T) {
+	// pid -> ppid：10 的子孙为 11、12、13（多层），20 与 10 无关，30 的父进程已不存在
+	parents := map[int]int{1: 0, 10: 1, 11: 10, 12: 11, 13: 12, 14: 10, 20: 1, 21: 20, 30: 99}
+	cases := []struct {
+		root int
+		want []int
+	}{
+		{root: 10, want: []int{10, 11, 12, 13, 14}},
+		{root: 12, want: []int{12, 13}},
+		{root: 13, want: []int{13}},
+		{root: 20, want: []int{20, 21}},
+		{root: 99, want: []int{30, 99}}, // 根进程已退出，仍可找到遗留的子进程
+		{root: 1, want: []int{1, 10, 11, 12, 13, 14, 20, 21}},
+	}
+	for _, c := range cases {
+		got := collectDescendants(c.root, parents)
+		sort.Ints(got)
+		if !reflect.DeepEqual(got, c.want) {
+			t.Errorf("root %d: got %v; want %v", c.root, got, c.want)
+		}
+	}
+}
+
+func TestStopProcessTree(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("需要 sh")
+	}
+	const grace = 300 * time.Millisecond
+	cases := []struct {
+		name   string
+		script string
+		force  bool // 是否需要强制终止
+	}{
+		{name: "响应软停止", script: "sleep 30"},
+		// sh 与 sleep 都忽略 SIGTERM（忽略的信号在 exec 后保留），只能在宽限时间后强制终止
+		{name: "忽略 SIGTERM", script: `trap "" TERM; sleep 30`, force: true},
+	}
+	for _, c := range cases {
+		cmd := exec.Command("sh", "-c", c.script)
+		processBackend.PrepareCommand(cmd)
+		if err := cmd.Start(); err != nil {
+			t.Fatal(err)
+		}
+		pid := cmd.Process.Pid
+		go cmd.Wait() // 回收子进程，避免残留僵尸进程
+
+		// 等待 sleep 启动，确保 trap 已生效且进程树中有子进程
+		var tree []int
+		for deadline := time.Now().Add(5 * time.Second); len(tree) < 2; time.Sleep(10 * time.Millisecond) {
+			if time.Now().After(deadline) {
+				t.Fatalf("%s: 子进程未启动", c.name)
+			}
+			tree = FindAllDescendantPids(pid)
+		}
+
+		var phases []string
+		start := time.Now()
+		err := StopProcessTree(pid, StopPolicy{Signal: StopSignalTerm, Grace: grace}, func(detail string) {
+			phases = append(phases, detail)
+		})
+		elapsed := time.Since(start)
+		if err != nil {
+			t.Errorf("%s: %v", c.name, err)
+		}
+		forced := strings.Contains(strings.Join(phases, "\n"), "正在强制终止")
+		if forced != c.force {
+			t.Errorf("%s: 强制终止 = %v，期望 %v（%q）", c.name, forced, c.force, phases)
+		}
+		if c.force && elapsed < grace {
+			t.Errorf("%s: %s 后即强制终止，未等待宽限时间 %s", c.name, elapsed, grace)
+		}
+		for _, p := range tree {
+			for deadline := time.Now().Add(time.Second); IsProcessAlive(p); time.Sleep(10 * time.Millisecond) {
+				if time.Now().After(deadline) {
+					t.Errorf("%s: 进程 %d 仍在运行", c.name, p)
+					break
+				}
+			}
+		}
+	}
+}
+
+func TestStopPolicyConfig(t *testing.T) {
+	exe, err := os.Executable()
+	if err != nil {
+		t.Fatal(err)
+	}
+	cases := []struct {
+		stop   string
+		want   StopPolicy // 按默认值补全后的策略，errors 非空时不检查
+		errors []string   // 期望的错误字段
+	}{
+		{stop: "{}", want: StopPolicy{Signal: StopSignalTerm, Grace: DefaultStopGrace}},
+		{stop: "{signal: int, grace: 2s}", want: StopPolicy{Signal: StopSignalInt, Grace: 2 * time.Second}},
+		{stop: "{signal: kill}", want: StopPolicy{Signal: StopSignalKill, Grace: DefaultStopGrace}},
+		{stop: "{signal: hup}", errors: []string{"apps.a.stop.signal"}},
+		{stop: "{grace: -1s}", errors: []string{"apps.a.stop.grace"}},
+		{stop: "{grace: soon}", errors: []string{""}}, // 类型不匹配
+	}
+	for _, c := range cases {
+		path := filepath.Join(t.TempDir(), "config.yaml")
+		src := "activate: a\napps:\n  a:\n    path: " + exe + "\n    stop: " + c.stop + "\n"
+		if err := os.WriteFile(path, []byte(src), 0644); err != nil {
+			t.Fatal(err)
+		}
+		findings, err := ValidateConfigFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got []string
+		for _, f := range findings {
+			if f.Severity == SeverityError {
+				got = append(got, f.Field)
+			}
+		}
+		if !reflect.DeepEqual(got, c.errors) {
+			t.Errorf("stop: %s: 错误字段 %q，期望 %q", c.stop, got, c.errors)
+			continue
+		}
+		if len(c.errors) > 0 {
+			continue
+		}
+		cfg, err := LoadConfig(path)
+		if err != nil {
+			t.Fatalf("stop: %s: %v", c.stop, err)
+		}
+		p := cfg.Apps["a"].Stop
+		if got := (StopPolicy{Signal: p.SignalOrDefault(), Grace: p.GraceOrDefault()}); got != c.want {
+			t.Errorf("stop: %s: got %+v; want %+v", c.stop, got, c.want)
+		}
+	}
+}
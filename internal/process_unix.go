@@ -0,0 +1,59 @@
+//go:build unix
+
+package internal
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"syscall"
+)
+
+// 各 Unix 后端共用的部分：进程组、信号与文件权限只依赖 POSIX
+
+func hideWindow(cmd *exec.Cmd) {}
+
+// isExecutableFile 任一执行权限位即视为可执行
+func isExecutableFile(path string, fi os.FileInfo) bool {
+	return fi.Mode()&0111 != 0
+}
+
+// setProcessGroup 让子进程成为新进程组组长，便于按组发送信号
+func setProcessGroup(cmd *exec.Cmd) {
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	cmd.SysProcAttr.Setpgid = true
+}
+
+// stopSignal 软停止使用的信号，见 StopSignal*
+func stopSignal(signal string) syscall.Signal {
+	if signal == StopSignalInt {
+		return syscall.SIGINT
+	}
+	return syscall.SIGTERM
+}
+
+// signalProcessTree 向以 pid 为组长的进程组及 pids 中的每个进程发送信号
+func signalProcessTree(pid int, pids []int, sig syscall.Signal) error {
+	groupErr := syscall.Kill(-pid, sig)
+	var lastErr error
+	for _, p := range pids {
+		if err := syscall.Kill(p, sig); err != nil && err != syscall.ESRCH {
+			lastErr = err
+		}
+	}
+	if groupErr != nil && groupErr != syscall.ESRCH && lastErr != nil {
+		return fmt.Errorf("向进程树发送信号失败: %v", lastErr)
+	}
+	return nil
+}
+
+// forwardSignal 前台模式下把 evs 收到的信号转发给子进程。
+// 终端的 Ctrl+C 会发给整个前台进程组，子进程已经收到，此时不再重复发送
+func forwardSignal(p *os.Process, sig os.Signal) {
+	if sig == os.Interrupt && terminalForeground() {
+		return
+	}
+	_ = p.Signal(sig)
+}
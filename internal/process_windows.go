@@ -0,0 +1,173 @@
+//go:build windows
+
+package internal
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"unsafe"
+
+	"github.com/StackExchange/wmi"
+)
+
+// windowsBackend 基于 taskkill + WMI 的进程后端
+type windowsBackend struct{}
+
+func newPlatformBackend() ProcessBackend {
+	return windowsBackend{}
+}
+
+func hideWindow(cmd *exec.Cmd) {
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	cmd.SysProcAttr.HideWindow = true
+}
+
+// isExecutableFile 按扩展名判断（PATHEXT，默认 .com/.exe/.bat/.cmd）
+func isExecutableFile(path string, fi os.FileInfo) bool {
+	exts := os.Getenv("PATHEXT")
+	if exts == "" {
+		exts = ".COM;.EXE;.BAT;.CMD"
+	}
+	ext := strings.ToLower(filepath.Ext(path))
+	for _, e := range strings.Split(strings.ToLower(exts), ";") {
+		if e != "" && e == ext {
+			return true
+		}
+	}
+	return false
+}
+
+func (windowsBackend) PrepareCommand(cmd *exec.Cmd) {}
+
+func (windowsBackend) IsProcessAlive(pid int) bool {
+	proc, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+	err = proc.Signal(syscall.Signal(0))
+	return err == nil
+}
+
+func (windowsBackend) KillProcessTree(pid int) error {
+	cmd := exec.Command("taskkill", "/F", "/T", "/PID", fmt.Sprintf("%d", pid))
+	hideWindow(cmd) // 隐藏控制台窗口
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("终止进程树失败: %v, 输出: %s", err, output)
+	}
+	return nil
+}
+
+// TerminateProcessTree 使用不带 /F 的 taskkill 向窗口发送关闭请求（控制台程序会返回失败）
+func (windowsBackend) TerminateProcessTree(pid int, signal string) error {
+	cmd := exec.Command("taskkill", "/T", "/PID", fmt.Sprintf("%d", pid))
+	hideWindow(cmd)
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("请求关闭失败: %v, 输出: %s", err, output)
+	}
+	return nil
+}
+
+type wmiProc struct {
+	ProcessId      uint32  `wmi:"ProcessId"`
+	ExecutablePath *string `wmi:"ExecutablePath"`
+	CommandLine    *string `wmi:"CommandLine"`
+}
+
+// FindProcessByPath Windows 需用 WMI 查询进程信息，路径比较大小写不敏感
+func (windowsBackend) FindProcessByPath(path string) (pid int, args []string, found bool) {
+	var (
+		wmiQuery = "SELECT ProcessId, ExecutablePath, CommandLine FROM Win32_Process"
+	)
+
+	var procs []wmiProc
+	if err := wmiQueryAll(wmiQuery, &procs); err != nil {
+		return 0, nil, false
+	}
+	for _, p := range procs {
+		if p.ExecutablePath != nil {
+			// fmt.Printf("[DEBUG] PID=%d, ExecutablePath=%q\n", p.ProcessId, *p.ExecutablePath)
+			// 路径归一化并大小写不敏感比较
+			normExe, err2 := filepath.Abs(filepath.Clean(*p.ExecutablePath))
+			normTarget, err1 := filepath.Abs(filepath.Clean(path))
+			if err1 == nil && err2 == nil && strings.EqualFold(normExe, normTarget) {
+				fmt.Printf("[DEBUG] MATCH: PID=%d\n", p.ProcessId)
+				pid := int(p.ProcessId)
+				args := parseCmdlineWin32(p.CommandLine)
+				fmt.Printf("[DEBUG] MATCHED CMDLINE: %v\n", args)
+				return pid, args, true
+			}
+		}
+	}
+	fmt.Println("[DEBUG] No matching process found.")
+	return 0, nil, false
+}
+
+// wmiQueryAll 封装 WMI 查询
+func wmiQueryAll(query string, dst interface{}) error {
+	return wmi.Query(query, dst)
+}
+
+// parseCmdlineWin32 将 Win32_Process.CommandLine 拆分为参数
+func parseCmdlineWin32(cmd *string) []string {
+	if cmd == nil {
+		return nil
+	}
+	argv, err := CommandLineToArgv(*cmd)
+	if err == nil {
+		return argv
+	}
+	return strings.Fields(*cmd)
+}
+
+// CommandLineToArgv 封装 Windows API
+func CommandLineToArgv(cmd string) ([]string, error) {
+	shell32 := syscall.NewLazyDLL("shell32.dll")
+	proc := shell32.NewProc("CommandLineToArgvW")
+	cmd16, _ := syscall.UTF16PtrFromString(cmd)
+	var argc int32
+	argv, _, err := proc.Call(uintptr(unsafe.Pointer(cmd16)), uintptr(unsafe.Pointer(&argc)))
+	if argv == 0 {
+		return nil, err
+	}
+	defer syscall.LocalFree(syscall.Handle(argv))
+	var args []string
+	for i := 0; i < int(argc); i++ {
+		p := (*[1 << 16]*uint16)(unsafe.Pointer(argv))[i]
+		args = append(args, syscall.UTF16ToString((*[1 << 16]uint16)(unsafe.Pointer(p))[:]))
+	}
+	return args, nil
+}
+
+type wmiProcTree struct {
+	ProcessId       uint32 `wmi:"ProcessId"`
+	ParentProcessId uint32 `wmi:"ParentProcessId"`
+}
+
+func (windowsBackend) FindAllDescendantPids(rootPid int) []int {
+	var procs []wmiProcTree
+	_ = wmi.Query("SELECT ProcessId, ParentProcessId FROM Win32_Process", &procs)
+	parents := make(map[int]int, len(procs))
+	for _, p := range procs {
+		parents[int(p.ProcessId)] = int(p.ParentProcessId)
+	}
+	return collectDescendants(rootPid, parents)
+}
+
+// forwardSignal 前台模式下把 evs 收到的信号转发给子进程。
+// Ctrl+C 由控制台发给所有附加的进程，子进程已经收到；关闭控制台等事件（SIGTERM）先请求关闭，失败再强制终止
+func forwardSignal(p *os.Process, sig os.Signal) {
+	if sig == os.Interrupt {
+		return
+	}
+	if err := processBackend.TerminateProcessTree(p.Pid, StopSignalTerm); err != nil {
+		_ = processBackend.KillProcessTree(p.Pid)
+	}
+}
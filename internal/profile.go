@@ -0,0 +1,143 @@
+package internal
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// 启动配置（profile）：同一个可执行文件的不同启动方式（如 debug、safe-mode、benchmark），
+// 在应用的 profiles 下按名称定义，只需写出与默认启动不同的部分：
+//
+//	args 按 mode 追加到（默认）或替换应用的 args
+//	env  与应用的 env 合并，同名变量以 profile 为准
+//	cwd  非空时替换应用的 cwd
+//
+// 当前选择的 profile 保存在配置的 profile 字段（紧挨 activate），只对定义了该 profile 的应用生效
+
+// DefaultProfile 保留名，表示不使用任何 profile
+const DefaultProfile = "default"
+
+// profile 的参数合并方式
+const (
+	ProfileArgsAppend   = "append"   // 追加到应用的 args 之后（默认）
+	ProfileArgsOverride = "override" // 替换应用的 args
+)
+
+// Profile 应用的一个启动配置
+type Profile struct {
+	Args []string          `yaml:"args,omitempty"`
+	Mode string            `yaml:"mode,omitempty"` // append（默认）/ override，只作用于 args
+	Env  map[string]string `yaml:"env,omitempty"`  // 与应用的 env 合并
+	Cwd  string            `yaml:"cwd,omitempty"`  // 非空时替换应用的 cwd
+}
+
+// ModeOrDefault 返回参数合并方式，未配置时为 append
+func (p Profile) ModeOrDefault() string {
+	if p.Mode == "" {
+		return ProfileArgsAppend
+	}
+	return p.Mode
+}
+
+// ProfileNames 返回应用定义的 profile 名称（排序）
+func (a App) ProfileNames() []string {
+	names := make([]string, 0, len(a.Profiles))
+	for name := range a.Profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// IsDefaultProfile 判断是否表示不使用 profile（空或 default）
+func IsDefaultProfile(name string) bool {
+	return name == "" || name == DefaultProfile
+}
+
+// WithProfile 返回应用了 profile 的配置，name 为空或 default 时原样返回；应用未定义该 profile 时报错
+func (a App) WithProfile(name string) (App, error) {
+	if IsDefaultProfile(name) {
+		return a, nil
+	}
+	p, ok := a.Profiles[name]
+	if !ok {
+		return a, fmt.Errorf("未定义 profile %s（可用: %s）", name, strings.Join(append([]string{DefaultProfile}, a.ProfileNames()...), "、"))
+	}
+	if p.ModeOrDefault() == ProfileArgsOverride {
+		a.Args = append([]string{}, p.Args...)
+	} else {
+		a.Args = append(append([]string{}, a.Args...), p.Args...)
+	}
+	if len(p.Env) > 0 {
+		env := make(map[string]string, len(a.Env)+len(p.Env))
+		for k, v := range a.Env {
+			env[k] = v
+		}
+		for k, v := range p.Env {
+			env[k] = v
+		}
+		a.Env = env
+	}
+	if p.Cwd != "" {
+		a.Cwd = p.Cwd
+	}
+	return a, nil
+}
+
+// ProfileFor 返回应用 name 启动时使用的 profile：当前选择的 profile 仅在应用定义了它时生效，否则为空
+func (c *Config) ProfileFor(name string) string {
+	if IsDefaultProfile(c.Profile) {
+		return ""
+	}
+	app, ok := c.LookupApp(name)
+	if !ok {
+		return ""
+	}
+	if _, ok := app.Profiles[c.Profile]; !ok {
+		return ""
+	}
+	return c.Profile
+}
+
+// SetProfile 设置当前选择的 profile，default 保存为空（不写入配置文件）
+func (c *Config) SetProfile(name string) {
+	if IsDefaultProfile(name) {
+		name = ""
+	}
+	c.Profile = name
+}
+
+// PrepareApp 返回应用实际启动时使用的配置：先应用 profile，再展开模板。profile 为空时使用 ProfileFor 的结果
+func (c *Config) PrepareApp(name, profile string) (App, error) {
+	app, ok := c.LookupApp(name)
+	if !ok {
+		return app, fmt.Errorf("未找到应用: %s", name)
+	}
+	if profile == "" {
+		profile = c.ProfileFor(name)
+	}
+	app, err := app.WithProfile(profile)
+	if err != nil {
+		return app, fmt.Errorf("应用 %s %v", name, err)
+	}
+	return c.ExpandApp(name, app)
+}
+
+// ParseProfileFlag 从参数开头取出 --profile <name> / --profile=<name>，返回 profile 与剩余参数
+func ParseProfileFlag(args []string) (profile string, rest []string, err error) {
+	for len(args) > 0 {
+		switch {
+		case args[0] == "--profile":
+			if len(args) < 2 {
+				return "", nil, fmt.Errorf("--profile 缺少名称")
+			}
+			profile, args = args[1], args[2:]
+		case strings.HasPrefix(args[0], "--profile="):
+			profile, args = strings.TrimPrefix(args[0], "--profile="), args[1:]
+		default:
+			return profile, args, nil
+		}
+	}
+	return profile, args, nil
+}
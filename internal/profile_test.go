@@ -0,0 +1,96 @@
+package internal
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestWithProfile(t *testing.T) {
+	app := App{
+		Args: []string{"--port", "8080"},
+		Env:  map[string]string{"MODE": "prod", "KEEP": "1"},
+		Cwd:  "work",
+		Profiles: map[string]Profile{
+			"debug": {Args: []string{"--inspect"}, Env: map[string]string{"MODE": "dev"}},
+			"safe":  {Args: []string{"--safe-mode"}, Mode: ProfileArgsOverride, Cwd: "safe"},
+		},
+	}
+
+	got, err := app.WithProfile("debug")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"--port", "8080", "--inspect"}; !reflect.DeepEqual(got.Args, want) {
+		t.Errorf("debug args %q, want %q", got.Args, want)
+	}
+	if want := map[string]string{"MODE": "dev", "KEEP": "1"}; !reflect.DeepEqual(got.Env, want) || got.Cwd != "work" {
+		t.Errorf("debug env %v cwd %s", got.Env, got.Cwd)
+	}
+	// 不修改原应用
+	if app.Env["MODE"] != "prod" || len(app.Args) != 2 {
+		t.Errorf("原应用被修改: %+v", app)
+	}
+
+	got, _ = app.WithProfile("safe")
+	if want := []string{"--safe-mode"}; !reflect.DeepEqual(got.Args, want) || got.Cwd != "safe" {
+		t.Errorf("safe args %q cwd %s", got.Args, got.Cwd)
+	}
+
+	for _, name := range []string{"", DefaultProfile} {
+		if got, err := app.WithProfile(name); err != nil || !reflect.DeepEqual(got.Args, app.Args) {
+			t.Errorf("%q: %q, %v", name, got.Args, err)
+		}
+	}
+	if _, err := app.WithProfile("bench"); err == nil {
+		t.Error("未定义的 profile 应报错")
+	}
+}
+
+func TestProfileFor(t *testing.T) {
+	cfg := &Config{
+		Profile: "debug",
+		Apps: map[string]App{
+			"a": {Path: "/bin/a", Profiles: map[string]Profile{"debug": {Args: []string{"-d"}}}},
+			"b": {Path: "/bin/b"},
+		},
+	}
+	// 当前 profile 只对定义了它的应用生效，切换到其它应用按默认方式启动
+	if got := cfg.ProfileFor("a"); got != "debug" {
+		t.Errorf("a: %q", got)
+	}
+	if got := cfg.ProfileFor("b"); got != "" {
+		t.Errorf("b: %q", got)
+	}
+	app, err := cfg.PrepareApp("a", "")
+	if err != nil || !reflect.DeepEqual(app.Args, []string{"-d"}) {
+		t.Errorf("PrepareApp: %q, %v", app.Args, err)
+	}
+	if _, err := cfg.PrepareApp("b", "debug"); err == nil {
+		t.Error("显式指定应用未定义的 profile 应报错")
+	}
+	cfg.SetProfile(DefaultProfile)
+	if cfg.Profile != "" {
+		t.Errorf("default 应保存为空: %q", cfg.Profile)
+	}
+}
+
+func TestParseProfileFlag(t *testing.T) {
+	cases := []struct {
+		args    []string
+		profile string
+		rest    []string
+	}{
+		{[]string{"--profile", "debug", "-v"}, "debug", []string{"-v"}},
+		{[]string{"--profile=safe"}, "safe", []string{}},
+		{[]string{"-v", "--profile", "debug"}, "", []string{"-v", "--profile", "debug"}},
+	}
+	for _, c := range cases {
+		profile, rest, err := ParseProfileFlag(c.args)
+		if err != nil || profile != c.profile || len(rest) != len(c.rest) || (len(rest) > 0 && !reflect.DeepEqual(rest, c.rest)) {
+			t.Errorf("%q: %q %q %v", c.args, profile, rest, err)
+		}
+	}
+	if _, _, err := ParseProfileFlag([]string{"--profile"}); err == nil {
+		t.Error("缺少名称应报错")
+	}
+}
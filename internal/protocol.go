@@ -0,0 +1,236 @@
+package internal
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// 控制 socket 的 JSON-lines 协议：每行一个 JSON 对象
+// 请求: {"v":1,"id":"1","cmd":"info","name":"app1"}
+// 响应: {"v":1,"id":"1","ok":true,"result":{...}} 或 {"v":1,"id":"1","ok":false,"error":{"code":"not_found","message":"..."}}
+// 首字节不是 '{' 的连接按旧文本协议处理（cmd 或 cmd:arg，单次请求）
+// 两种协议的第一帧都必须是认证帧（见 auth.go）
+const ProtocolVersion = 1
+
+// 错误码
+const (
+	ErrCodeUnauthorized       = "unauthorized"        // 未认证或令牌错误
+	ErrCodeBadRequest         = "bad_request"         // 请求无法解析或缺少参数
+	ErrCodeUnsupportedVersion = "unsupported_version" // 协议版本不支持
+	ErrCodeUnknownCommand     = "unknown_command"     // 未知命令
+	ErrCodeNotFound           = "not_found"           // 应用不存在
+	ErrCodeConfigNotLoaded    = "config_not_loaded"   // 配置未加载
+	ErrCodeConflict           = "conflict"            // 配置文件并发修改冲突或正被锁定，可重试
+	ErrCodeInternal           = "internal"            // 执行失败（如终止进程失败）
+)
+
+// Request 协议请求
+type Request struct {
+	V    int      `json:"v"`
+	ID   string   `json:"id,omitempty"`
+	Cmd  string   `json:"cmd"`
+	Name string   `json:"name,omitempty"` // 目标应用名（info/switch/logs/history/dry-run/run），status/stop/restart 为托管实例（app#id）或应用名
+	Args []string `json:"args,omitempty"` // 启动参数（run/dry-run）或命令选项（logs 的 -f、--tail N，history 的 -n N）
+
+	Profile string `json:"profile,omitempty"` // run 使用的 profile，default 表示默认；为空时沿用当前选择
+
+	Token string `json:"token,omitempty"` // 认证令牌（auth）
+}
+
+// Response 协议响应，ok 为 true 时 Result 有效，否则 Error 有效
+type Response struct {
+	V      int             `json:"v"`
+	ID     string          `json:"id,omitempty"`
+	OK     bool            `json:"ok"`
+	Result json.RawMessage `json:"result,omitempty"`
+	Error  *ProtocolError  `json:"error,omitempty"`
+}
+
+// ProtocolError 协议错误，Code 为稳定的机器可读错误码
+type ProtocolError struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *ProtocolError) Error() string {
+	return e.Message
+}
+
+// NewProtocolError 构建协议错误
+func NewProtocolError(code, format string, a ...interface{}) *ProtocolError {
+	return &ProtocolError{Code: code, Message: fmt.Sprintf(format, a...)}
+}
+
+// AsProtocolError 将任意 error 转为 ProtocolError，非协议错误视为 internal
+func AsProtocolError(err error) *ProtocolError {
+	var perr *ProtocolError
+	if errors.As(err, &perr) {
+		return perr
+	}
+	if IsConfigRetryable(err) {
+		return &ProtocolError{Code: ErrCodeConflict, Message: err.Error()}
+	}
+	return &ProtocolError{Code: ErrCodeInternal, Message: err.Error()}
+}
+
+// IsJSONFrame 根据连接的首字节识别协议：'{' 为 JSON-lines，否则为旧文本协议
+func IsJSONFrame(first byte) bool {
+	return first == '{'
+}
+
+// DecodeRequest 解析并校验一行 JSON 请求，失败时返回错误响应；v 为 0（未填写）视为当前版本
+func DecodeRequest(line []byte) (Request, *Response) {
+	var req Request
+	if err := json.Unmarshal(line, &req); err != nil {
+		return req, EncodeResponse("", nil, NewProtocolError(ErrCodeBadRequest, "invalid json: %v", err))
+	}
+	if req.V != 0 && req.V != ProtocolVersion {
+		return req, EncodeResponse(req.ID, nil, NewProtocolError(ErrCodeUnsupportedVersion, "unsupported protocol version %d (server %d)", req.V, ProtocolVersion))
+	}
+	if req.Cmd == "" {
+		return req, EncodeResponse(req.ID, nil, NewProtocolError(ErrCodeBadRequest, "empty command"))
+	}
+	return req, nil
+}
+
+// EncodeResponse 构建响应：err 非 nil 时为错误响应（非协议错误视为 internal），否则编码 result
+func EncodeResponse(id string, result interface{}, err error) *Response {
+	resp := &Response{V: ProtocolVersion, ID: id}
+	if err != nil {
+		resp.Error = AsProtocolError(err)
+		return resp
+	}
+	resp.OK = true
+	if result != nil {
+		data, mErr := json.Marshal(result)
+		if mErr != nil {
+			resp.OK = false
+			resp.Error = NewProtocolError(ErrCodeInternal, "encode result: %v", mErr)
+			return resp
+		}
+		resp.Result = data
+	}
+	return resp
+}
+
+// ParseLegacyRequest 解析旧文本协议的命令行 "cmd" 或 "cmd:arg"（取第一个空格之前的部分）。
+// run:<args> 运行激活应用并透传参数，run:@<profile> [args] 使用指定 profile，run:@ 恢复默认
+func ParseLegacyRequest(line string) (Request, *ProtocolError) {
+	line = strings.TrimSpace(line)
+	if line == "" {
+		return Request{}, NewProtocolError(ErrCodeBadRequest, "empty command")
+	}
+	cmd, _, _ := strings.Cut(line, " ")
+	cmd, arg, _ := strings.Cut(cmd, ":")
+	req := Request{V: ProtocolVersion, Cmd: cmd, Name: arg}
+	if cmd == "run" && arg != "" {
+		req.Name = ""
+		req.Args = strings.Fields(arg)
+		if strings.HasPrefix(arg, "@") {
+			req.Profile = strings.TrimPrefix(req.Args[0], "@")
+			if req.Profile == "" {
+				req.Profile = DefaultProfile
+			}
+			req.Args = req.Args[1:]
+		}
+	}
+	return req, nil
+}
+
+// PingResult ping 命令结果
+type PingResult struct {
+	Version int `json:"version"`
+}
+
+// StatusResult status 命令结果
+type StatusResult struct {
+	Main      AppMainStatus `json:"main"`
+	Status    string        `json:"status"` // 主状态文本
+	Pid       int           `json:"pid"`
+	ExitCode  int           `json:"exit_code"`
+	Detail    string        `json:"detail"`
+	Timestamp time.Time     `json:"timestamp"`
+	Log       string        `json:"log,omitempty"` // 应用输出的日志文件
+
+	App    string `json:"app,omitempty"`    // 托管实例所属的应用
+	Target string `json:"target,omitempty"` // 托管实例标识 <应用名>#<序号>，可作为 status/stop/restart 的目标
+}
+
+// NewStatusResult 由 AppStatus 构建 StatusResult
+func NewStatusResult(s AppStatus) StatusResult {
+	return StatusResult{
+		Main:      s.Main,
+		Status:    s.Main.String(),
+		Pid:       s.Pid,
+		ExitCode:  s.ExitCode,
+		Detail:    s.Detail,
+		Timestamp: s.Timestamp,
+		Log:       s.LogFile,
+	}
+}
+
+// String 与旧文本协议一致的状态描述
+func (s StatusResult) String() string {
+	str := fmt.Sprintf("%s | PID=%d | ExitCode=%d", s.Status, s.Pid, s.ExitCode)
+	if s.Detail != "" {
+		str += " | " + s.Detail
+	}
+	return str
+}
+
+// ManagedResult ps 命令结果：全部托管实例（含已退出的）的状态，按创建顺序
+type ManagedResult struct {
+	Instances []StatusResult `json:"instances"`
+}
+
+// ActivateResult activate 命令结果
+type ActivateResult struct {
+	Name    string `json:"name"`
+	Profile string `json:"profile,omitempty"` // 激活应用当前使用的 profile，空为默认
+}
+
+// ListResult list 命令结果：Apps 为全部应用（手动添加的在前），Scanned 为其中扫描生成的部分
+type ListResult struct {
+	Apps    []string `json:"apps"`
+	Scanned []string `json:"scanned,omitempty"`
+}
+
+// AppInfoResult info 命令结果，Cwd/Env 为解析后的值
+type AppInfoResult struct {
+	Name string            `json:"name"`
+	Path string            `json:"path"`
+	Args []string          `json:"args"`
+	Cwd  string            `json:"cwd,omitempty"`
+	Env  map[string]string `json:"env,omitempty"`
+
+	Resolved   bool   `json:"resolved,omitempty"`   // Path/Args 是否为模板展开后的值（请求 args 含 --resolved）
+	Scanned    bool   `json:"scanned,omitempty"`    // 是否由扫描生成
+	Version    string `json:"version,omitempty"`    // 语义化版本
+	Resolution string `json:"resolution,omitempty"` // 版本约束的解析过程，如 "^18 → node18 (18.20.0)"
+	Source     string `json:"source,omitempty"`     // 激活应用由环境变量或目录版本文件选定时的来源说明
+
+	Profile  string   `json:"profile,omitempty"`  // 启动时使用的 profile，空为默认
+	Profiles []string `json:"profiles,omitempty"` // 应用定义的全部 profile
+}
+
+// LogsResult logs 命令结果：日志文件的最后若干行。带 -f 时随后在同一连接上推送 log 事件
+type LogsResult struct {
+	Name  string   `json:"name"`
+	File  string   `json:"file"`
+	Lines []string `json:"lines"`
+}
+
+// HistoryResult history 命令结果：状态变化记录，按时间升序
+type HistoryResult struct {
+	Entries []HistoryEntry `json:"entries"`
+}
+
+// DryRunResult dry-run 命令结果：按 core 当前状态启动应用将使用的参数及每一段的来源
+type DryRunResult struct {
+	Name string `json:"name"`
+	Path string `json:"path"`
+	ArgPlan
+}
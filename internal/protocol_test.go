@@ -0,0 +1,124 @@
+package internal
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestDecodeRequest(t *testing.T) {
+	cases := []struct {
+		line string
+		want Request
+		code string // 期望的错误码，空表示成功
+	}{
+		{line: `{"v":1,"id":"7","cmd":"info","name":"node18"}`, want: Request{V: 1, ID: "7", Cmd: "info", Name: "node18"}},
+		{line: `{"cmd":"run","args":["--port","1"],"profile":"debug"}`, want: Request{Cmd: "run", Args: []string{"--port", "1"}, Profile: "debug"}},
+		{line: `{"v":2,"id":"8","cmd":"info"}`, code: ErrCodeUnsupportedVersion},
+		{line: `{"v":1,"id":"9"}`, code: ErrCodeBadRequest},
+		{line: `{"v":1,"cmd":`, code: ErrCodeBadRequest},
+		{line: `{"cmd":["x"]}`, code: ErrCodeBadRequest},
+	}
+	for _, c := range cases {
+		req, resp := DecodeRequest([]byte(c.line))
+		if c.code == "" {
+			if resp != nil {
+				t.Errorf("%s: 意外的错误 %+v", c.line, resp.Error)
+			} else if !reflect.DeepEqual(req, c.want) {
+				t.Errorf("%s: got %+v; want %+v", c.line, req, c.want)
+			}
+			continue
+		}
+		if resp == nil || resp.OK || resp.Error == nil || resp.Error.Code != c.code {
+			t.Errorf("%s: 响应 %+v，期望错误码 %s", c.line, resp, c.code)
+			continue
+		}
+		if resp.V != ProtocolVersion || resp.ID != req.ID {
+			t.Errorf("%s: 错误响应的 v/id = %d/%q", c.line, resp.V, resp.ID)
+		}
+	}
+}
+
+func TestEncodeResponseRoundTrip(t *testing.T) {
+	cases := []struct {
+		name   string
+		result interface{}
+		err    error
+		code   string
+	}{
+		{name: "结果", result: ActivateResult{Name: "node18", Profile: "debug"}},
+		{name: "无结果"},
+		{name: "协议错误", err: NewProtocolError(ErrCodeNotFound, "应用 x 不存在"), code: ErrCodeNotFound},
+		{name: "普通错误视为 internal", err: errors.New("boom"), code: ErrCodeInternal},
+		{name: "结果无法编码", result: func() {}, code: ErrCodeInternal},
+	}
+	for _, c := range cases {
+		data, err := json.Marshal(EncodeResponse("42", c.result, c.err))
+		if err != nil {
+			t.Fatalf("%s: %v", c.name, err)
+		}
+		var resp Response
+		if err := json.Unmarshal(data, &resp); err != nil {
+			t.Fatalf("%s: %v", c.name, err)
+		}
+		if resp.V != ProtocolVersion || resp.ID != "42" {
+			t.Errorf("%s: v/id = %d/%q", c.name, resp.V, resp.ID)
+		}
+		if c.code != "" {
+			if resp.OK || resp.Error == nil || resp.Error.Code != c.code {
+				t.Errorf("%s: %s，期望错误码 %s", c.name, data, c.code)
+			}
+			continue
+		}
+		if !resp.OK || resp.Error != nil {
+			t.Errorf("%s: %s，期望成功", c.name, data)
+			continue
+		}
+		if c.result == nil {
+			if resp.Result != nil {
+				t.Errorf("%s: result = %s，期望为空", c.name, resp.Result)
+			}
+			continue
+		}
+		var got ActivateResult
+		if err := json.Unmarshal(resp.Result, &got); err != nil || got != c.result {
+			t.Errorf("%s: result = %s，期望 %+v", c.name, resp.Result, c.result)
+		}
+	}
+}
+
+func TestLegacyFallback(t *testing.T) {
+	// 首字节不是 '{' 的连接（包括非 JSON 的首行）按旧文本协议处理
+	for _, first := range []string{`{"cmd":"auth"}`, "auth:abc", "status", " {", "[1]"} {
+		if got, want := IsJSONFrame(first[0]), first[0] == '{'; got != want {
+			t.Errorf("%q: IsJSONFrame = %v", first, got)
+		}
+	}
+
+	cases := []struct {
+		line string
+		want Request
+		code string
+	}{
+		{line: "status\n", want: Request{V: ProtocolVersion, Cmd: "status"}},
+		{line: "stop:node18#2", want: Request{V: ProtocolVersion, Cmd: "stop", Name: "node18#2"}},
+		{line: "run:--port", want: Request{V: ProtocolVersion, Cmd: "run", Args: []string{"--port"}}},
+		{line: "run:@debug", want: Request{V: ProtocolVersion, Cmd: "run", Args: []string{}, Profile: "debug"}},
+		{line: "run:@", want: Request{V: ProtocolVersion, Cmd: "run", Args: []string{}, Profile: DefaultProfile}},
+		{line: "switch:node20 ignored", want: Request{V: ProtocolVersion, Cmd: "switch", Name: "node20"}},
+		{line: " \r\n", code: ErrCodeBadRequest},
+	}
+	for _, c := range cases {
+		req, perr := ParseLegacyRequest(c.line)
+		if c.code != "" {
+			if perr == nil || perr.Code != c.code {
+				t.Errorf("%q: 错误 %v，期望错误码 %s", c.line, perr, c.code)
+			}
+			continue
+		}
+		if perr != nil || !reflect.DeepEqual(req, c.want) {
+			t.Errorf("%q: got %+v, %v; want %+v", c.line, req, perr, c.want)
+		}
+	}
+}
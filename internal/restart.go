@@ -0,0 +1,102 @@
+package internal
+
+import (
+	"sync"
+	"time"
+)
+
+// 自动重启模式
+const (
+	RestartNever     = "never"      // 不自动重启（默认）
+	RestartOnFailure = "on-failure" // 仅异常退出时重启
+	RestartAlways    = "always"     // 任何退出都重启（主动停止除外）
+)
+
+// 自动重启默认值
+const (
+	DefaultRestartMaxRetries = 5
+	DefaultRestartBackoff    = time.Second
+	DefaultRestartMaxBackoff = time.Minute
+	DefaultRestartResetAfter = time.Minute
+)
+
+// RestartPolicy 自动重启策略，退避时间按 Backoff * 2^n 指数增长，不超过 MaxBackoff
+// 进程运行时间超过 ResetAfter 视为稳定，重试计数清零
+type RestartPolicy struct {
+	Mode       string        `yaml:"mode,omitempty"`        // never / on-failure / always
+	MaxRetries int           `yaml:"max_retries,omitempty"` // 最大连续重试次数，0 为默认 5，负数不限
+	Backoff    time.Duration `yaml:"backoff,omitempty"`     // 首次重启等待时间，默认 1s
+	MaxBackoff time.Duration `yaml:"max_backoff,omitempty"` // 最大等待时间，默认 1m
+	ResetAfter time.Duration `yaml:"reset_after,omitempty"` // 稳定运行窗口，默认 1m
+}
+
+// ModeOrDefault 返回重启模式，未配置时为 never
+func (p RestartPolicy) ModeOrDefault() string {
+	if p.Mode == "" {
+		return RestartNever
+	}
+	return p.Mode
+}
+
+// RestartTracker 记录单个应用的连续重启次数，决定下一次是否重启以及等待多久
+type RestartTracker struct {
+	mu       sync.Mutex
+	attempts int
+}
+
+// Reset 清零重试计数（手动启动/切换时调用）
+func (t *RestartTracker) Reset() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.attempts = 0
+}
+
+// Next 根据策略、是否异常退出以及本次运行时长决定是否重启
+// 返回：等待时长、是否重启、是否已放弃（超过最大重试次数）、本次为第几次重试
+func (t *RestartTracker) Next(p RestartPolicy, failed bool, uptime time.Duration) (delay time.Duration, restart bool, giveUp bool, attempt int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	switch p.ModeOrDefault() {
+	case RestartAlways:
+	case RestartOnFailure:
+		if !failed {
+			return 0, false, false, 0
+		}
+	default:
+		return 0, false, false, 0
+	}
+
+	resetAfter := p.ResetAfter
+	if resetAfter <= 0 {
+		resetAfter = DefaultRestartResetAfter
+	}
+	if uptime >= resetAfter {
+		t.attempts = 0
+	}
+
+	maxRetries := p.MaxRetries
+	if maxRetries == 0 {
+		maxRetries = DefaultRestartMaxRetries
+	}
+	if maxRetries > 0 && t.attempts >= maxRetries {
+		return 0, false, true, t.attempts
+	}
+
+	delay = p.Backoff
+	if delay <= 0 {
+		delay = DefaultRestartBackoff
+	}
+	maxBackoff := p.MaxBackoff
+	if maxBackoff <= 0 {
+		maxBackoff = DefaultRestartMaxBackoff
+	}
+	for i := 0; i < t.attempts && delay < maxBackoff; i++ {
+		delay *= 2
+	}
+	if delay > maxBackoff {
+		delay = maxBackoff
+	}
+	t.attempts++
+	return delay, true, false, t.attempts
+}
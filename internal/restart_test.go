@@ -0,0 +1,71 @@
+package internal
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRestartTrackerNext(t *testing.T) {
+	policy := RestartPolicy{Mode: RestartOnFailure, MaxRetries: 4, Backoff: time.Second, MaxBackoff: 5 * time.Second, ResetAfter: time.Minute}
+	type step struct {
+		failed  bool
+		uptime  time.Duration
+		delay   time.Duration
+		restart bool
+		giveUp  bool
+		attempt int
+	}
+	steps := []step{
+		{failed: true, delay: time.Second, restart: true, attempt: 1},
+		{failed: true, delay: 2 * time.Second, restart: true, attempt: 2},
+		{failed: false, restart: false},                                                    // on-failure：正常退出不重启，也不计数
+		{failed: true, delay: 4 * time.Second, restart: true, attempt: 3},                  // 退避翻倍
+		{failed: true, delay: 5 * time.Second, restart: true, attempt: 4},                  // 不超过 max_backoff
+		{failed: true, giveUp: true, attempt: 4},                                           // 超过 max_retries 放弃
+		{failed: true, uptime: time.Minute, delay: time.Second, restart: true, attempt: 1}, // 稳定运行超过 reset_after 后清零
+		{failed: true, uptime: 59 * time.Second, delay: 2 * time.Second, restart: true, attempt: 2},
+	}
+	var tr RestartTracker
+	for i, s := range steps {
+		delay, restart, giveUp, attempt := tr.Next(policy, s.failed, s.uptime)
+		if delay != s.delay || restart != s.restart || giveUp != s.giveUp || attempt != s.attempt {
+			t.Fatalf("第 %d 步: got (%s, %v, %v, %d); want (%s, %v, %v, %d)", i+1, delay, restart, giveUp, attempt, s.delay, s.restart, s.giveUp, s.attempt)
+		}
+	}
+
+	tr.Reset()
+	if _, _, _, attempt := tr.Next(policy, true, 0); attempt != 1 {
+		t.Errorf("Reset 后 attempt = %d", attempt)
+	}
+}
+
+func TestRestartTrackerModes(t *testing.T) {
+	cases := []struct {
+		policy  RestartPolicy
+		failed  bool
+		restart bool
+		delay   time.Duration
+	}{
+		{policy: RestartPolicy{}, failed: true, restart: false}, // 默认 never
+		{policy: RestartPolicy{Mode: RestartNever}, failed: true, restart: false},
+		{policy: RestartPolicy{Mode: RestartOnFailure}, failed: false, restart: false},
+		{policy: RestartPolicy{Mode: RestartAlways}, failed: false, restart: true, delay: DefaultRestartBackoff},
+		{policy: RestartPolicy{Mode: RestartAlways, Backoff: 2 * time.Minute}, failed: true, restart: true, delay: DefaultRestartMaxBackoff},
+	}
+	for i, c := range cases {
+		var tr RestartTracker
+		delay, restart, giveUp, _ := tr.Next(c.policy, c.failed, 0)
+		if restart != c.restart || giveUp || delay != c.delay {
+			t.Errorf("case %d: got (%s, %v, %v); want (%s, %v)", i, delay, restart, giveUp, c.delay, c.restart)
+		}
+	}
+
+	// max_retries 为负数时不限次数
+	var tr RestartTracker
+	unlimited := RestartPolicy{Mode: RestartAlways, MaxRetries: -1}
+	for i := 0; i < 20; i++ {
+		if _, restart, giveUp, _ := tr.Next(unlimited, true, 0); !restart || giveUp {
+			t.Fatalf("第 %d 次: restart=%v giveUp=%v", i+1, restart, giveUp)
+		}
+	}
+}
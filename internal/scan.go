@@ -0,0 +1,169 @@
+package internal
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"sort"
+)
+
+// 扫描来源：按 glob 匹配安装目录中的可执行文件，用正则从路径中提取版本号，自动生成应用。
+// 扫描到的应用不写回配置文件，与手动添加的应用分组显示；同名时手动添加的优先
+
+// DefaultScanVersion 未配置 version 正则时使用：路径中第一个形如 18 / 3.11 / 1.22.1 的数字串
+const DefaultScanVersion = `\d+(?:\.\d+)*`
+
+// ScanSource 一个扫描来源
+type ScanSource struct {
+	Root    string `yaml:"root,omitempty"`    // 根目录，相对路径相对于配置文件目录；glob 为绝对路径时可省略
+	Glob    string `yaml:"glob"`              // 可执行文件的匹配模式，如 node-*/node.exe、*/bin/go
+	Version string `yaml:"version,omitempty"` // 提取版本号的正则，优先取命名分组 version，其次第一个分组，否则整个匹配
+	Prefix  string `yaml:"prefix,omitempty"`  // 应用名前缀，应用名为 前缀+版本号，如 go1.22.1
+	App     App    `yaml:"app,omitempty"`     // 生成应用的公共配置（args/env/stop/restart 等，path 忽略）
+}
+
+// ScannedApp 一个扫描结果
+type ScannedApp struct {
+	Name    string
+	Version string
+	App     App
+}
+
+// Pattern 返回完整的匹配模式
+func (s ScanSource) Pattern(configDir string) string {
+	if filepath.IsAbs(s.Glob) || s.Root == "" {
+		return s.Glob
+	}
+	return filepath.Join(s.rootDir(configDir), s.Glob)
+}
+
+func (s ScanSource) rootDir(configDir string) string {
+	if s.Root == "" || filepath.IsAbs(s.Root) || configDir == "" {
+		return s.Root
+	}
+	return filepath.Join(configDir, s.Root)
+}
+
+// Compile 编译版本号正则
+func (s ScanSource) Compile() (*regexp.Regexp, error) {
+	expr := s.Version
+	if expr == "" {
+		expr = DefaultScanVersion
+	}
+	return regexp.Compile(expr)
+}
+
+// Discover 执行扫描，按版本号升序返回；路径中提取不到版本号的匹配项忽略。
+// 正则匹配的是相对于 root 的路径（未配置 root 时为完整路径），分隔符统一为 /
+func (s ScanSource) Discover(configDir string) ([]ScannedApp, error) {
+	if s.Glob == "" {
+		return nil, fmt.Errorf("缺少 glob")
+	}
+	re, err := s.Compile()
+	if err != nil {
+		return nil, fmt.Errorf("version 正则无效: %v", err)
+	}
+	pattern := s.Pattern(configDir)
+	if !filepath.IsAbs(pattern) {
+		return nil, fmt.Errorf("匹配模式必须是绝对路径（或配置 root）: %s", pattern)
+	}
+	matches, err := filepath.Glob(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("glob 无效: %v", err)
+	}
+	root := s.rootDir(configDir)
+	var out []ScannedApp
+	for _, path := range matches {
+		subject := path
+		if root != "" {
+			if rel, err := filepath.Rel(root, path); err == nil {
+				subject = rel
+			}
+		}
+		version := extractVersion(re, filepath.ToSlash(subject))
+		if version == "" {
+			continue
+		}
+		app := s.App
+		app.Path = path
+		if app.Version == "" {
+			app.Version = version
+		}
+		out = append(out, ScannedApp{Name: s.Prefix + version, Version: version, App: app})
+	}
+	// 与 sortByVersion 相同：按语义化版本升序（预发布低于正式版本），无法解析的版本保持原顺序排在前面
+	sort.SliceStable(out, func(i, j int) bool {
+		vi, erri := ParseVersion(out[i].Version)
+		vj, errj := ParseVersion(out[j].Version)
+		if erri != nil || errj != nil {
+			return erri != nil && errj == nil
+		}
+		return vi.Compare(vj) < 0
+	})
+	return out, nil
+}
+
+func extractVersion(re *regexp.Regexp, subject string) string {
+	m := re.FindStringSubmatch(subject)
+	if m == nil {
+		return ""
+	}
+	if idx := re.SubexpIndex("version"); idx > 0 {
+		return m[idx]
+	}
+	if len(m) > 1 {
+		return m[1]
+	}
+	return m[0]
+}
+
+// scanResult 一个扫描来源的扫描结果
+type scanResult struct {
+	found []ScannedApp
+	err   error
+}
+
+// scanApps 执行全部扫描来源，填充 Scanned/ScannedOrder，并记录每个来源的结果供 validate 复用。
+// 出错的来源、无效的应用名，以及与手动应用或先出现的扫描结果同名时跳过（均由 validate 报告）
+func (c *Config) scanApps() {
+	c.Scanned = make(map[string]App)
+	c.ScannedOrder = nil
+	c.scanResults = make([]scanResult, len(c.Scan))
+	for i, src := range c.Scan {
+		found, err := src.Discover(c.Dir())
+		c.scanResults[i] = scanResult{found: found, err: err}
+		if err != nil {
+			continue
+		}
+		for _, s := range found {
+			if _, ok := c.LookupApp(s.Name); ok || checkAppName(s.Name) != nil {
+				continue
+			}
+			c.Scanned[s.Name] = s.App
+			c.ScannedOrder = append(c.ScannedOrder, s.Name)
+		}
+	}
+}
+
+// LookupApp 按名称查找应用，手动添加的优先，其次为扫描到的应用
+func (c *Config) LookupApp(name string) (App, bool) {
+	if app, ok := c.Apps[name]; ok {
+		return app, true
+	}
+	app, ok := c.Scanned[name]
+	return app, ok
+}
+
+// IsScanned 判断应用是否由扫描生成
+func (c *Config) IsScanned(name string) bool {
+	if _, ok := c.Apps[name]; ok {
+		return false
+	}
+	_, ok := c.Scanned[name]
+	return ok
+}
+
+// AllApps 返回全部应用名：手动添加的在前，扫描到的在后，组内按版本升序（无版本的保持配置顺序）
+func (c *Config) AllApps() []string {
+	return append(c.sortByVersion(c.AppOrder), c.sortByVersion(c.ScannedOrder)...)
+}
@@ -0,0 +1,114 @@
+package internal
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestScanSources(t *testing.T) {
+	dir := t.TempDir()
+	for _, p := range []string{"go/1.9.7/bin/go", "go/1.22.1/bin/go", "go/1.10/bin/go", "go/tip/bin/go", "node-v18.20.0/node", "node-v20.1.0/node"} {
+		full := filepath.Join(dir, p)
+		if err := os.MkdirAll(filepath.Dir(full), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(full, nil, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	src := `activate: go1.22.1
+apps:
+  go1.10:
+    path: /usr/local/go/bin/go
+scan:
+  - root: go
+    glob: "*/bin/go"
+    prefix: go
+    app:
+      args: ["version"]
+  - glob: ` + filepath.Join(dir, "node-*", "node") + `
+    version: 'node-v(?P<version>\d+)\.'
+    prefix: node
+`
+	path := filepath.Join(dir, "config.yaml")
+	if err := os.WriteFile(path, []byte(src), 0644); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := LoadConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 版本号按数字排序，提取不到版本号的 tip 忽略，与手动应用同名的 go1.10 由手动应用优先
+	want := []string{"go1.9.7", "go1.22.1", "node18", "node20"}
+	if !reflect.DeepEqual(cfg.ScannedOrder, want) {
+		t.Fatalf("scanned = %v, want %v", cfg.ScannedOrder, want)
+	}
+	if got := cfg.AllApps(); !reflect.DeepEqual(got, append([]string{"go1.10"}, want...)) {
+		t.Fatalf("all apps = %v", got)
+	}
+	app, ok := cfg.LookupApp("go1.22.1")
+	if !ok || app.Path != filepath.Join(dir, "go", "1.22.1", "bin", "go") || !reflect.DeepEqual(app.Args, []string{"version"}) {
+		t.Fatalf("go1.22.1 = %+v, %v", app, ok)
+	}
+	if app, _ := cfg.LookupApp("go1.10"); app.Path != "/usr/local/go/bin/go" || cfg.IsScanned("go1.10") {
+		t.Fatalf("manual app overridden: %+v", app)
+	}
+
+	// 激活扫描到的应用不是错误；重名只给出警告
+	findings := ValidateConfig(cfg)
+	if err := findings.Err(); err != nil {
+		t.Fatalf("unexpected errors: %v", err)
+	}
+
+	// 校验复用加载时的扫描结果，不再重新扫描
+	for _, p := range []string{"node-v18.20.0", "node-v20.1.0"} {
+		if err := os.RemoveAll(filepath.Join(dir, p)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, f := range ValidateConfig(cfg) {
+		if strings.HasPrefix(f.Field, "scan[1]") {
+			t.Errorf("校验重新扫描了来源: %+v", f)
+		}
+	}
+
+	// 扫描结果不写回配置文件
+	cfg.Activate = "node20"
+	if err := SaveConfig(cfg, path); err != nil {
+		t.Fatal(err)
+	}
+	data, _ := os.ReadFile(path)
+	if string(data) != strings.Replace(src, "activate: go1.22.1", "activate: node20", 1) {
+		t.Fatalf("saved config:\n%s", data)
+	}
+}
+
+func TestScanSortsBySemver(t *testing.T) {
+	dir := t.TempDir()
+	for _, v := range []string{"1.10.0", "1.0.0", "1.0.0-rc1", "1.9.0", "1.0.0-rc.10", "1.0.0-rc.2"} {
+		full := filepath.Join(dir, "tool-"+v, "tool")
+		if err := os.MkdirAll(filepath.Dir(full), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(full, nil, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	apps, err := ScanSource{Glob: filepath.Join(dir, "tool-*", "tool"), Version: `tool-(?P<version>[^/\\]+)`, Prefix: "t"}.Discover(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, a := range apps {
+		got = append(got, a.Version)
+	}
+	// 预发布版本低于正式版本，预发布标识中的数字段按数值比较
+	want := []string{"1.0.0-rc.2", "1.0.0-rc.10", "1.0.0-rc1", "1.0.0", "1.9.0", "1.10.0"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+}
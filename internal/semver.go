@@ -0,0 +1,310 @@
+package internal
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// 语义化版本：应用可以在配置中声明 version，扫描生成的应用使用提取到的版本号。
+// 解析较宽松：允许 v 前缀与省略次版本/修订号（18、3.11），不支持构建元数据之外的扩展写法
+
+// Version 解析后的语义化版本
+type Version struct {
+	Major, Minor, Patch int
+	Pre                 string // 预发布标识，如 rc.1
+	Raw                 string // 原始字符串
+	parts               int    // 实际给出的数字段数（1~3），用于约束中的部分版本
+}
+
+func (v Version) String() string {
+	s := fmt.Sprintf("%d.%d.%d", v.Major, v.Minor, v.Patch)
+	if v.Pre != "" {
+		s += "-" + v.Pre
+	}
+	return s
+}
+
+// ParseVersion 解析版本字符串，如 1.22.1、v18、3.11.0-rc.1、1.2.3+build
+func ParseVersion(s string) (Version, error) {
+	v := Version{Raw: s}
+	str := strings.TrimPrefix(strings.TrimSpace(s), "v")
+	if i := strings.IndexByte(str, '+'); i != -1 {
+		str = str[:i]
+	}
+	if i := strings.IndexByte(str, '-'); i != -1 {
+		v.Pre = str[i+1:]
+		str = str[:i]
+		if v.Pre == "" {
+			return Version{}, fmt.Errorf("无效的版本号: %s", s)
+		}
+	}
+	fields := strings.Split(str, ".")
+	if len(fields) > 3 || str == "" {
+		return Version{}, fmt.Errorf("无效的版本号: %s", s)
+	}
+	nums := []*int{&v.Major, &v.Minor, &v.Patch}
+	for i, f := range fields {
+		n, err := strconv.Atoi(f)
+		if err != nil || n < 0 {
+			return Version{}, fmt.Errorf("无效的版本号: %s", s)
+		}
+		*nums[i] = n
+	}
+	v.parts = len(fields)
+	return v, nil
+}
+
+// Compare 比较两个版本，返回 -1/0/1；预发布版本低于对应的正式版本
+func (v Version) Compare(o Version) int {
+	for _, d := range []int{v.Major - o.Major, v.Minor - o.Minor, v.Patch - o.Patch} {
+		if d != 0 {
+			return sign(d)
+		}
+	}
+	switch {
+	case v.Pre == o.Pre:
+		return 0
+	case v.Pre == "":
+		return 1
+	case o.Pre == "":
+		return -1
+	}
+	return comparePre(v.Pre, o.Pre)
+}
+
+// comparePre 按 semver 规则比较预发布标识：逐段比较，数字段按数值且低于非数字段
+func comparePre(a, b string) int {
+	pa, pb := strings.Split(a, "."), strings.Split(b, ".")
+	for i := 0; i < len(pa) && i < len(pb); i++ {
+		na, errA := strconv.Atoi(pa[i])
+		nb, errB := strconv.Atoi(pb[i])
+		switch {
+		case errA == nil && errB == nil:
+			if na != nb {
+				return sign(na - nb)
+			}
+		case errA == nil:
+			return -1
+		case errB == nil:
+			return 1
+		case pa[i] != pb[i]:
+			return strings.Compare(pa[i], pb[i])
+		}
+	}
+	return sign(len(pa) - len(pb))
+}
+
+func sign(n int) int {
+	switch {
+	case n < 0:
+		return -1
+	case n > 0:
+		return 1
+	}
+	return 0
+}
+
+// Constraint 版本约束：^18、~3.11、latest、18.x、>=1.20、=1.22.1 等
+type Constraint struct {
+	Raw   string
+	lower *Version // 下限（含）
+	upper *Version // 上限（不含）
+	exact *Version // 精确匹配（含预发布）
+}
+
+// ParseConstraint 解析版本约束：
+//
+//	latest / *    任意正式版本中的最高者
+//	^1.2.3        >=1.2.3 <2.0.0（主版本为 0 时锁定次版本）
+//	~1.2          >=1.2.0 <1.3.0（只给主版本时锁定主版本）
+//	1 / 1.2 / 1.x 该前缀下的任意版本
+//	=1.2.3        精确匹配
+//	>=1.2 / >1.2 / <=1.2 / <1.2
+func ParseConstraint(s string) (Constraint, error) {
+	c := Constraint{Raw: s}
+	str := strings.TrimSpace(s)
+	if str == "latest" || str == "*" {
+		return c, nil
+	}
+	op := ""
+	for _, p := range []string{">=", "<=", "^", "~", "=", ">", "<"} {
+		if strings.HasPrefix(str, p) {
+			op, str = p, strings.TrimSpace(str[len(p):])
+			break
+		}
+	}
+	for strings.HasSuffix(str, ".x") || strings.HasSuffix(str, ".*") {
+		str = str[:len(str)-2]
+	}
+	v, err := ParseVersion(str)
+	if err != nil {
+		return Constraint{}, fmt.Errorf("无效的版本约束: %s", s)
+	}
+	lower := Version{Major: v.Major, Minor: v.Minor, Patch: v.Patch, Pre: v.Pre}
+	// next 返回部分版本的下一个前缀，如 1.2 → 1.3.0，1 → 2.0.0
+	next := func(parts int) *Version {
+		switch parts {
+		case 1:
+			return &Version{Major: v.Major + 1}
+		case 2:
+			return &Version{Major: v.Major, Minor: v.Minor + 1}
+		}
+		return &Version{Major: v.Major, Minor: v.Minor, Patch: v.Patch + 1}
+	}
+	switch op {
+	case "^":
+		c.lower = &lower
+		switch {
+		case v.Major > 0 || v.parts == 1:
+			c.upper = next(1)
+		case v.Minor > 0 || v.parts == 2:
+			c.upper = next(2)
+		default:
+			c.upper = next(3)
+		}
+	case "~":
+		c.lower = &lower
+		if v.parts == 1 {
+			c.upper = next(1)
+		} else {
+			c.upper = next(2)
+		}
+	case "=", "":
+		if v.parts == 3 {
+			c.exact = &lower
+		} else {
+			c.lower, c.upper = &lower, next(v.parts)
+		}
+	case ">=":
+		c.lower = &lower
+	case ">":
+		c.lower = next(v.parts)
+	case "<":
+		c.upper = &lower
+	case "<=":
+		c.upper = next(v.parts)
+	}
+	return c, nil
+}
+
+// Match 判断版本是否满足约束；除精确匹配外不匹配预发布版本
+func (c Constraint) Match(v Version) bool {
+	if c.exact != nil {
+		return v.Compare(*c.exact) == 0
+	}
+	if v.Pre != "" {
+		return false
+	}
+	if c.lower != nil && v.Compare(*c.lower) < 0 {
+		return false
+	}
+	if c.upper != nil && v.Compare(*c.upper) >= 0 {
+		return false
+	}
+	return true
+}
+
+func (c Constraint) String() string {
+	return c.Raw
+}
+
+// AppVersion 返回应用的语义化版本（配置中的 version 或扫描提取的版本号），无法解析时 ok 为 false
+func (c *Config) AppVersion(name string) (Version, bool) {
+	app, ok := c.LookupApp(name)
+	if !ok || app.Version == "" {
+		return Version{}, false
+	}
+	v, err := ParseVersion(app.Version)
+	return v, err == nil
+}
+
+// sortByVersion 按版本升序排序，没有版本的应用保持原顺序排在前面
+func (c *Config) sortByVersion(names []string) []string {
+	out := append([]string(nil), names...)
+	sort.SliceStable(out, func(i, j int) bool {
+		vi, oki := c.AppVersion(out[i])
+		vj, okj := c.AppVersion(out[j])
+		if !oki || !okj {
+			return !oki && okj
+		}
+		return vi.Compare(vj) < 0
+	})
+	return out
+}
+
+// Resolution 应用名或版本约束的解析结果
+type Resolution struct {
+	Spec    string // 输入的应用名或约束
+	Name    string // 解析到的应用名
+	Version string // 解析到的版本，应用未声明版本时为空
+}
+
+// IsConstraint 是否由版本约束解析而来
+func (r Resolution) IsConstraint() bool {
+	return r.Spec != r.Name
+}
+
+func (r Resolution) String() string {
+	if r.Version != "" {
+		return fmt.Sprintf("%s → %s (%s)", r.Spec, r.Name, r.Version)
+	}
+	return fmt.Sprintf("%s → %s", r.Spec, r.Name)
+}
+
+// ResolveApp 将应用名或版本约束解析为应用：精确的应用名优先，否则按约束选出满足条件的最高版本
+func (c *Config) ResolveApp(spec string) (Resolution, error) {
+	if _, ok := c.LookupApp(spec); ok {
+		r := Resolution{Spec: spec, Name: spec}
+		if v, ok := c.AppVersion(spec); ok {
+			r.Version = v.String()
+		}
+		return r, nil
+	}
+	con, err := ParseConstraint(spec)
+	if err != nil {
+		return Resolution{}, fmt.Errorf("未找到应用: %s", spec)
+	}
+	var best string
+	var bestVer Version
+	for _, name := range c.AllApps() {
+		v, ok := c.AppVersion(name)
+		if !ok || !con.Match(v) {
+			continue
+		}
+		if best == "" || v.Compare(bestVer) > 0 {
+			best, bestVer = name, v
+		}
+	}
+	if best == "" {
+		return Resolution{}, fmt.Errorf("没有满足 %s 的已安装版本", spec)
+	}
+	return Resolution{Spec: spec, Name: best, Version: bestVer.String()}, nil
+}
+
+// SetActivate 按解析结果设置激活应用：由约束解析而来时记录约束，直接指定应用名时清除
+func (c *Config) SetActivate(r Resolution) {
+	c.Activate = r.Name
+	c.ActivateRange = ""
+	if r.IsConstraint() {
+		c.ActivateRange = r.Spec
+	}
+}
+
+// ResolutionLine 返回 info 中显示的解析说明：由约束解析的应用显示解析过程；
+// 查看激活应用且其来自约束时，若约束现在解析到其它版本（如新安装了版本）一并提示
+func (c *Config) ResolutionLine(r Resolution, activate bool) string {
+	if r.IsConstraint() {
+		return r.String()
+	}
+	if !activate || c.ActivateRange == "" || r.Name != c.Activate {
+		return ""
+	}
+	recorded := Resolution{Spec: c.ActivateRange, Name: r.Name, Version: r.Version}
+	best, err := c.ResolveApp(c.ActivateRange)
+	if err != nil || best.Name == r.Name {
+		return recorded.String()
+	}
+	return fmt.Sprintf("%s，当前最佳匹配为 %s", recorded, best.Name)
+}
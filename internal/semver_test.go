@@ -0,0 +1,123 @@
+package internal
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestVersionCompare(t *testing.T) {
+	ordered := []string{"1.0.0-alpha", "1.0.0-alpha.1", "1.0.0-beta", "1.0.0-rc.1", "1.0.0", "v1.2", "1.10.0", "2"}
+	for i := 0; i+1 < len(ordered); i++ {
+		a, err := ParseVersion(ordered[i])
+		if err != nil {
+			t.Fatal(err)
+		}
+		b, err := ParseVersion(ordered[i+1])
+		if err != nil {
+			t.Fatal(err)
+		}
+		if a.Compare(b) >= 0 || b.Compare(a) <= 0 {
+			t.Errorf("expected %s < %s", ordered[i], ordered[i+1])
+		}
+	}
+	for _, bad := range []string{"", "node18", "1.2.3.4", "1.x", "1.0-"} {
+		if _, err := ParseVersion(bad); err == nil {
+			t.Errorf("ParseVersion(%q) succeeded", bad)
+		}
+	}
+}
+
+func TestConstraintMatch(t *testing.T) {
+	for _, c := range []struct {
+		constraint string
+		match      []string
+		reject     []string
+	}{
+		{"^18", []string{"18.0.0", "18.20.1"}, []string{"17.9.9", "19.0.0", "18.1.0-rc.1"}},
+		{"^0.3.1", []string{"0.3.1", "0.3.9"}, []string{"0.4.0", "0.3.0"}},
+		{"~3.11", []string{"3.11.0", "3.11.9"}, []string{"3.12.0", "3.10.4"}},
+		{"~3", []string{"3.0.0", "3.12.1"}, []string{"4.0.0"}},
+		{"1.22", []string{"1.22.0", "1.22.7"}, []string{"1.23.0"}},
+		{"1.x", []string{"1.0.0", "1.99.0"}, []string{"2.0.0"}},
+		{"=1.22.1", []string{"1.22.1"}, []string{"1.22.2"}},
+		{">=1.20", []string{"1.20.0", "2.0.0"}, []string{"1.19.9"}},
+		{"<1.20", []string{"1.19.9"}, []string{"1.20.0"}},
+		{"<=1.20", []string{"1.20.5"}, []string{"1.21.0"}},
+		{"latest", []string{"0.0.1", "99.0.0"}, []string{"2.0.0-beta"}},
+	} {
+		con, err := ParseConstraint(c.constraint)
+		if err != nil {
+			t.Fatalf("ParseConstraint(%q): %v", c.constraint, err)
+		}
+		for _, s := range c.match {
+			if v, _ := ParseVersion(s); !con.Match(v) {
+				t.Errorf("%s should match %s", c.constraint, s)
+			}
+		}
+		for _, s := range c.reject {
+			if v, _ := ParseVersion(s); con.Match(v) {
+				t.Errorf("%s should not match %s", c.constraint, s)
+			}
+		}
+	}
+	if _, err := ParseConstraint("node18"); err == nil {
+		t.Error("app name parsed as constraint")
+	}
+}
+
+func TestResolveApp(t *testing.T) {
+	cfg := &Config{
+		Apps: map[string]App{
+			"legacy": {Path: "/bin/legacy"},
+			"node20": {Path: "/opt/node20/bin/node", Version: "20.11.0"},
+			"node18": {Path: "/opt/node18/bin/node", Version: "18.20.2"},
+			"node16": {Path: "/opt/node16/bin/node", Version: "16.20.0"},
+		},
+		AppOrder:     []string{"node20", "legacy", "node18", "node16"},
+		Scanned:      map[string]App{"node18.19.0": {Path: "/opt/n/18.19.0/node", Version: "18.19.0"}, "node21": {Path: "/opt/n/21/node", Version: "21.0.0-nightly"}},
+		ScannedOrder: []string{"node18.19.0", "node21"},
+	}
+
+	// 手动应用与扫描应用各自按版本排序，无版本的排在组内最前
+	want := []string{"legacy", "node16", "node18", "node20", "node18.19.0", "node21"}
+	if got := cfg.AllApps(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("AllApps = %v, want %v", got, want)
+	}
+
+	for spec, name := range map[string]string{
+		"legacy": "legacy",
+		"node18": "node18",
+		"^18":    "node18",
+		"~18.19": "node18.19.0",
+		"latest": "node20", // 预发布版本不参与
+		"<18":    "node16",
+	} {
+		res, err := cfg.ResolveApp(spec)
+		if err != nil {
+			t.Errorf("ResolveApp(%q): %v", spec, err)
+			continue
+		}
+		if res.Name != name {
+			t.Errorf("ResolveApp(%q) = %s, want %s", spec, res.Name, name)
+		}
+	}
+	if _, err := cfg.ResolveApp("^22"); err == nil {
+		t.Error("ResolveApp(^22) succeeded")
+	}
+
+	// 通过约束切换时记录约束，info 显示解析过程
+	res, _ := cfg.ResolveApp("^18")
+	cfg.SetActivate(res)
+	if cfg.Activate != "node18" || cfg.ActivateRange != "^18" {
+		t.Fatalf("activate = %s, range = %s", cfg.Activate, cfg.ActivateRange)
+	}
+	cur, _ := cfg.ResolveApp(cfg.Activate)
+	if got := cfg.ResolutionLine(cur, true); got != "^18 → node18 (18.20.2)" {
+		t.Errorf("resolution = %q", got)
+	}
+	res, _ = cfg.ResolveApp("node20")
+	cfg.SetActivate(res)
+	if cfg.ActivateRange != "" {
+		t.Errorf("range not cleared: %s", cfg.ActivateRange)
+	}
+}
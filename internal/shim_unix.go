@@ -0,0 +1,26 @@
+//go:build unix
+
+package internal
+
+import (
+	"fmt"
+	"strings"
+)
+
+func shimFileName(tool string) string {
+	return tool
+}
+
+// shimScript 生成 sh 脚本，exec 替换 shell 进程，参数原样透传
+func shimScript(evsPath, configPath, tool string) string {
+	return fmt.Sprintf("#!/bin/sh\n# %s%s\nexec %s --config %s exec %s \"$@\"\n",
+		shimMarker, configPath, shellQuote(evsPath), shellQuote(configPath), shellQuote(tool))
+}
+
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+func consoleExecutable(exe string) string {
+	return exe
+}
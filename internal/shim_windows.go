@@ -0,0 +1,32 @@
+//go:build windows
+
+package internal
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+func shimFileName(tool string) string {
+	return tool + ".cmd"
+}
+
+// shimScript 生成 cmd 脚本，%* 透传全部参数，退出码沿用 evs 的退出码
+func shimScript(evsPath, configPath, tool string) string {
+	return fmt.Sprintf("@echo off\r\nrem %s%s\r\n\"%s\" --config \"%s\" exec %s %%*\r\nexit /b %%ERRORLEVEL%%\r\n",
+		shimMarker, configPath, evsPath, configPath, tool)
+}
+
+// consoleExecutable GUI 版 evs.exe 没有控制台，shim 改用同目录下的 evs-console.exe 以继承终端输入输出
+func consoleExecutable(exe string) string {
+	if !strings.EqualFold(filepath.Base(exe), "evs.exe") {
+		return exe
+	}
+	console := filepath.Join(filepath.Dir(exe), "evs-console.exe")
+	if _, err := os.Stat(console); err == nil {
+		return console
+	}
+	return exe
+}
@@ -0,0 +1,246 @@
+package internal
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// 工具组与 shim：每个应用版本除 path 外还可以通过 tools 提供同组的其它工具（如 node 附带 npm/npx），
+// evs shims 为组内每个工具在 bin 目录生成 shim 脚本，shim 调用 evs exec <tool>，
+// 按 环境变量 > 目录版本文件 > activate 选出当前版本后运行该版本的对应工具，同组工具随版本一起切换。
+// 一个配置文件（实例）即一个工具组，多个组各用一个配置文件，shim 可以生成到同一个目录
+
+// shimMarker 写在 shim 中的标记行，用于识别由 evs 生成的 shim 及其所属配置
+const shimMarker = "evs shim, config: "
+
+// ShimsDir 返回 shim 目录：配置中的 shims_dir（相对于配置文件目录），默认 <用户配置目录>/evs/shims
+func (c *Config) ShimsDir() (string, error) {
+	if c.ShimsDirPath != "" {
+		return resolvePath(os.ExpandEnv(c.ShimsDirPath), c.Dir()), nil
+	}
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("获取用户配置目录失败: %v", err)
+	}
+	return filepath.Join(dir, "evs", "shims"), nil
+}
+
+// primaryTool 应用 path 对应的工具名：可执行文件名去掉扩展名，如 /opt/node18/bin/node → node
+func primaryTool(path string) string {
+	base := filepath.Base(path)
+	return strings.TrimSuffix(base, filepath.Ext(base))
+}
+
+// AppTools 返回应用提供的全部工具（工具名 → 可执行文件绝对路径），
+// tools 中的相对路径相对于 path 所在目录（path 中的模板已展开）
+func (c *Config) AppTools(name string) map[string]string {
+	app, ok := c.LookupApp(name)
+	if !ok {
+		return nil
+	}
+	if expanded, err := c.ExpandApp(name, app); err == nil {
+		app = expanded
+	}
+	tools := make(map[string]string, len(app.Tools)+1)
+	if app.Path != "" {
+		tools[primaryTool(app.Path)] = app.Path
+	}
+	for tool, exe := range app.Tools {
+		tools[tool] = resolvePath(os.ExpandEnv(exe), filepath.Dir(app.Path))
+	}
+	return tools
+}
+
+// ToolNames 返回组内所有应用提供的工具名（排序）
+func (c *Config) ToolNames() []string {
+	seen := make(map[string]bool)
+	var names []string
+	for _, app := range c.AllApps() {
+		for tool := range c.AppTools(app) {
+			if !seen[tool] {
+				seen[tool] = true
+				names = append(names, tool)
+			}
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ToolPath 返回应用提供的指定工具的路径
+func (c *Config) ToolPath(name, tool string) (string, error) {
+	path, ok := c.AppTools(name)[tool]
+	if !ok {
+		return "", fmt.Errorf("应用 %s 未提供工具 %s", name, tool)
+	}
+	return path, nil
+}
+
+// ShimTarget 返回 shim 调用的 evs 可执行文件
+func ShimTarget() (string, error) {
+	exe, err := os.Executable()
+	if err != nil {
+		return "", err
+	}
+	if resolved, err := filepath.EvalSymlinks(exe); err == nil {
+		exe = resolved
+	}
+	return consoleExecutable(exe), nil
+}
+
+// GenerateShims 在 dir 中为组内每个工具生成 shim，并删除本配置以前生成、现已不存在的工具的 shim。
+// 同名 shim 属于其它配置时跳过并在 skipped 中返回
+func GenerateShims(cfg *Config, dir, evsPath string) (written, removed, skipped []string, err error) {
+	configPath, err := filepath.Abs(cfg.Source)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, nil, nil, err
+	}
+	tools := make(map[string]bool)
+	for _, tool := range cfg.ToolNames() {
+		tools[tool] = true
+		path := filepath.Join(dir, shimFileName(tool))
+		if owner := shimOwner(path); owner != "" && !samePath(owner, configPath) {
+			skipped = append(skipped, fmt.Sprintf("%s（属于 %s）", tool, owner))
+			continue
+		} else if owner == "" {
+			if _, statErr := os.Stat(path); statErr == nil {
+				skipped = append(skipped, fmt.Sprintf("%s（%s 不是 evs 生成的文件）", tool, path))
+				continue
+			}
+		}
+		if err := os.WriteFile(path, []byte(shimScript(evsPath, configPath, tool)), 0755); err != nil {
+			return written, removed, skipped, err
+		}
+		written = append(written, tool)
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return written, removed, skipped, err
+	}
+	for _, e := range entries {
+		path := filepath.Join(dir, e.Name())
+		tool := strings.TrimSuffix(e.Name(), filepath.Ext(shimFileName("x")))
+		if tools[tool] || e.IsDir() {
+			continue
+		}
+		if owner := shimOwner(path); owner != "" && samePath(owner, configPath) {
+			if err := os.Remove(path); err == nil {
+				removed = append(removed, tool)
+			}
+		}
+	}
+	return written, removed, skipped, nil
+}
+
+// shimOwner 返回 shim 所属的配置文件，不是 evs 生成的文件时返回空
+func shimOwner(path string) string {
+	f, err := os.Open(path)
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+	sc := bufio.NewScanner(f)
+	for i := 0; i < 3 && sc.Scan(); i++ {
+		if idx := strings.Index(sc.Text(), shimMarker); idx != -1 {
+			return strings.TrimSpace(sc.Text()[idx+len(shimMarker):])
+		}
+	}
+	return ""
+}
+
+// ShimsCommand 执行 shims 命令：shims [--dir <dir>]
+func ShimsCommand(cfg *Config, args []string) bool {
+	dir := ""
+	for i := 0; i < len(args); i++ {
+		switch {
+		case args[i] == "--dir" && i+1 < len(args):
+			dir = args[i+1]
+			i++
+		case strings.HasPrefix(args[i], "--dir="):
+			dir = strings.TrimPrefix(args[i], "--dir=")
+		default:
+			fmt.Println("用法: shims [--dir <dir>]")
+			return false
+		}
+	}
+	if dir == "" {
+		var err error
+		if dir, err = cfg.ShimsDir(); err != nil {
+			fmt.Println(err)
+			return false
+		}
+	}
+	evsPath, err := ShimTarget()
+	if err != nil {
+		fmt.Printf("获取 evs 路径失败: %v\n", err)
+		return false
+	}
+	written, removed, skipped, err := GenerateShims(cfg, dir, evsPath)
+	if err != nil {
+		fmt.Printf("生成 shim 失败: %v\n", err)
+		return false
+	}
+	if len(written) == 0 && len(skipped) == 0 {
+		fmt.Println("没有可生成 shim 的工具（应用的 path 与 tools）")
+	}
+	if len(written) > 0 {
+		fmt.Printf("已生成 shim: %s\n", strings.Join(written, " "))
+	}
+	if len(removed) > 0 {
+		fmt.Printf("已删除过期 shim: %s\n", strings.Join(removed, " "))
+	}
+	for _, s := range skipped {
+		fmt.Printf("已跳过: %s\n", s)
+	}
+	fmt.Printf("shim 目录: %s\n", dir)
+	if !inPath(dir) {
+		fmt.Println("提示: 该目录不在 PATH 中，请将其加入 PATH（放在其它安装目录之前）")
+	}
+	return len(skipped) == 0
+}
+
+func inPath(dir string) bool {
+	for _, p := range filepath.SplitList(os.Getenv("PATH")) {
+		if p != "" && samePath(p, dir) {
+			return true
+		}
+	}
+	return false
+}
+
+// ExecTool 执行 exec 命令：选出当前版本（环境变量 > 目录版本文件 > activate），
+// 以前台模式（见 RunForeground）运行该版本的工具，返回应使用的退出码
+func ExecTool(cfg *Config, tool string, args []string) int {
+	wd, _ := os.Getwd()
+	sel, err := cfg.SelectApp(wd)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "evs: %v\n", err)
+		return 1
+	}
+	path, err := cfg.ToolPath(sel.Name, tool)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "evs: %v（来源: %s）\n", err, sel.Describe(cfg))
+		return 1
+	}
+	app, _ := cfg.LookupApp(sel.Name)
+	env, err := ResolveAppEnv(app, cfg.Dir())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "evs: %v\n", err)
+		return 1
+	}
+
+	// 工作目录保持调用者的当前目录
+	code, err := RunForeground(path, args, ProcessOptions{Env: env.Environ()})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "evs: 启动 %s 失败: %v\n", path, err)
+	}
+	return code
+}
@@ -0,0 +1,84 @@
+package internal
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestGenerateShims(t *testing.T) {
+	dir := t.TempDir()
+	bin := filepath.Join(dir, "bin")
+	configPath := filepath.Join(dir, "node.yaml")
+	src := `activate: node18
+apps:
+  node18:
+    path: /opt/node18/bin/node
+    tools:
+      npm: npm
+      npx: ../lib/npx
+  node20:
+    path: /opt/node20/bin/node
+    tools:
+      npm: npm
+      corepack: corepack
+`
+	if err := os.WriteFile(configPath, []byte(src), 0644); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := LoadConfig(configPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := cfg.ToolNames(); !reflect.DeepEqual(got, []string{"corepack", "node", "npm", "npx"}) {
+		t.Fatalf("tools = %v", got)
+	}
+	if p, _ := cfg.ToolPath("node18", "npx"); p != filepath.Join("/opt/node18/lib/npx") {
+		t.Fatalf("npx path = %s", p)
+	}
+	if _, err := cfg.ToolPath("node18", "corepack"); err == nil {
+		t.Fatal("node18 should not provide corepack")
+	}
+
+	// 其它配置生成的同名 shim 与非 evs 生成的文件都不覆盖
+	if err := os.MkdirAll(bin, 0755); err != nil {
+		t.Fatal(err)
+	}
+	other := filepath.Join(bin, shimFileName("corepack"))
+	if err := os.WriteFile(other, []byte(shimScript("/usr/bin/evs", "/etc/other.yaml", "corepack")), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(bin, shimFileName("npx")), []byte("user script\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	written, removed, skipped, err := GenerateShims(cfg, bin, "/usr/bin/evs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(written, []string{"node", "npm"}) || len(removed) != 0 || len(skipped) != 2 {
+		t.Fatalf("written %v, removed %v, skipped %v", written, removed, skipped)
+	}
+	data, _ := os.ReadFile(filepath.Join(bin, shimFileName("npm")))
+	if !strings.Contains(string(data), "exec") || shimOwner(filepath.Join(bin, shimFileName("npm"))) != cfg.Source {
+		t.Fatalf("npm shim:\n%s", data)
+	}
+
+	// 工具不再由任何版本提供时删除本配置生成的 shim
+	delete(cfg.Apps, "node20")
+	cfg.AppOrder = []string{"node18"}
+	cfg.Apps["node18"] = App{Path: "/opt/node18/bin/node"}
+	_, removed, _, err = GenerateShims(cfg, bin, "/usr/bin/evs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(removed, []string{"npm"}) {
+		t.Fatalf("removed = %v", removed)
+	}
+	if _, err := os.Stat(other); err != nil {
+		t.Fatalf("shim of other config removed: %v", err)
+	}
+}
@@ -0,0 +1,164 @@
+package internal
+
+import (
+	"crypto/sha1"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// 默认控制 socket 地址（默认配置文件未配置 socket 与 name 时使用，兼容旧版本）
+const DefaultSocketAddress = "127.0.0.1:50505"
+
+// DefaultConfigFile 默认配置文件名
+const DefaultConfigFile = "config.yaml"
+
+// SocketAddr 控制 socket 地址，Network 为 tcp 或 unix
+type SocketAddr struct {
+	Network string
+	Address string
+}
+
+func (a SocketAddr) String() string {
+	return a.Network + "://" + a.Address
+}
+
+// ParseSocketAddr 解析 config.yaml 中的 socket 配置，客户端与服务端共用：
+//   - 空：tcp://127.0.0.1:50505
+//   - host:port 或 tcp://host:port：TCP
+//   - unix:///path/evs.sock 或 unix:path：Unix domain socket（Windows 10+ 同样支持 AF_UNIX）
+//   - user：当前用户专属的 Unix domain socket，<用户配置目录>/evs/<instance>.sock
+func ParseSocketAddr(s, instance string) (SocketAddr, error) {
+	s = strings.TrimSpace(s)
+	switch {
+	case s == "":
+		return SocketAddr{Network: "tcp", Address: DefaultSocketAddress}, nil
+	case s == "user":
+		dir, err := os.UserConfigDir()
+		if err != nil {
+			return SocketAddr{}, fmt.Errorf("获取用户配置目录失败: %v", err)
+		}
+		if instance == "" {
+			instance = DefaultInstanceName
+		}
+		return SocketAddr{Network: "unix", Address: filepath.Join(dir, "evs", instance+".sock")}, nil
+	case strings.HasPrefix(s, "unix://"):
+		return unixSocketAddr(strings.TrimPrefix(s, "unix://"))
+	case strings.HasPrefix(s, "unix:"):
+		return unixSocketAddr(strings.TrimPrefix(s, "unix:"))
+	}
+	addr := strings.TrimPrefix(s, "tcp://")
+	if _, _, err := net.SplitHostPort(addr); err != nil {
+		return SocketAddr{}, fmt.Errorf("socket 地址格式错误 %q: %v", s, err)
+	}
+	return SocketAddr{Network: "tcp", Address: addr}, nil
+}
+
+func unixSocketAddr(path string) (SocketAddr, error) {
+	if path == "" {
+		return SocketAddr{}, fmt.Errorf("unix socket 路径为空")
+	}
+	abs, err := filepath.Abs(os.ExpandEnv(path))
+	if err != nil {
+		return SocketAddr{}, err
+	}
+	return SocketAddr{Network: "unix", Address: abs}, nil
+}
+
+// SocketAddr 返回配置对应的控制 socket 地址。未配置 socket 时，只有未设置 name 的默认配置文件（config.yaml）
+// 使用兼容旧版本的 127.0.0.1:50505，其它配置按实例使用各自的 user socket，避免多个 core 争用同一地址
+func (c *Config) SocketAddr() (SocketAddr, error) {
+	if strings.TrimSpace(c.Socket) == "" && (c.Name != "" || filepath.Base(c.Source) != DefaultConfigFile) {
+		return ParseSocketAddr("user", c.defaultSocketName())
+	}
+	return ParseSocketAddr(c.Socket, c.InstanceName())
+}
+
+// defaultSocketName 未配置 socket 时的 user socket 文件名：设置了 name 时为 name，
+// 否则为配置文件名加其绝对路径的哈希（如 node-1a2b3c4d），不同目录下的同名配置互不冲突
+func (c *Config) defaultSocketName() string {
+	if c.Name != "" {
+		return c.Name
+	}
+	path := c.Source
+	if abs, err := filepath.Abs(path); err == nil {
+		path = abs
+	}
+	sum := sha1.Sum([]byte(path))
+	return fmt.Sprintf("%s-%x", strings.TrimSuffix(filepath.Base(path), filepath.Ext(path)), sum[:4])
+}
+
+// ResolveSocketAddr 读取配置文件并解析 socket 地址，配置无法读取时使用默认地址
+func ResolveSocketAddr(configPath string) (SocketAddr, error) {
+	cfg, err := LoadConfig(configPath)
+	if err != nil {
+		return ParseSocketAddr("", "")
+	}
+	return cfg.SocketAddr()
+}
+
+// TokenPath 返回该地址对应的令牌文件路径：unix socket 为 <socket>.token，TCP 为 <用户配置目录>/evs/token-<host>-<port>
+func (a SocketAddr) TokenPath() (string, error) {
+	if a.Network == "unix" {
+		return a.Address + ".token", nil
+	}
+	path, err := DefaultTokenPath()
+	if err != nil {
+		return "", err
+	}
+	if a.Address == DefaultSocketAddress {
+		return path, nil
+	}
+	name := strings.NewReplacer(":", "-", "[", "", "]", "").Replace(a.Address)
+	return path + "-" + name, nil
+}
+
+// Dial 连接控制 socket
+func (a SocketAddr) Dial(timeout time.Duration) (net.Conn, error) {
+	return net.DialTimeout(a.Network, a.Address, timeout)
+}
+
+// Listen 监听控制 socket；unix socket 会创建 0700 目录、清理无人监听的残留文件，并将 socket 设为 0600
+func (a SocketAddr) Listen() (net.Listener, error) {
+	if a.Network != "unix" {
+		return net.Listen(a.Network, a.Address)
+	}
+	if err := os.MkdirAll(filepath.Dir(a.Address), 0700); err != nil {
+		return nil, err
+	}
+	ln, err := net.Listen("unix", a.Address)
+	if err != nil && isAddrInUse(err) {
+		if conn, dialErr := a.Dial(300 * time.Millisecond); dialErr == nil {
+			conn.Close()
+			return nil, err // 已有实例在监听
+		}
+		os.Remove(a.Address) // 残留的 socket 文件
+		ln, err = net.Listen("unix", a.Address)
+	}
+	if err != nil {
+		return nil, err
+	}
+	os.Chmod(a.Address, 0600)
+	return ln, nil
+}
+
+// Cleanup 删除 unix socket 文件（退出时调用）
+func (a SocketAddr) Cleanup() {
+	if a.Network == "unix" {
+		os.Remove(a.Address)
+	}
+}
+
+func isAddrInUse(err error) bool {
+	var errno syscall.Errno
+	if errors.As(err, &errno) && errno == syscall.EADDRINUSE {
+		return true
+	}
+	// Windows 的 AF_UNIX 返回 WSAEADDRINUSE，错误信息兜底判断
+	return strings.Contains(err.Error(), "address already in use") || strings.Contains(err.Error(), "Only one usage")
+}
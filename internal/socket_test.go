@@ -0,0 +1,43 @@
+package internal
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestConfigSocketAddrDefaults(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	t.Setenv("AppData", t.TempDir())
+	dir := t.TempDir()
+	addr := func(c *Config) SocketAddr {
+		t.Helper()
+		a, err := c.SocketAddr()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return a
+	}
+
+	// 默认配置文件、未设置 name：兼容旧版本的固定地址
+	if a := addr(&Config{Source: DefaultConfigFile}); a.Network != "tcp" || a.Address != DefaultSocketAddress {
+		t.Errorf("默认配置: %s", a)
+	}
+	// 显式配置的 socket 优先
+	if a := addr(&Config{Source: filepath.Join(dir, "node.yaml"), Socket: "127.0.0.1:6000"}); a.Address != "127.0.0.1:6000" {
+		t.Errorf("显式 socket: %s", a)
+	}
+	// 设置了 name：<name>.sock
+	if a := addr(&Config{Source: DefaultConfigFile, Name: "node"}); a.Network != "unix" || filepath.Base(a.Address) != "node.sock" {
+		t.Errorf("name: %s", a)
+	}
+	// 其它配置文件：文件名加路径哈希，不同目录互不冲突
+	a1 := addr(&Config{Source: filepath.Join(dir, "a", "node.yaml")})
+	a2 := addr(&Config{Source: filepath.Join(dir, "b", "node.yaml")})
+	if a1.Network != "unix" || !strings.HasPrefix(filepath.Base(a1.Address), "node-") || a1 == a2 {
+		t.Errorf("按路径派生: %s, %s", a1, a2)
+	}
+	if again := addr(&Config{Source: filepath.Join(dir, "a", "node.yaml")}); again != a1 {
+		t.Errorf("同一配置文件得到不同地址: %s, %s", a1, again)
+	}
+}
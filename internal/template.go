@@ -0,0 +1,114 @@
+package internal
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// 参数模板：应用的 path 与 args 可以使用 Go 模板，启动时展开一次
+//
+//	{{.AppDir}}    path 所在目录（只能用于 args）
+//	{{.Name}}      应用名
+//	{{.Version}}   应用版本（扫描生成的应用为提取到的版本号）
+//	{{.ConfigDir}} 配置文件所在目录
+//	{{.Home}}      用户主目录
+//	{{env "X"}}    环境变量 X
+//	{{now "20060102"}} 当前时间，参数为 Go 时间格式
+//
+// 未知变量与函数直接报错，不会带着原样的 {{ }} 启动
+
+// hasTemplate 判断字符串是否包含模板
+func hasTemplate(s string) bool {
+	return strings.Contains(s, "{{")
+}
+
+// HasTemplate 判断应用的 path 或 args 是否使用了模板
+func (a App) HasTemplate() bool {
+	if hasTemplate(a.Path) {
+		return true
+	}
+	for _, arg := range a.Args {
+		if hasTemplate(arg) {
+			return true
+		}
+	}
+	return false
+}
+
+var missingKeyRe = regexp.MustCompile(`map has no entry for key "([^"]*)"`)
+
+// templateFuncs 模板函数，now 在一次展开中使用同一时刻
+func templateFuncs(now time.Time) template.FuncMap {
+	return template.FuncMap{
+		"env": os.Getenv,
+		"now": func(layout string) string { return now.Format(layout) },
+	}
+}
+
+// TemplateError 模板展开失败，Field 为出错的字段（path 或 args[i]）
+type TemplateError struct {
+	Field string
+	Msg   string
+}
+
+func (e *TemplateError) Error() string {
+	return e.Field + " " + e.Msg
+}
+
+// expandTemplate 展开单个字段，field 用于错误信息（如 args[1]）
+func expandTemplate(field, text string, data map[string]interface{}, funcs template.FuncMap) (string, error) {
+	if !hasTemplate(text) {
+		return text, nil
+	}
+	t, err := template.New(field).Option("missingkey=error").Funcs(funcs).Parse(text)
+	if err != nil {
+		return "", &TemplateError{field, fmt.Sprintf("模板语法错误: %v", err)}
+	}
+	var b strings.Builder
+	if err := t.Execute(&b, data); err != nil {
+		if m := missingKeyRe.FindStringSubmatch(err.Error()); m != nil {
+			keys := make([]string, 0, len(data))
+			for k := range data {
+				keys = append(keys, "."+k)
+			}
+			sort.Strings(keys)
+			return "", &TemplateError{field, fmt.Sprintf("中的模板变量 .%s 未定义（可用: %s，函数: env、now）", m[1], strings.Join(keys, " "))}
+		}
+		return "", &TemplateError{field, fmt.Sprintf("模板展开失败: %v", err)}
+	}
+	return b.String(), nil
+}
+
+// ExpandApp 返回 path 与 args 中的模板展开后的应用；先展开 path，再以其所在目录作为 .AppDir 展开 args
+func (c *Config) ExpandApp(name string, app App) (App, error) {
+	if !app.HasTemplate() {
+		return app, nil
+	}
+	funcs := templateFuncs(time.Now())
+	home, _ := os.UserHomeDir()
+	data := map[string]interface{}{
+		"Name":      name,
+		"Version":   app.Version,
+		"ConfigDir": c.Dir(),
+		"Home":      home,
+	}
+	path, err := expandTemplate("path", app.Path, data, funcs)
+	if err != nil {
+		return app, fmt.Errorf("应用 %s: %w", name, err)
+	}
+	data["AppDir"] = filepath.Dir(path)
+	args := make([]string, len(app.Args))
+	for i, arg := range app.Args {
+		if args[i], err = expandTemplate(fmt.Sprintf("args[%d]", i), arg, data, funcs); err != nil {
+			return app, fmt.Errorf("应用 %s: %w", name, err)
+		}
+	}
+	app.Path, app.Args = path, args
+	return app, nil
+}
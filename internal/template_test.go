@@ -0,0 +1,60 @@
+package internal
+
+import (
+	"errors"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestExpandApp(t *testing.T) {
+	t.Setenv("EVS_TEST_PORT", "8080")
+	cfg := &Config{Source: filepath.Join(t.TempDir(), "config.yaml")}
+	app := App{
+		Path:    "{{.ConfigDir}}/bin/{{.Name}}",
+		Version: "18.20.0",
+		Args:    []string{"--root={{.AppDir}}", "--port", `{{env "EVS_TEST_PORT"}}`, "v{{.Version}}", "-x"},
+	}
+	got, err := cfg.ExpandApp("node18", app)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := cfg.Dir()
+	wantPath := dir + "/bin/node18"
+	want := []string{"--root=" + filepath.Dir(wantPath), "--port", "8080", "v18.20.0", "-x"}
+	if got.Path != wantPath || !reflect.DeepEqual(got.Args, want) {
+		t.Errorf("got %s %q; want %s %q", got.Path, got.Args, wantPath, want)
+	}
+	// 原应用不被修改
+	if app.Args[0] != "--root={{.AppDir}}" {
+		t.Errorf("原参数被修改: %q", app.Args)
+	}
+
+	got, err = cfg.ExpandApp("a", App{Path: "/bin/a", Args: []string{`{{now "2006"}}`}})
+	if err != nil || got.Args[0] != time.Now().Format("2006") {
+		t.Errorf("now: %q, %v", got.Args, err)
+	}
+}
+
+func TestExpandAppErrors(t *testing.T) {
+	cfg := &Config{}
+	cases := []struct {
+		name  string
+		app   App
+		field string
+		msg   string
+	}{
+		{name: "unknown var", app: App{Path: "/bin/a", Args: []string{"-v", "{{.Port}}"}}, field: "args[1]", msg: ".Port 未定义"},
+		{name: "AppDir in path", app: App{Path: "{{.AppDir}}/a"}, field: "path", msg: ".AppDir 未定义"},
+		{name: "unknown func", app: App{Path: "/bin/a", Args: []string{"{{port}}"}}, field: "args[0]", msg: "模板语法错误"},
+	}
+	for _, c := range cases {
+		_, err := cfg.ExpandApp("a", c.app)
+		var te *TemplateError
+		if !errors.As(err, &te) || te.Field != c.field || !strings.Contains(err.Error(), c.msg) {
+			t.Errorf("%s: got %v; want %s 错误包含 %q", c.name, err, c.field, c.msg)
+		}
+	}
+}
@@ -0,0 +1,6 @@
+package internal
+
+// terminalForeground Solaris 上无法查询终端的前台进程组，总是转发信号
+func terminalForeground() bool {
+	return false
+}
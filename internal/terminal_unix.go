@@ -0,0 +1,16 @@
+//go:build unix && !solaris
+
+package internal
+
+import (
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+// terminalForeground 判断 evs 是否为控制终端的前台进程组（SIGINT 多半来自终端）
+func terminalForeground() bool {
+	var pgrp int32
+	_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, os.Stdin.Fd(), syscall.TIOCGPGRP, uintptr(unsafe.Pointer(&pgrp)))
+	return errno == 0 && int(pgrp) == syscall.Getpgrp()
+}
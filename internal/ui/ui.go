@@ -1,4 +1,4 @@
-package internal
+package ui
 
 import (
 	"time"
@@ -85,14 +85,19 @@ func RegisterMenuRefresher(f func()) {
 	menuRefreshers = append(menuRefreshers, f)
 }
 
+// RefreshMenus 执行所有注册的刷新器并刷新所有菜单项（事件驱动时按需调用）
+func RefreshMenus() {
+	for _, f := range menuRefreshers {
+		f()
+	}
+
+	RefreshAllMenuItems() // 统一刷新所有菜单项
+}
+
 func StartMenuRefresher() {
 	go func() {
 		for {
-			for _, f := range menuRefreshers {
-				f()
-			}
-
-			RefreshAllMenuItems() // 统一刷新所有菜单项
+			RefreshMenus()
 			time.Sleep(time.Second * 2)
 		}
 	}()
@@ -26,11 +26,6 @@ func Spaces(n int) string {
 	return fmt.Sprintf("%*s", n, "")
 }
 
-// 合并默认参数和额外参数
-func MergeArgs(defaults, extras []string) []string {
-	return append(defaults, extras...)
-}
-
 // joinArgs 将参数数组拼接为空格分隔字符串
 func joinArgs(args []string) string {
 	if len(args) == 0 {
@@ -0,0 +1,743 @@
+package internal
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Severity 校验结果级别：error 会阻止 core 加载配置，warning 仅提示
+type Severity string
+
+const (
+	SeverityError   Severity = "error"
+	SeverityWarning Severity = "warning"
+)
+
+// Finding 一条校验结果，Line/Column 来自 yaml.Node（从 1 开始，0 表示无法定位）
+type Finding struct {
+	Severity Severity `json:"severity"`
+	Line     int      `json:"line"`
+	Column   int      `json:"column"`
+	Field    string   `json:"field,omitempty"` // 字段路径，如 apps.node18.path
+	Message  string   `json:"message"`
+}
+
+func (f Finding) String() string {
+	pos := "-"
+	if f.Line > 0 && f.Column > 0 {
+		pos = fmt.Sprintf("%d:%d", f.Line, f.Column)
+	} else if f.Line > 0 {
+		pos = strconv.Itoa(f.Line)
+	}
+	if f.Field != "" {
+		return fmt.Sprintf("%s: %s: %s: %s", pos, f.Severity, f.Field, f.Message)
+	}
+	return fmt.Sprintf("%s: %s: %s", pos, f.Severity, f.Message)
+}
+
+// Findings 一组校验结果
+type Findings []Finding
+
+// Errors 返回 error 级别的结果
+func (fs Findings) Errors() Findings {
+	var out Findings
+	for _, f := range fs {
+		if f.Severity == SeverityError {
+			out = append(out, f)
+		}
+	}
+	return out
+}
+
+// Err 将 error 级别的结果合并为一个 error，没有则返回 nil
+func (fs Findings) Err() error {
+	errs := fs.Errors()
+	if len(errs) == 0 {
+		return nil
+	}
+	lines := make([]string, len(errs))
+	for i, f := range errs {
+		lines[i] = f.String()
+	}
+	return errors.New(strings.Join(lines, "; "))
+}
+
+// validator 收集校验结果
+type validator struct {
+	root     *yaml.Node // 文档的顶层映射，可为 nil
+	findings Findings
+	seen     map[string]bool
+}
+
+func (v *validator) add(sev Severity, n *yaml.Node, field, format string, a ...interface{}) {
+	f := Finding{Severity: sev, Field: field, Message: fmt.Sprintf(format, a...)}
+	if n != nil {
+		f.Line, f.Column = n.Line, n.Column
+	}
+	// 别名引用同一节点时只报告一次
+	key := f.String()
+	if v.seen[key] {
+		return
+	}
+	v.seen[key] = true
+	v.findings = append(v.findings, f)
+}
+
+func (v *validator) sorted() Findings {
+	sort.SliceStable(v.findings, func(i, j int) bool {
+		a, b := v.findings[i], v.findings[j]
+		if a.Line != b.Line {
+			return a.Line < b.Line
+		}
+		return a.Column < b.Column
+	})
+	return v.findings
+}
+
+// ValidateConfigFile 校验配置文件：语法、重复键、未知字段、类型错误以及 ValidateConfig 的全部检查
+func ValidateConfigFile(path string) (Findings, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	v := &validator{seen: make(map[string]bool)}
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		v.addYAMLError(err)
+		return v.sorted(), nil
+	}
+	if doc.Kind != yaml.DocumentNode || len(doc.Content) == 0 || doc.Content[0].Kind != yaml.MappingNode {
+		v.add(SeverityError, &doc, "", "配置文件必须是一个映射（包含 activate/apps 等字段）")
+		return v.sorted(), nil
+	}
+	v.root = doc.Content[0]
+	v.checkDuplicates(v.root, "")
+	v.checkKeys(v.root, reflect.TypeOf(Config{}), "")
+
+	// 重复键已单独报告，去掉后再解码，使其余检查仍能进行；
+	// 类型错误时 yaml.v3 仍会填充其余字段，同样继续检查
+	var cfg Config
+	if err := dropDuplicateKeys(copyNode(&doc)).Decode(&cfg); err != nil {
+		v.addYAMLError(err)
+		var typeErr *yaml.TypeError
+		if !errors.As(err, &typeErr) {
+			return v.sorted(), nil
+		}
+	}
+	if cfg.Apps == nil {
+		cfg.Apps = make(map[string]App)
+	}
+	cfg.AppOrder = appOrderFromNode(&doc)
+	cfg.Source = path
+	cfg.scanApps()
+	v.checkSemantics(&cfg)
+	return v.sorted(), nil
+}
+
+// ValidateConfig 对已加载的配置做语义检查（激活应用、应用名、路径、策略取值），
+// 位置信息来自加载时保留的文档树
+func ValidateConfig(cfg *Config) Findings {
+	v := &validator{seen: make(map[string]bool)}
+	if cfg.node != nil && len(cfg.node.Content) > 0 {
+		v.root = cfg.node.Content[0]
+		v.checkKeys(v.root, reflect.TypeOf(Config{}), "")
+	}
+	v.checkSemantics(cfg)
+	return v.sorted()
+}
+
+// Validate 返回 error 级别的校验结果（core 加载配置时使用）
+func (c *Config) Validate() error {
+	return ValidateConfig(c).Err()
+}
+
+// RunValidate 执行 validate 命令，打印全部校验结果，存在 error 级别结果时返回 false
+func RunValidate(configPath string) bool {
+	path := configPath
+	for _, p := range configCandidates(configPath) {
+		if _, err := os.Stat(p); err == nil {
+			path = p
+			break
+		}
+	}
+	findings, err := ValidateConfigFile(path)
+	if err != nil {
+		fmt.Printf("读取配置失败: %v\n", err)
+		return false
+	}
+	warnings := 0
+	for _, f := range findings {
+		fmt.Printf("%s:%s\n", path, f)
+		if f.Severity == SeverityWarning {
+			warnings++
+		}
+	}
+	errs := len(findings) - warnings
+	if errs == 0 && warnings == 0 {
+		fmt.Printf("%s: 配置有效\n", path)
+	} else {
+		fmt.Printf("%d 个错误，%d 个警告\n", errs, warnings)
+	}
+	return errs == 0
+}
+
+var yamlLineRe = regexp.MustCompile(`^(?:yaml: )?line (\d+): `)
+
+// addYAMLError 将 yaml 解析/解码错误转为校验结果，从错误信息中提取行号
+func (v *validator) addYAMLError(err error) {
+	msgs := []string{err.Error()}
+	var typeErr *yaml.TypeError
+	if errors.As(err, &typeErr) {
+		msgs = typeErr.Errors
+	}
+	for _, msg := range msgs {
+		f := Finding{Severity: SeverityError, Message: strings.TrimPrefix(msg, "yaml: ")}
+		if m := yamlLineRe.FindStringSubmatch(msg); m != nil {
+			f.Line, _ = strconv.Atoi(m[1])
+			f.Message = msg[len(m[0]):]
+		}
+		v.findings = append(v.findings, f)
+	}
+}
+
+// checkDuplicates 检查所有映射中的重复键（应用名重复即在此发现）
+func (v *validator) checkDuplicates(n *yaml.Node, field string) {
+	switch n.Kind {
+	case yaml.MappingNode:
+		first := make(map[string]*yaml.Node)
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			k := n.Content[i]
+			if prev, ok := first[k.Value]; ok && k.Value != "<<" {
+				v.add(SeverityError, k, joinField(field, k.Value), "重复的键，首次定义在第 %d 行", prev.Line)
+			} else {
+				first[k.Value] = k
+			}
+			v.checkDuplicates(n.Content[i+1], joinField(field, k.Value))
+		}
+	case yaml.SequenceNode:
+		for _, c := range n.Content {
+			v.checkDuplicates(c, field)
+		}
+	}
+}
+
+// dropDuplicateKeys 删除映射中重复出现的键（保留第一个）
+func dropDuplicateKeys(n *yaml.Node) *yaml.Node {
+	if n.Kind == yaml.MappingNode {
+		seen := make(map[string]bool)
+		content := n.Content[:0]
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			k := n.Content[i]
+			if seen[k.Value] && k.Value != "<<" {
+				continue
+			}
+			seen[k.Value] = true
+			content = append(content, k, n.Content[i+1])
+		}
+		n.Content = content
+	}
+	for _, c := range n.Content {
+		dropDuplicateKeys(c)
+	}
+	return n
+}
+
+// checkKeys 按结构体的 yaml 标签检查未知字段，支持别名与合并键（<<）
+func (v *validator) checkKeys(n *yaml.Node, t reflect.Type, field string) {
+	n = resolveAlias(n)
+	if n == nil {
+		return
+	}
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	switch {
+	case t.Kind() == reflect.Struct && n.Kind == yaml.MappingNode:
+		known := make(map[string]reflect.Type)
+		var names []string
+		for i := 0; i < t.NumField(); i++ {
+			if name, _, ok := yamlFieldName(t.Field(i)); ok {
+				known[name] = t.Field(i).Type
+				names = append(names, name)
+			}
+		}
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			k, val := n.Content[i], n.Content[i+1]
+			if k.Value == "<<" {
+				for _, m := range mergeSources(val) {
+					v.checkKeys(m, t, field)
+				}
+				continue
+			}
+			ft, ok := known[k.Value]
+			if !ok {
+				// 顶层的 x- 前缀键与带锚点的键视为用户自定义（如存放公共片段），不报告
+				if field == "" && (strings.HasPrefix(k.Value, "x-") || val.Anchor != "") {
+					continue
+				}
+				msg := "未知字段"
+				if s := closestName(k.Value, names); s != "" {
+					msg += fmt.Sprintf("，是否为 %s？", s)
+				}
+				v.add(SeverityWarning, k, joinField(field, k.Value), "%s", msg)
+				continue
+			}
+			v.checkKeys(val, ft, joinField(field, k.Value))
+		}
+	case t.Kind() == reflect.Map && n.Kind == yaml.MappingNode:
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			v.checkKeys(n.Content[i+1], t.Elem(), joinField(field, n.Content[i].Value))
+		}
+	}
+}
+
+// checkSemantics 语义检查
+func (v *validator) checkSemantics(cfg *Config) {
+	appsNode := v.lookup("apps")
+	if cfg.Activate != "" {
+		if _, ok := cfg.LookupApp(cfg.Activate); !ok {
+			v.add(SeverityError, v.lookup("activate"), "activate", "激活应用 %s 不存在", cfg.Activate)
+		}
+	} else if len(cfg.AllApps()) > 0 {
+		v.add(SeverityWarning, appsNode, "activate", "未设置激活应用")
+	}
+	if cfg.ActivateRange != "" {
+		if _, err := ParseConstraint(cfg.ActivateRange); err != nil {
+			v.add(SeverityError, v.lookup("activate_range"), "activate_range", "%v", err)
+		}
+	}
+
+	folded := make(map[string]string)
+	for _, name := range cfg.AppOrder {
+		app := cfg.Apps[name]
+		field := "apps." + name
+		keyNode := v.lookup("apps", name)
+		if err := checkAppName(name); err != nil {
+			v.add(SeverityError, keyNode, field, "应用名无效: %v", err)
+		}
+		if prev, ok := folded[strings.ToLower(name)]; ok && prev != name {
+			v.add(SeverityWarning, keyNode, field, "与应用 %s 仅大小写不同，在 Windows 下容易混淆", prev)
+		}
+		folded[strings.ToLower(name)] = name
+
+		if app.HasTemplate() {
+			v.checkTemplate(cfg, name, app, keyNode)
+		} else {
+			v.checkPath(app.Path, v.lookupOr(keyNode, "apps", name, "path"), field+".path")
+		}
+		if app.Version != "" {
+			if _, err := ParseVersion(app.Version); err != nil {
+				v.add(SeverityError, v.lookupOr(keyNode, "apps", name, "version"), field+".version", "%v", err)
+			}
+		}
+		v.checkTools(app, keyNode, name)
+
+		switch app.Stop.Signal {
+		case "", StopSignalTerm, StopSignalInt, StopSignalKill:
+		default:
+			v.add(SeverityError, v.lookupOr(keyNode, "apps", name, "stop", "signal"), field+".stop.signal",
+				"无效的停止信号 %s（可选 term/int/kill）", app.Stop.Signal)
+		}
+		if app.Stop.Grace < 0 {
+			v.add(SeverityError, v.lookupOr(keyNode, "apps", name, "stop", "grace"), field+".stop.grace",
+				"宽限时间 %s 不能为负数", app.Stop.Grace)
+		}
+		switch app.Restart.Mode {
+		case "", RestartNever, RestartOnFailure, RestartAlways:
+		default:
+			v.add(SeverityError, v.lookupOr(keyNode, "apps", name, "restart", "mode"), field+".restart.mode",
+				"无效的重启模式 %s（可选 never/on-failure/always）", app.Restart.Mode)
+		}
+		v.checkHealth(app.Health, keyNode, name)
+		v.checkArgsPolicy(app.ArgsPolicy, keyNode, name)
+		v.checkProfiles(cfg, app, keyNode, name)
+	}
+	v.checkScan(cfg)
+
+	if !IsDefaultProfile(cfg.Profile) && cfg.Activate != "" && cfg.ProfileFor(cfg.Activate) == "" {
+		v.add(SeverityWarning, v.lookup("profile"), "profile", "激活应用 %s 未定义 profile %s，将按默认方式启动", cfg.Activate, cfg.Profile)
+	}
+
+	if cfg.Log.MaxSizeMB < 0 {
+		v.add(SeverityError, v.lookup("log", "max_size_mb"), "log.max_size_mb", "日志文件大小上限不能为负数")
+	}
+	if cfg.HistorySize < 0 {
+		v.add(SeverityError, v.lookup("history_size"), "history_size", "状态历史条数不能为负数")
+	}
+	if cfg.Log.MaxFiles < 0 {
+		v.add(SeverityError, v.lookup("log", "max_files"), "log.max_files", "保留的日志文件数不能为负数")
+	}
+}
+
+// checkHealth 健康检查：探测方式不是恰好一种、地址/URL/正则无效、状态码或阈值非法为错误
+func (v *validator) checkHealth(h HealthCheck, keyNode *yaml.Node, name string) {
+	field := "apps." + name + ".health"
+	node := func(key string) *yaml.Node {
+		return v.lookupOr(keyNode, "apps", name, "health", key)
+	}
+	if reflect.DeepEqual(h, HealthCheck{}) {
+		return
+	}
+	if kinds := h.Probes(); len(kinds) != 1 {
+		v.add(SeverityError, v.lookupOr(keyNode, "apps", name, "health"), field, "需要且只能配置一种探测方式（tcp/http/exec/log），当前为 %d 种", len(kinds))
+	}
+	if h.TCP != "" {
+		if _, _, err := net.SplitHostPort(h.TCP); err != nil {
+			v.add(SeverityError, node("tcp"), field+".tcp", "无效的地址 %s（应为 host:port）", h.TCP)
+		}
+	}
+	if h.HTTP != "" {
+		if u, err := url.Parse(h.HTTP); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			v.add(SeverityError, node("http"), field+".http", "无效的 URL %s（应为 http:// 或 https:// 开头）", h.HTTP)
+		}
+	}
+	if h.Status != 0 && (h.Status < 100 || h.Status > 599) {
+		v.add(SeverityError, node("status"), field+".status", "无效的 HTTP 状态码 %d", h.Status)
+	}
+	if h.Log != "" {
+		if _, err := regexp.Compile(h.Log); err != nil {
+			v.add(SeverityError, node("log"), field+".log", "无效的正则: %v", err)
+		}
+	}
+	for _, n := range []struct {
+		key string
+		val int64
+	}{
+		{"interval", int64(h.Interval)}, {"timeout", int64(h.Timeout)}, {"start_period", int64(h.StartPeriod)},
+		{"healthy_threshold", int64(h.HealthyThreshold)}, {"unhealthy_threshold", int64(h.UnhealthyThreshold)},
+	} {
+		if n.val < 0 {
+			v.add(SeverityError, node(n.key), field+"."+n.key, "%s 不能为负数", n.key)
+		}
+	}
+	if h.Timeout > 0 && h.Timeout > h.IntervalOrDefault() {
+		v.add(SeverityWarning, node("timeout"), field+".timeout", "超时 %s 大于探测间隔 %s", h.Timeout, h.IntervalOrDefault())
+	}
+}
+
+// checkArgsPolicy 参数策略：inherit/mode 取值非法为错误，allow/deny/takes_value 中不是 flag 的项为警告
+func (v *validator) checkArgsPolicy(p ArgPolicy, keyNode *yaml.Node, name string) {
+	field := "apps." + name + ".args_policy"
+	switch p.Inherit {
+	case "", ArgInheritSame, ArgInheritAlways, ArgInheritNever:
+	default:
+		v.add(SeverityError, v.lookupOr(keyNode, "apps", name, "args_policy", "inherit"), field+".inherit",
+			"无效的继承方式 %s（可选 same/always/never）", p.Inherit)
+	}
+	switch p.Mode {
+	case "", ArgModeAppend, ArgModeOverride:
+	default:
+		v.add(SeverityError, v.lookupOr(keyNode, "apps", name, "args_policy", "mode"), field+".mode",
+			"无效的合并方式 %s（可选 append/override）", p.Mode)
+	}
+	for _, list := range []struct {
+		key   string
+		flags []string
+	}{{"allow", p.Allow}, {"deny", p.Deny}, {"takes_value", p.TakesValue}} {
+		for _, flag := range list.flags {
+			if !isFlag(flag) || strings.Contains(flag, "=") {
+				v.add(SeverityWarning, v.lookupOr(keyNode, "apps", name, "args_policy", list.key), field+"."+list.key,
+					"%s 不是 flag 键（应形如 --port 或 -p，不含 =值），不会匹配任何参数", flag)
+			}
+		}
+	}
+	if p.InheritOrDefault() == ArgInheritNever && (len(p.Allow) > 0 || len(p.Deny) > 0) {
+		v.add(SeverityWarning, v.lookupOr(keyNode, "apps", name, "args_policy"), field,
+			"inherit 为 never 时 allow/deny 不生效")
+	}
+}
+
+// checkProfiles 应用的 profile：名称无效、mode 取值非法或 args 模板无法展开为错误
+func (v *validator) checkProfiles(cfg *Config, app App, keyNode *yaml.Node, name string) {
+	for _, profile := range app.ProfileNames() {
+		p := app.Profiles[profile]
+		field := "apps." + name + ".profiles." + profile
+		n := v.lookupOr(keyNode, "apps", name, "profiles", profile)
+		if err := checkAppName(profile); err != nil {
+			v.add(SeverityError, n, field, "profile 名无效: %v", err)
+		} else if profile == DefaultProfile {
+			v.add(SeverityError, n, field, "%s 为保留名，表示不使用 profile", DefaultProfile)
+		}
+		switch p.Mode {
+		case "", ProfileArgsAppend, ProfileArgsOverride:
+		default:
+			v.add(SeverityError, v.lookupOr(keyNode, "apps", name, "profiles", profile, "mode"), field+".mode",
+				"无效的参数合并方式 %s（可选 append/override）", p.Mode)
+		}
+		// 只展开 profile 自己的 args，path 的问题已在应用上报告
+		_, err := cfg.ExpandApp(name, App{Path: app.Path, Version: app.Version, Args: p.Args})
+		var te *TemplateError
+		if errors.As(err, &te) && te.Field != "path" {
+			v.add(SeverityError, v.lookupOr(keyNode, "apps", name, "profiles", profile, "args"), field+".args", "%v", err)
+		}
+	}
+}
+
+// checkScan 扫描来源：glob/正则无效为错误；扫描不到版本、应用名无效或与其它应用重名为警告
+func (v *validator) checkScan(cfg *Config) {
+	var items []*yaml.Node
+	if n := resolveAlias(v.lookup("scan")); n != nil && n.Kind == yaml.SequenceNode {
+		items = n.Content
+	}
+	taken := make(map[string]string)
+	for _, name := range cfg.AppOrder {
+		taken[name] = "apps"
+	}
+	for i, src := range cfg.Scan {
+		field := fmt.Sprintf("scan[%d]", i)
+		var item *yaml.Node
+		if i < len(items) {
+			item = resolveAlias(items[i])
+		}
+		at := func(key string) *yaml.Node {
+			if n := mappingValue(item, key); n != nil {
+				return n
+			}
+			return item
+		}
+		if src.App.Path != "" {
+			v.add(SeverityWarning, at("app"), field+".app.path", "扫描来源的 path 由匹配结果决定，此处的值被忽略")
+		}
+		if _, err := src.Compile(); err != nil {
+			v.add(SeverityError, at("version"), field+".version", "正则无效: %v", err)
+			continue
+		}
+		if i >= len(cfg.scanResults) {
+			continue // 未经 LoadConfig 扫描的配置（如在内存中构建），没有扫描结果可检查
+		}
+		found, err := cfg.scanResults[i].found, cfg.scanResults[i].err
+		if err != nil {
+			v.add(SeverityError, at("glob"), field+".glob", "%v", err)
+			continue
+		}
+		if len(found) == 0 {
+			v.add(SeverityWarning, at("glob"), field+".glob", "未扫描到任何版本: %s", src.Pattern(cfg.Dir()))
+		}
+		for _, s := range found {
+			if err := checkAppName(s.Name); err != nil {
+				v.add(SeverityWarning, at("glob"), field, "扫描结果 %s 的应用名无效（%v），已忽略", s.Name, err)
+				continue
+			}
+			if prev, ok := taken[s.Name]; ok {
+				v.add(SeverityWarning, at("glob"), field, "扫描结果 %s 与 %s 中的应用重名，已忽略（%s）", s.Name, prev, s.App.Path)
+				continue
+			}
+			taken[s.Name] = field
+		}
+	}
+}
+
+// checkTools 工具名会作为 shim 文件名，规则与应用名相同；工具路径按 path 所在目录解析后检查
+func (v *validator) checkTools(app App, keyNode *yaml.Node, name string) {
+	tools := make([]string, 0, len(app.Tools))
+	for tool := range app.Tools {
+		tools = append(tools, tool)
+	}
+	sort.Strings(tools)
+	for _, tool := range tools {
+		field := "apps." + name + ".tools." + tool
+		n := v.lookupOr(keyNode, "apps", name, "tools", tool)
+		if err := checkAppName(tool); err != nil {
+			v.add(SeverityError, n, field, "工具名无效: %v", err)
+			continue
+		}
+		if app.Tools[tool] == "" {
+			v.add(SeverityError, n, field, "缺少可执行文件路径")
+			continue
+		}
+		if filepath.IsAbs(app.Path) {
+			v.checkPath(resolvePath(os.ExpandEnv(app.Tools[tool]), filepath.Dir(app.Path)), n, field)
+		}
+	}
+}
+
+// checkTemplate 使用模板的 path/args：展开失败为错误，展开后的 path 按 checkPath 检查
+// （env、now 以校验时的值展开，仅供参考）
+func (v *validator) checkTemplate(cfg *Config, name string, app App, keyNode *yaml.Node) {
+	field := "apps." + name
+	expanded, err := cfg.ExpandApp(name, app)
+	if err != nil {
+		var te *TemplateError
+		key := "path"
+		if errors.As(err, &te) && te.Field != "path" {
+			key = "args"
+		}
+		v.add(SeverityError, v.lookupOr(keyNode, "apps", name, key), field+"."+key, "%v", err)
+		return
+	}
+	v.checkPath(expanded.Path, v.lookupOr(keyNode, "apps", name, "path"), field+".path")
+}
+
+// checkPath 可执行文件路径：为空或非绝对路径为错误，不存在或不可执行为警告（可能位于尚未挂载的磁盘）
+func (v *validator) checkPath(path string, n *yaml.Node, field string) {
+	if path == "" {
+		v.add(SeverityError, n, field, "缺少可执行文件路径")
+		return
+	}
+	if !filepath.IsAbs(path) {
+		v.add(SeverityError, n, field, "必须是绝对路径: %s", path)
+		return
+	}
+	fi, err := os.Stat(path)
+	switch {
+	case err != nil:
+		v.add(SeverityWarning, n, field, "文件不存在: %s", path)
+	case fi.IsDir():
+		v.add(SeverityWarning, n, field, "路径是目录: %s", path)
+	case !isExecutableFile(path, fi):
+		v.add(SeverityWarning, n, field, "文件不可执行: %s", path)
+	}
+}
+
+// checkAppName 应用名用于命令行、托盘菜单与文件名，不允许空白、控制字符、路径分隔符及以 - 开头
+func checkAppName(name string) error {
+	if name == "" {
+		return errors.New("不能为空")
+	}
+	if strings.HasPrefix(name, "-") {
+		return errors.New("不能以 - 开头")
+	}
+	for _, r := range name {
+		switch {
+		case unicode.IsSpace(r) || unicode.IsControl(r):
+			return errors.New("不能包含空白或控制字符")
+		case strings.ContainsRune(`/\:*?"<>|`, r):
+			return fmt.Errorf("不能包含字符 %q", r)
+		}
+	}
+	return nil
+}
+
+// lookup 按键路径查找节点（解析别名与合并键），返回值节点，找不到返回 nil
+func (v *validator) lookup(keys ...string) *yaml.Node {
+	n := v.root
+	for _, key := range keys {
+		if n = mappingValue(resolveAlias(n), key); n == nil {
+			return nil
+		}
+	}
+	return n
+}
+
+// lookupOr 查找节点，找不到时返回 fallback（如字段来自默认值时定位到应用名）
+func (v *validator) lookupOr(fallback *yaml.Node, keys ...string) *yaml.Node {
+	if n := v.lookup(keys...); n != nil {
+		return n
+	}
+	return fallback
+}
+
+// mappingValue 返回映射中 key 对应的值节点，直接键优先，其次查找合并键。
+// 值为映射时返回带键位置的副本（报告定位到键更符合阅读习惯），别名定位到引用处
+func mappingValue(n *yaml.Node, key string) *yaml.Node {
+	if n == nil || n.Kind != yaml.MappingNode {
+		return nil
+	}
+	if idx := mappingIndex(n, key); idx != -1 {
+		k, val := n.Content[idx], n.Content[idx+1]
+		target := resolveAlias(val)
+		if target == nil {
+			return nil
+		}
+		pos := val
+		if target.Kind == yaml.MappingNode {
+			pos = k
+		}
+		c := *target
+		c.Line, c.Column = pos.Line, pos.Column
+		return &c
+	}
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		if n.Content[i].Value == "<<" {
+			for _, m := range mergeSources(n.Content[i+1]) {
+				if val := mappingValue(m, key); val != nil {
+					return val
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// mergeSources 返回合并键的来源映射（单个别名或别名列表）
+func mergeSources(n *yaml.Node) []*yaml.Node {
+	n = resolveAlias(n)
+	if n == nil {
+		return nil
+	}
+	if n.Kind == yaml.SequenceNode {
+		var out []*yaml.Node
+		for _, c := range n.Content {
+			if m := resolveAlias(c); m != nil {
+				out = append(out, m)
+			}
+		}
+		return out
+	}
+	return []*yaml.Node{n}
+}
+
+func resolveAlias(n *yaml.Node) *yaml.Node {
+	for n != nil && n.Kind == yaml.AliasNode {
+		n = n.Alias
+	}
+	return n
+}
+
+func joinField(parent, key string) string {
+	if parent == "" {
+		return key
+	}
+	return parent + "." + key
+}
+
+// closestName 返回编辑距离不超过 2 的最接近字段名，用于提示拼写错误
+func closestName(name string, candidates []string) string {
+	best, bestDist := "", 3
+	for _, c := range candidates {
+		if d := editDistance(name, c); d < bestDist {
+			best, bestDist = c, d
+		}
+	}
+	return best
+}
+
+func editDistance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	cur := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		cur[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			cur[j] = minInt(prev[j]+1, minInt(cur[j-1]+1, prev[j-1]+cost))
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(rb)]
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,97 @@
+package internal
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestValidateConfigFile(t *testing.T) {
+	exe, err := os.Executable() // 测试二进制本身，确保存在且可执行
+	if err != nil {
+		t.Fatal(err)
+	}
+	src := `activate: nope
+x-common: {}
+defaults: &defaults
+  path: ` + exe + `
+apps:
+  a:
+    <<: *defaults
+    pth: /bin/x
+    stop:
+      signl: term
+  b:
+    path: relative/bin
+  b:
+    path: ` + exe + `
+  "bad name":
+    path: ` + exe + `
+    restart:
+      mode: sometimes
+  c: *defaults
+  d:
+    path: ` + filepath.Dir(exe) + `
+  e:
+    path: ` + exe + `
+    args: 5
+`
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(src), 0644); err != nil {
+		t.Fatal(err)
+	}
+	findings, err := ValidateConfigFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	type want struct {
+		line, column int
+		severity     Severity
+		field        string
+	}
+	wants := []want{
+		{1, 11, SeverityError, "activate"},
+		{8, 5, SeverityWarning, "apps.a.pth"},
+		{10, 7, SeverityWarning, "apps.a.stop.signl"},
+		{12, 11, SeverityError, "apps.b.path"},
+		{13, 3, SeverityError, "apps.b"},
+		{15, 3, SeverityError, "apps.bad name"},
+		{18, 13, SeverityError, "apps.bad name.restart.mode"},
+		{21, 11, SeverityWarning, "apps.d.path"},
+		{24, 0, SeverityError, ""},
+	}
+	if len(findings) != len(wants) {
+		for _, f := range findings {
+			t.Log(f)
+		}
+		t.Fatalf("got %d findings, want %d", len(findings), len(wants))
+	}
+	for i, w := range wants {
+		f := findings[i]
+		if f.Line != w.line || f.Column != w.column || f.Severity != w.severity || f.Field != w.field {
+			t.Errorf("finding %d = %s, want %d:%d %s %s", i, f, w.line, w.column, w.severity, w.field)
+		}
+	}
+	if findings.Err() == nil {
+		t.Error("Err() = nil, want error")
+	}
+}
+
+func TestValidateConfigWarningsOnly(t *testing.T) {
+	cfg, _ := writeTempConfig(t, commentedConfig)
+	findings := ValidateConfig(cfg)
+	if err := findings.Err(); err != nil {
+		t.Fatalf("unexpected errors: %v", err)
+	}
+	// 未知字段与不存在的路径只是警告
+	var unknown bool
+	for _, f := range findings {
+		if f.Field == "apps.node20.my_note" && f.Severity == SeverityWarning && f.Line == 21 {
+			unknown = true
+		}
+	}
+	if !unknown {
+		t.Errorf("missing unknown-key warning: %v", findings)
+	}
+}
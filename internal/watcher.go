@@ -0,0 +1,174 @@
+package internal
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"sync"
+	"time"
+)
+
+// 配置文件监听：Linux 使用 inotify 监听所在目录，其它平台或 inotify 不可用时按固定间隔轮询。
+// 通知只表示“可能有变化”，去抖后按内容哈希判断，校验通过才替换缓存。
+// 安装或卸载版本不会改动配置文件，scan 来源另按 WatchRescanInterval 定期重新扫描，不随每次轮询执行
+const (
+	WatchDebounce       = 200 * time.Millisecond // 编辑器保存通常是 写临时文件 → rename / 截断 → 多次写入，合并为一次
+	WatchPollInterval   = time.Second
+	WatchRescanInterval = time.Minute
+)
+
+// ConfigDiff 两次加载之间应用列表的变化
+type ConfigDiff struct {
+	Added           []string // 新增的应用
+	Removed         []string // 删除的应用
+	Changed         []string // 配置有变化的应用
+	ActivateChanged bool     // 激活应用或当前 profile 是否变化
+}
+
+// Empty 应用列表与激活应用均无变化（其它字段可能有变化）
+func (d ConfigDiff) Empty() bool {
+	return len(d.Added) == 0 && len(d.Removed) == 0 && len(d.Changed) == 0 && !d.ActivateChanged
+}
+
+func (d ConfigDiff) String() string {
+	if d.Empty() {
+		return "应用无变化"
+	}
+	return fmt.Sprintf("新增 %v，删除 %v，修改 %v，激活应用变化: %v", d.Added, d.Removed, d.Changed, d.ActivateChanged)
+}
+
+// DiffConfig 比较两次加载的配置（含扫描生成的应用），old 为 nil 时所有应用视为新增
+func DiffConfig(old, cur *Config) ConfigDiff {
+	var d ConfigDiff
+	if old == nil {
+		d.Added = append(d.Added, cur.AllApps()...)
+		d.ActivateChanged = cur.Activate != ""
+		return d
+	}
+	for _, name := range cur.AllApps() {
+		prev, ok := old.LookupApp(name)
+		app, _ := cur.LookupApp(name)
+		switch {
+		case !ok:
+			d.Added = append(d.Added, name)
+		case !reflect.DeepEqual(prev, app):
+			d.Changed = append(d.Changed, name)
+		}
+	}
+	for _, name := range old.AllApps() {
+		if _, ok := cur.LookupApp(name); !ok {
+			d.Removed = append(d.Removed, name)
+		}
+	}
+	sort.Strings(d.Removed)
+	d.ActivateChanged = old.Activate != cur.Activate || old.Profile != cur.Profile
+	return d
+}
+
+// fileNotifier 平台相关的文件变化通知
+type fileNotifier interface {
+	Events() <-chan struct{}
+	Close() error
+}
+
+// pollNotifier 轮询回退：每个间隔发出一次通知，是否真的变化由内容哈希决定
+type pollNotifier struct {
+	ticker *time.Ticker
+	ch     chan struct{}
+	done   chan struct{}
+}
+
+func newPollNotifier(interval time.Duration) fileNotifier {
+	p := &pollNotifier{ticker: time.NewTicker(interval), ch: make(chan struct{}, 1), done: make(chan struct{})}
+	go func() {
+		for {
+			select {
+			case <-p.ticker.C:
+				select {
+				case p.ch <- struct{}{}:
+				default:
+				}
+			case <-p.done:
+				return
+			}
+		}
+	}()
+	return p
+}
+
+func (p *pollNotifier) Events() <-chan struct{} { return p.ch }
+
+func (p *pollNotifier) Close() error {
+	p.ticker.Stop()
+	close(p.done)
+	return nil
+}
+
+// Watch 在后台监听配置文件，变化并校验通过后调用 onReload，加载或校验失败时调用 onError（保留原配置）。
+// 返回的函数用于停止监听
+func (s *ConfigStore) Watch(onReload func(ConfigDiff), onError func(error)) func() {
+	path := s.path
+	if cfg := s.Get(); cfg != nil && cfg.Source != "" {
+		path = cfg.Source
+	}
+	if abs, err := filepath.Abs(path); err == nil {
+		path = abs
+	}
+	n, err := newPlatformNotifier(path)
+	if err != nil {
+		fmt.Printf("[watch] %v，改用轮询（%s）\n", err, WatchPollInterval)
+		n = newPollNotifier(WatchPollInterval)
+	}
+
+	stop := make(chan struct{})
+	go func() {
+		defer n.Close()
+		rescan := time.NewTicker(WatchRescanInterval)
+		defer rescan.Stop()
+		var debounce <-chan time.Time
+		rejected := "" // 上次加载失败时的文件状态，内容未再变化前不重复报错（轮询时尤为重要）
+		for {
+			select {
+			case <-n.Events():
+				debounce = time.After(WatchDebounce)
+			case <-debounce:
+				debounce = nil
+				state := fileState(path)
+				if state == rejected {
+					continue
+				}
+				diff, err := s.reload(false)
+				if err != nil {
+					rejected = state
+					onError(err)
+					continue
+				}
+				rejected = ""
+				if diff != nil {
+					onReload(*diff)
+				}
+			case <-rescan.C:
+				if diff := s.Rescan(); diff != nil {
+					onReload(*diff)
+				}
+			case <-stop:
+				return
+			}
+		}
+	}()
+
+	var once sync.Once
+	return func() { once.Do(func() { close(stop) }) }
+}
+
+// fileState 文件内容哈希，读取失败时为错误信息
+func fileState(path string) string {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "error: " + err.Error()
+	}
+	return fmt.Sprintf("%x", sha256.Sum256(data))
+}
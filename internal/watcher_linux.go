@@ -0,0 +1,72 @@
+//go:build linux
+
+package internal
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"syscall"
+	"unsafe"
+)
+
+// inotifyNotifier 监听配置文件所在目录（编辑器与原子写入都会 rename 替换文件，直接监听文件会丢失后续事件）
+type inotifyNotifier struct {
+	file *os.File
+	ch   chan struct{}
+}
+
+const inotifyMask = syscall.IN_CLOSE_WRITE | syscall.IN_MODIFY | syscall.IN_CREATE |
+	syscall.IN_DELETE | syscall.IN_MOVED_TO | syscall.IN_MOVED_FROM
+
+func newPlatformNotifier(path string) (fileNotifier, error) {
+	fd, err := syscall.InotifyInit1(syscall.IN_CLOEXEC | syscall.IN_NONBLOCK)
+	if err != nil {
+		return nil, fmt.Errorf("inotify 初始化失败: %v", err)
+	}
+	if _, err := syscall.InotifyAddWatch(fd, filepath.Dir(path), inotifyMask); err != nil {
+		syscall.Close(fd)
+		return nil, fmt.Errorf("inotify 监听 %s 失败: %v", filepath.Dir(path), err)
+	}
+	// 非阻塞 fd 交给 os.File 后由运行时 poller 管理，Close 可以打断阻塞中的 Read
+	n := &inotifyNotifier{file: os.NewFile(uintptr(fd), "inotify"), ch: make(chan struct{}, 1)}
+	go n.readLoop(filepath.Base(path))
+	return n, nil
+}
+
+func (n *inotifyNotifier) readLoop(name string) {
+	buf := make([]byte, 64*(syscall.SizeofInotifyEvent+syscall.NAME_MAX+1))
+	for {
+		size, err := n.file.Read(buf)
+		if err != nil {
+			return
+		}
+		for off := 0; off+syscall.SizeofInotifyEvent <= size; {
+			ev := (*syscall.InotifyEvent)(unsafe.Pointer(&buf[off]))
+			nameBytes := buf[off+syscall.SizeofInotifyEvent : off+syscall.SizeofInotifyEvent+int(ev.Len)]
+			off += syscall.SizeofInotifyEvent + int(ev.Len)
+			if ev.Mask&syscall.IN_Q_OVERFLOW == 0 && trimNul(nameBytes) != name {
+				continue
+			}
+			select {
+			case n.ch <- struct{}{}:
+			default:
+			}
+		}
+	}
+}
+
+func trimNul(b []byte) string {
+	for i, c := range b {
+		if c == 0 {
+			return string(b[:i])
+		}
+	}
+	return string(b)
+}
+
+func (n *inotifyNotifier) Events() <-chan struct{} { return n.ch }
+
+func (n *inotifyNotifier) Close() error {
+	return n.file.Close()
+}
@@ -0,0 +1,10 @@
+//go:build !linux
+
+package internal
+
+import "errors"
+
+// Windows 等平台暂未实现原生目录通知，使用轮询
+func newPlatformNotifier(path string) (fileNotifier, error) {
+	return nil, errors.New("当前平台不支持文件通知")
+}
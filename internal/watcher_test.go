@@ -0,0 +1,135 @@
+package internal
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDiffConfig(t *testing.T) {
+	old := &Config{
+		Activate: "a",
+		Apps:     map[string]App{"a": {Path: "/bin/a"}, "b": {Path: "/bin/b"}, "c": {Path: "/bin/c"}},
+		AppOrder: []string{"a", "b", "c"},
+	}
+	cur := &Config{
+		Activate: "b",
+		Apps:     map[string]App{"a": {Path: "/bin/a"}, "b": {Path: "/bin/b2"}, "d": {Path: "/bin/d"}},
+		AppOrder: []string{"a", "b", "d"},
+	}
+	got := DiffConfig(old, cur)
+	want := ConfigDiff{Added: []string{"d"}, Removed: []string{"c"}, Changed: []string{"b"}, ActivateChanged: true}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %+v, want %+v", got, want)
+	}
+	if d := DiffConfig(cur, cur); !d.Empty() {
+		t.Fatalf("diff of identical configs = %+v", d)
+	}
+}
+
+func TestConfigStoreWatch(t *testing.T) {
+	_, path := writeTempConfig(t, commentedConfig)
+	store := NewConfigStore(path)
+	if _, err := store.Reload(); err != nil {
+		t.Fatal(err)
+	}
+
+	reloads := make(chan ConfigDiff, 4)
+	errs := make(chan error, 4)
+	stop := store.Watch(func(d ConfigDiff) { reloads <- d }, func(err error) { errs <- err })
+	defer stop()
+
+	wait := func() (ConfigDiff, error) {
+		select {
+		case d := <-reloads:
+			return d, nil
+		case err := <-errs:
+			return ConfigDiff{}, err
+		case <-time.After(3 * time.Second):
+			t.Fatal("timed out waiting for watcher")
+		}
+		return ConfigDiff{}, nil
+	}
+
+	// 模拟编辑器的多次写入，去抖后只触发一次
+	edited := strings.Replace(commentedConfig, "/opt/node18/bin/node", "/opt/node18.1/bin/node", 1)
+	for i := 0; i < 3; i++ {
+		if err := os.WriteFile(path, []byte(edited), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	d, err := wait()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(d.Changed, []string{"node18"}) {
+		t.Fatalf("diff = %+v", d)
+	}
+	if got := store.Get().Apps["node18"].Path; got != "/opt/node18.1/bin/node" {
+		t.Fatalf("path = %q", got)
+	}
+
+	// 内容不变的写入不触发重载
+	if err := os.WriteFile(path, []byte(edited), 0644); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case d := <-reloads:
+		t.Fatalf("unexpected reload: %+v", d)
+	case <-time.After(WatchDebounce + 300*time.Millisecond):
+	}
+
+	// 校验失败时保留原配置
+	invalid := strings.Replace(edited, "activate: node18", "activate: missing", 1)
+	if err := os.WriteFile(path, []byte(invalid), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := wait(); err == nil {
+		t.Fatal("expected validation error")
+	}
+	if got := store.Get().Activate; got != "node18" {
+		t.Fatalf("activate = %q, want node18 kept", got)
+	}
+}
+
+func TestConfigStoreRescan(t *testing.T) {
+	dir := t.TempDir()
+	install := func(version string) {
+		full := filepath.Join(dir, "go", version, "bin", "go")
+		if err := os.MkdirAll(filepath.Dir(full), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(full, nil, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	install("1.21.0")
+	path := filepath.Join(dir, "config.yaml")
+	src := "activate: go1.21.0\nscan:\n  - root: go\n    glob: \"*/bin/go\"\n    prefix: go\n"
+	if err := os.WriteFile(path, []byte(src), 0644); err != nil {
+		t.Fatal(err)
+	}
+	store := NewConfigStore(path)
+	if _, err := store.Reload(); err != nil {
+		t.Fatal(err)
+	}
+
+	// 配置文件未变化时，监听文件使用的 reload 不重新扫描，新安装的版本要等定期扫描或 reload 命令
+	install("1.22.0")
+	if d, err := store.reload(false); d != nil || err != nil {
+		t.Fatalf("reload(false) = %+v, %v", d, err)
+	}
+	if got := store.Get().ScannedOrder; !reflect.DeepEqual(got, []string{"go1.21.0"}) {
+		t.Fatalf("scanned = %v", got)
+	}
+	d := store.Rescan()
+	if d == nil || !reflect.DeepEqual(d.Added, []string{"go1.22.0"}) {
+		t.Fatalf("Rescan = %+v", d)
+	}
+	if d := store.Rescan(); d != nil {
+		t.Fatalf("扫描结果未变化: %+v", d)
+	}
+}
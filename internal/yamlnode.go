@@ -0,0 +1,391 @@
+package internal
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// 基于 yaml.Node 的原地编辑：只改动与上次加载结果不同的字段，
+// 其余节点（注释、键顺序、锚点/别名、引号风格、未知字段）原样保留
+
+// nodeEditor 在一棵文档树上做差异同步，root 用于查找引用某个锚点的别名
+type nodeEditor struct {
+	root *yaml.Node
+}
+
+// sync 将 n 从 old 对应的值更新为 cur 对应的值，old 为 n 当前解码结果
+func (e *nodeEditor) sync(n *yaml.Node, old, cur reflect.Value) error {
+	if reflect.DeepEqual(old.Interface(), cur.Interface()) {
+		return nil
+	}
+	e.detach(n)
+
+	switch {
+	case cur.Kind() == reflect.Struct && n.Kind == yaml.MappingNode:
+		return e.syncStruct(n, old, cur)
+	case cur.Kind() == reflect.Map && n.Kind == yaml.MappingNode:
+		return e.syncMap(n, old, cur)
+	}
+	repl, err := encodeNode(cur)
+	if err != nil {
+		return err
+	}
+	replaceNode(n, repl)
+	return nil
+}
+
+// syncStruct 按 yaml 标签逐字段同步，结构体中不存在的键（如用户自定义键、合并键）保持不变
+func (e *nodeEditor) syncStruct(n *yaml.Node, old, cur reflect.Value) error {
+	t := cur.Type()
+	for i := 0; i < t.NumField(); i++ {
+		name, omitEmpty, ok := yamlFieldName(t.Field(i))
+		if !ok {
+			continue
+		}
+		oldF, curF := old.Field(i), cur.Field(i)
+		if reflect.DeepEqual(oldF.Interface(), curF.Interface()) {
+			continue
+		}
+		idx := mappingIndex(n, name)
+		var err error
+		switch {
+		case omitEmpty && isEmptyValue(curF) && idx != -1:
+			removePair(n, idx)
+		case idx != -1:
+			err = e.sync(n.Content[idx+1], oldF, curF)
+		default:
+			// 键不存在：旧值来自合并键或默认值，显式写出新值，尽量放在结构体中前一个字段之后
+			err = appendPair(n, name, curF)
+			if err == nil {
+				movePairAfterPrev(n, t, i)
+			}
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// syncMap 删除已移除的键、同步保留的键，新键按名称排序追加到末尾
+func (e *nodeEditor) syncMap(n *yaml.Node, old, cur reflect.Value) error {
+	for _, k := range old.MapKeys() {
+		if cur.MapIndex(k).IsValid() {
+			continue
+		}
+		if idx := mappingIndex(n, fmt.Sprint(k.Interface())); idx != -1 {
+			removePair(n, idx)
+		}
+	}
+	var added []reflect.Value
+	for _, k := range cur.MapKeys() {
+		oldV := old.MapIndex(k)
+		idx := mappingIndex(n, fmt.Sprint(k.Interface()))
+		if !oldV.IsValid() || idx == -1 {
+			added = append(added, k)
+			continue
+		}
+		if err := e.sync(n.Content[idx+1], oldV, cur.MapIndex(k)); err != nil {
+			return err
+		}
+	}
+	sort.Slice(added, func(i, j int) bool {
+		return fmt.Sprint(added[i].Interface()) < fmt.Sprint(added[j].Interface())
+	})
+	for _, k := range added {
+		if err := appendPair(n, fmt.Sprint(k.Interface()), cur.MapIndex(k)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// detach 在修改节点前解除共享：别名节点展开为目标的副本，
+// 带锚点的节点先把引用它的别名替换为修改前的副本，避免一处修改波及其它条目
+func (e *nodeEditor) detach(n *yaml.Node) {
+	if n.Kind == yaml.AliasNode && n.Alias != nil {
+		c := copyNode(n.Alias)
+		c.Anchor = ""
+		c.HeadComment, c.LineComment, c.FootComment = n.HeadComment, n.LineComment, n.FootComment
+		c.Line, c.Column = n.Line, n.Column
+		*n = *c
+		return
+	}
+	if n.Anchor == "" {
+		return
+	}
+	var walk func(x *yaml.Node)
+	walk = func(x *yaml.Node) {
+		for _, c := range x.Content {
+			if c.Kind == yaml.AliasNode && c.Alias == n {
+				cp := copyNode(n)
+				cp.Anchor = ""
+				cp.HeadComment, cp.LineComment, cp.FootComment = c.HeadComment, c.LineComment, c.FootComment
+				cp.Line, cp.Column = c.Line, c.Column
+				*c = *cp
+				continue
+			}
+			walk(c)
+		}
+	}
+	walk(e.root)
+}
+
+// copyNode 深拷贝节点，别名仍指向原目标
+func copyNode(n *yaml.Node) *yaml.Node {
+	c := *n
+	c.Content = make([]*yaml.Node, len(n.Content))
+	for i, child := range n.Content {
+		c.Content[i] = copyNode(child)
+	}
+	return &c
+}
+
+// replaceNode 用 repl 替换 n 的内容，保留 n 的注释、位置以及流式/引号风格
+func replaceNode(n, repl *yaml.Node) {
+	keepStyle(n, repl)
+	repl.HeadComment, repl.LineComment, repl.FootComment = n.HeadComment, n.LineComment, n.FootComment
+	repl.Line, repl.Column = n.Line, n.Column
+	*n = *repl
+}
+
+// keepStyle 将旧节点的流式/引号风格沿用到新节点，列表按下标逐项沿用
+func keepStyle(old, repl *yaml.Node) {
+	if old.Kind != repl.Kind {
+		return
+	}
+	switch old.Kind {
+	case yaml.SequenceNode, yaml.MappingNode:
+		repl.Style |= old.Style & yaml.FlowStyle
+		if old.Kind == yaml.SequenceNode {
+			for i := 0; i < len(old.Content) && i < len(repl.Content); i++ {
+				keepStyle(old.Content[i], repl.Content[i])
+			}
+		}
+	case yaml.ScalarNode:
+		quoted := old.Style & (yaml.DoubleQuotedStyle | yaml.SingleQuotedStyle)
+		if repl.Style == 0 && quoted != 0 && !strings.Contains(repl.Value, "\n") {
+			repl.Style = quoted
+		}
+	}
+}
+
+func encodeNode(v reflect.Value) (*yaml.Node, error) {
+	var n yaml.Node
+	if err := n.Encode(v.Interface()); err != nil {
+		return nil, err
+	}
+	return &n, nil
+}
+
+func appendPair(n *yaml.Node, key string, v reflect.Value) error {
+	val, err := encodeNode(v)
+	if err != nil {
+		return err
+	}
+	k := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: key}
+	n.Content = append(n.Content, k, val)
+	return nil
+}
+
+// movePairAfterPrev 将刚追加到末尾的第 i 个字段移到映射中已存在的、结构体内位于其前的最近字段之后；
+// 前面的字段都不存在时保持在末尾
+func movePairAfterPrev(n *yaml.Node, t reflect.Type, i int) {
+	for j := i - 1; j >= 0; j-- {
+		name, _, ok := yamlFieldName(t.Field(j))
+		if !ok {
+			continue
+		}
+		idx := mappingIndex(n, name)
+		if idx == -1 {
+			continue
+		}
+		last := len(n.Content) - 2
+		pair := []*yaml.Node{n.Content[last], n.Content[last+1]}
+		rest := append([]*yaml.Node{}, n.Content[idx+2:last]...)
+		n.Content = append(append(n.Content[:idx+2], pair...), rest...)
+		return
+	}
+}
+
+// mappingIndex 返回键在映射节点 Content 中的下标（仅直接键，不含合并进来的键），不存在返回 -1
+func mappingIndex(n *yaml.Node, key string) int {
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		if n.Content[i].Kind == yaml.ScalarNode && n.Content[i].Value == key {
+			return i
+		}
+	}
+	return -1
+}
+
+func removePair(n *yaml.Node, idx int) {
+	n.Content = append(n.Content[:idx], n.Content[idx+2:]...)
+}
+
+// reorderMapping 按 order 重排映射的键值对（连同注释一起移动），order 之外的键保持在末尾
+func reorderMapping(n *yaml.Node, order []string) {
+	if n == nil || n.Kind != yaml.MappingNode {
+		return
+	}
+	content := make([]*yaml.Node, 0, len(n.Content))
+	used := make(map[int]bool)
+	for _, key := range order {
+		if idx := mappingIndex(n, key); idx != -1 && !used[idx] {
+			content = append(content, n.Content[idx], n.Content[idx+1])
+			used[idx] = true
+		}
+	}
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		if !used[i] {
+			content = append(content, n.Content[i], n.Content[i+1])
+		}
+	}
+	n.Content = content
+}
+
+// yamlFieldName 解析结构体字段的 yaml 键名，跳过未导出字段与 yaml:"-"
+func yamlFieldName(f reflect.StructField) (name string, omitEmpty bool, ok bool) {
+	if f.PkgPath != "" {
+		return "", false, false
+	}
+	tag := f.Tag.Get("yaml")
+	if tag == "-" {
+		return "", false, false
+	}
+	parts := strings.Split(tag, ",")
+	name = parts[0]
+	if name == "" {
+		name = strings.ToLower(f.Name)
+	}
+	for _, opt := range parts[1:] {
+		if opt == "omitempty" {
+			omitEmpty = true
+		}
+	}
+	return name, omitEmpty, true
+}
+
+func isEmptyValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Map, reflect.Slice:
+		return v.Len() == 0
+	}
+	return v.IsZero()
+}
+
+// detectIndent 推断原文件的缩进宽度（最小的非零行首空格数），无法推断时返回 0
+func detectIndent(data []byte) int {
+	indent := 0
+	for _, line := range strings.Split(string(data), "\n") {
+		trimmed := strings.TrimLeft(line, " ")
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+		if n := len(line) - len(trimmed); n > 0 && (indent == 0 || n < indent) {
+			indent = n
+		}
+	}
+	return indent
+}
+
+// encodeDocument 序列化文档树，并按原文件恢复空行与换行符（yaml.v3 不保留空行）
+// raw 为节点 Line 所对应的原始内容（已统一为 LF），新增节点（Line 为 0）不补空行
+func encodeDocument(root *yaml.Node, raw []byte, indent int, crlf bool) ([]byte, error) {
+	clearMergeTags(root)
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	if indent > 0 {
+		enc.SetIndent(indent)
+	}
+	if err := enc.Encode(root); err != nil {
+		return nil, err
+	}
+	if err := enc.Close(); err != nil {
+		return nil, err
+	}
+	out := buf.Bytes()
+	if len(raw) > 0 {
+		var err error
+		if out, err = restoreBlankLines(root, out, raw); err != nil {
+			return nil, err
+		}
+	}
+	if crlf {
+		out = bytes.ReplaceAll(out, []byte("\n"), []byte("\r\n"))
+	}
+	return out, nil
+}
+
+// restoreBlankLines 在输出中原文件有空行的键/列表项之前补回空行
+func restoreBlankLines(root *yaml.Node, out, raw []byte) ([]byte, error) {
+	var parsed yaml.Node
+	if err := yaml.Unmarshal(out, &parsed); err != nil {
+		return nil, err
+	}
+	rawLines := strings.Split(string(raw), "\n")
+	blank := make(map[int]bool)
+	markBlankLines(root, &parsed, rawLines, blank)
+	if len(blank) == 0 {
+		return out, nil
+	}
+
+	outLines := strings.Split(string(out), "\n")
+	insertAt := make(map[int]bool)
+	for line := range blank {
+		insertAt[blockStart(outLines, line)] = true
+	}
+	result := make([]string, 0, len(outLines)+len(blank))
+	for i, line := range outLines {
+		if insertAt[i] && i > 0 && strings.TrimSpace(outLines[i-1]) != "" {
+			result = append(result, "")
+		}
+		result = append(result, line)
+	}
+	return []byte(strings.Join(result, "\n")), nil
+}
+
+// markBlankLines 并行遍历编辑后的树与重新解析的输出树，
+// 记录原文件中前面有空行的键/列表项在输出中的行号
+func markBlankLines(src, out *yaml.Node, rawLines []string, blank map[int]bool) {
+	if src.Kind != out.Kind || len(src.Content) != len(out.Content) || src.Kind == yaml.AliasNode {
+		return
+	}
+	for i := range src.Content {
+		s, o := src.Content[i], out.Content[i]
+		isEntry := src.Kind == yaml.SequenceNode || (src.Kind == yaml.MappingNode && i%2 == 0)
+		if isEntry && s.Line > 0 && o.Line > 1 && blankBefore(rawLines, s.Line) {
+			blank[o.Line] = true
+		}
+		markBlankLines(s, o, rawLines, blank)
+	}
+}
+
+// blankBefore 判断第 line 行（从 1 开始）连同其上方注释之前是否为空行
+func blankBefore(lines []string, line int) bool {
+	i := blockStart(lines, line) - 1
+	return i >= 0 && strings.TrimSpace(lines[i]) == ""
+}
+
+// blockStart 返回第 line 行（从 1 开始）上方连续注释的起始下标（从 0 开始）
+func blockStart(lines []string, line int) int {
+	i := line - 1
+	for i > 0 && i-1 < len(lines) && strings.HasPrefix(strings.TrimSpace(lines[i-1]), "#") {
+		i--
+	}
+	return i
+}
+
+// clearMergeTags 去掉合并键上的显式 !!merge 标签，否则 yaml.v3 会输出 "!!merge <<"
+func clearMergeTags(n *yaml.Node) {
+	if n.Kind == yaml.ScalarNode && n.Tag == "!!merge" {
+		n.Tag = ""
+	}
+	for _, c := range n.Content {
+		clearMergeTags(c)
+	}
+}
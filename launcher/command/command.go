@@ -2,176 +2,457 @@ package command
 
 import (
 	"bufio"
+	"encoding/json"
 	"fmt"
-	"io"
 	"net"
 	"os"
 	"os/exec"
 	"path/filepath"
-	"strings"
-	"syscall"
+	"strconv"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/SSwser/exe-version-selector/internal"
 )
 
-var localSocketAddr = "127.0.0.1:50505"
+// Client 控制 socket 的 JSON-lines 客户端，每次调用单独建立连接
+type Client struct {
+	Addr        internal.SocketAddr
+	DialTimeout time.Duration
+	TokenFile   string // 令牌文件路径，为空时使用 Addr.TokenPath()
+	seq         uint64
+}
 
-// Ping checks if the socket server is reachable.
-// Returns (ok, timeout): ok=true 表示连接成功，timeout=true 表示超时未响应，二者都为 false 表示连接被拒绝或其它错误。
-func Ping() (ok bool, timeout bool) {
-	conn, err := net.DialTimeout("tcp", localSocketAddr, 300*time.Millisecond)
-	if err == nil {
+// NewClient 创建连接到 addr 的客户端
+func NewClient(addr internal.SocketAddr) *Client {
+	return &Client{Addr: addr, DialTimeout: 2 * time.Second}
+}
+
+// token 每次连接时重新读取令牌文件（core 每次启动都会生成新令牌）
+func (c *Client) token() (string, error) {
+	path := c.TokenFile
+	if path == "" {
+		var err error
+		if path, err = c.Addr.TokenPath(); err != nil {
+			return "", err
+		}
+	}
+	return internal.ReadTokenFile(path)
+}
+
+// dial 建立连接并完成认证，返回连接与其 reader
+func (c *Client) dial(timeout time.Duration) (net.Conn, *bufio.Reader, error) {
+	token, err := c.token()
+	if err != nil {
+		return nil, nil, err
+	}
+	conn, err := c.Addr.Dial(timeout)
+	if err != nil {
+		return nil, nil, err
+	}
+	conn.SetDeadline(time.Now().Add(timeout))
+	r := bufio.NewReader(conn)
+	if _, err := c.roundTrip(conn, r, internal.Request{Cmd: "auth", Token: token}); err != nil {
 		conn.Close()
-		return true, false
+		return nil, nil, err
 	}
-	if nerr, ok := err.(net.Error); ok && nerr.Timeout() {
-		return false, true
+	conn.SetDeadline(time.Time{})
+	return conn, r, nil
+}
+
+// roundTrip 在已建立的连接上发送一个请求并读取对应响应
+func (c *Client) roundTrip(conn net.Conn, r *bufio.Reader, req internal.Request) (*internal.Response, error) {
+	req.V = internal.ProtocolVersion
+	req.ID = strconv.FormatUint(atomic.AddUint64(&c.seq, 1), 10)
+	data, err := json.Marshal(req)
+	if err != nil {
+		return nil, err
 	}
-	return false, false
+	if _, err := conn.Write(append(data, '\n')); err != nil {
+		return nil, err
+	}
+	line, err := r.ReadBytes('\n')
+	if err != nil {
+		return nil, err
+	}
+	var resp internal.Response
+	if err := json.Unmarshal(line, &resp); err != nil {
+		return nil, fmt.Errorf("invalid response: %v", err)
+	}
+	if resp.ID != "" && resp.ID != req.ID {
+		return nil, fmt.Errorf("response id mismatch: %s != %s", resp.ID, req.ID)
+	}
+	if !resp.OK {
+		if resp.Error == nil {
+			return nil, internal.NewProtocolError(internal.ErrCodeInternal, "unknown error")
+		}
+		return nil, resp.Error
+	}
+	return &resp, nil
+}
+
+// DefaultConfigPath core 未指定 --config 时使用的配置文件
+const DefaultConfigPath = "config.yaml"
+
+var (
+	clientMu      sync.RWMutex
+	defaultClient = NewClient(internal.SocketAddr{Network: "tcp", Address: internal.DefaultSocketAddress})
+	configPath    = DefaultConfigPath
+)
+
+// Configure 按配置文件中的 socket 设置默认客户端地址，与 core 使用相同的解析规则
+func Configure(path string) error {
+	addr, err := internal.ResolveSocketAddr(path)
+	if err != nil {
+		return err
+	}
+	setTarget(NewClient(addr), path)
+	return nil
 }
 
-// SendCommand sends a line command to the socket server and returns trimmed response.
-func SendCommand(cmd string) (string, error) {
-	conn, err := net.Dial("tcp", localSocketAddr)
+// UseInstance 将默认客户端切换到已登记的实例，当前订阅会断开并重连到新实例
+func UseInstance(info internal.InstanceInfo) error {
+	addr, err := info.Addr()
 	if err != nil {
-		return "", err
+		return err
+	}
+	setTarget(NewClient(addr), info.Config)
+	return nil
+}
+
+// ListInstances 列出本机正在运行的 core 实例
+func ListInstances() []internal.InstanceInfo {
+	list, err := internal.ListInstances()
+	if err != nil {
+		fmt.Printf("[launcher] 读取实例列表失败: %v\n", err)
+	}
+	return list
+}
+
+func setTarget(c *Client, path string) {
+	clientMu.Lock()
+	defaultClient = c
+	configPath = path
+	clientMu.Unlock()
+	closeSubscription()
+}
+
+// DefaultClient returns the client used by the package-level helpers.
+func DefaultClient() *Client {
+	clientMu.RLock()
+	defer clientMu.RUnlock()
+	return defaultClient
+}
+
+// ConfigPath 返回当前目标实例的配置文件路径
+func ConfigPath() string {
+	clientMu.RLock()
+	defer clientMu.RUnlock()
+	return configPath
+}
+
+// Call 发送一个请求并把结果解码到 result（result 可为 nil）。
+// 服务端返回的错误以 *internal.ProtocolError 形式返回。
+func (c *Client) Call(req internal.Request, result interface{}) error {
+	return c.call(req, result, 0)
+}
+
+func (c *Client) call(req internal.Request, result interface{}, timeout time.Duration) error {
+	dialTimeout := c.DialTimeout
+	if timeout > 0 {
+		dialTimeout = timeout
+	}
+	conn, r, err := c.dial(dialTimeout)
+	if err != nil {
+		return err
 	}
 	defer conn.Close()
-	writer := bufio.NewWriter(conn)
-	writer.WriteString(cmd + "\n")
-	writer.Flush()
-	resp, err := io.ReadAll(conn)
+	if timeout > 0 {
+		conn.SetDeadline(time.Now().Add(timeout))
+	}
+
+	resp, err := c.roundTrip(conn, r, req)
 	if err != nil {
-		return "", err
+		return err
+	}
+	if result != nil && len(resp.Result) > 0 {
+		return json.Unmarshal(resp.Result, result)
 	}
-	return strings.TrimSpace(string(resp)), nil
+	return nil
 }
 
-// GetApps returns the list of apps by "list" (每行一个 name)。
-func GetApps() []string {
-	resp, err := SendCommand("list")
+// Subscription 事件订阅长连接，Events 在连接断开后关闭
+type Subscription struct {
+	Snapshot internal.SubscribeResult
+	Events   <-chan internal.Event
+	conn     net.Conn
+}
+
+// Close closes the subscription connection.
+func (s *Subscription) Close() error {
+	return s.conn.Close()
+}
+
+// Subscribe 建立长连接并订阅服务端事件，返回订阅时刻的状态快照
+func (c *Client) Subscribe() (*Subscription, error) {
+	conn, r, err := c.dial(c.DialTimeout)
 	if err != nil {
-		return nil
+		return nil, err
 	}
-	lines := strings.Split(resp, "\n")
-	var apps []string
-	for _, line := range lines {
-		line = strings.TrimSpace(line)
-		if line != "" {
-			apps = append(apps, line)
+	conn.SetDeadline(time.Now().Add(c.DialTimeout))
+	sub := &Subscription{conn: conn}
+	resp, err := c.roundTrip(conn, r, internal.Request{Cmd: "subscribe"})
+	if err == nil {
+		err = json.Unmarshal(resp.Result, &sub.Snapshot)
+	}
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	conn.SetDeadline(time.Time{})
+
+	ch := make(chan internal.Event, 16)
+	sub.Events = ch
+	go func() {
+		defer close(ch)
+		for {
+			line, err := r.ReadBytes('\n')
+			if err != nil {
+				return
+			}
+			var ev internal.Event
+			if json.Unmarshal(line, &ev) != nil || ev.Event == "" {
+				continue // 非事件行（如响应）忽略
+			}
+			ch <- ev
 		}
+	}()
+	return sub, nil
+}
+
+// Ping 发送 ping 请求，timeout 同时作用于连接与读写
+func (c *Client) Ping(timeout time.Duration) (internal.PingResult, error) {
+	var res internal.PingResult
+	err := c.call(internal.Request{Cmd: "ping"}, &res, timeout)
+	return res, err
+}
+
+// Status returns the status of the current app.
+func (c *Client) Status() (internal.StatusResult, error) {
+	var res internal.StatusResult
+	err := c.Call(internal.Request{Cmd: "status"}, &res)
+	return res, err
+}
+
+// Activate returns the activated app name.
+func (c *Client) Activate() (string, error) {
+	var res internal.ActivateResult
+	err := c.Call(internal.Request{Cmd: "activate"}, &res)
+	return res.Name, err
+}
+
+// List returns app names in config order.
+func (c *Client) List() ([]string, error) {
+	res, err := c.ListAll()
+	return res.Apps, err
+}
+
+// ListAll returns all apps together with the scanned group.
+func (c *Client) ListAll() (internal.ListResult, error) {
+	var res internal.ListResult
+	err := c.Call(internal.Request{Cmd: "list"}, &res)
+	return res, err
+}
+
+// Info returns app info, name 为空则为当前激活 app。
+func (c *Client) Info(name string) (internal.AppInfoResult, error) {
+	var res internal.AppInfoResult
+	err := c.Call(internal.Request{Cmd: "info", Name: name}, &res)
+	return res, err
+}
+
+// Logs returns the last tail lines of the app's output log, name 为空则为当前应用。
+func (c *Client) Logs(name string, tail int) (internal.LogsResult, error) {
+	var res internal.LogsResult
+	err := c.Call(internal.Request{Cmd: "logs", Name: name, Args: []string{"--tail", strconv.Itoa(tail)}}, &res)
+	return res, err
+}
+
+// History returns the recorded status transitions, name 为空则为全部应用。
+func (c *Client) History(name string, limit int) ([]internal.HistoryEntry, error) {
+	var res internal.HistoryResult
+	err := c.Call(internal.Request{Cmd: "history", Name: name, Args: []string{"-n", strconv.Itoa(limit)}}, &res)
+	return res.Entries, err
+}
+
+// Reload asks the core to reload config.
+func (c *Client) Reload() error {
+	return c.Call(internal.Request{Cmd: "reload"}, nil)
+}
+
+// Run starts the activated app with extra args.
+func (c *Client) Run(args ...string) error {
+	return c.Call(internal.Request{Cmd: "run", Args: args}, nil)
+}
+
+// RunProfile starts the activated app with the given profile (default 恢复默认)，正在运行的应用会先停止。
+func (c *Client) RunProfile(profile string) error {
+	return c.Call(internal.Request{Cmd: "run", Profile: profile}, nil)
+}
+
+// Switch switches the activated app.
+func (c *Client) Switch(name string) error {
+	return c.Call(internal.Request{Cmd: "switch", Name: name}, nil)
+}
+
+// Start starts another instance of the named app alongside the running ones, 返回新实例的状态（含 target）。
+func (c *Client) Start(name string, args ...string) (internal.StatusResult, error) {
+	var res internal.StatusResult
+	err := c.Call(internal.Request{Cmd: "run", Name: name, Args: args}, &res)
+	return res, err
+}
+
+// Ps returns the status of every managed instance.
+func (c *Client) Ps() ([]internal.StatusResult, error) {
+	var res internal.ManagedResult
+	err := c.Call(internal.Request{Cmd: "ps"}, &res)
+	return res.Instances, err
+}
+
+// Restart restarts the target (app#id 或应用名)，target 为空则为默认实例。
+func (c *Client) Restart(target string) error {
+	return c.Call(internal.Request{Cmd: "restart", Name: target}, nil)
+}
+
+// Stop stops the target (app#id 或应用名)，target 为空则为默认实例。
+func (c *Client) Stop(target string) error {
+	return c.Call(internal.Request{Cmd: "stop", Name: target}, nil)
+}
+
+// Exit stops the current app and exits the core.
+func (c *Client) Exit() error {
+	return c.Call(internal.Request{Cmd: "exit"}, nil)
+}
+
+// Ping checks if the socket server is reachable.
+// Returns (ok, timeout): ok=true 表示连接成功，timeout=true 表示超时未响应，二者都为 false 表示连接被拒绝或其它错误。
+func Ping() (ok bool, timeout bool) {
+	_, err := DefaultClient().Ping(300 * time.Millisecond)
+	if err == nil {
+		return true, false
+	}
+	if nerr, ok := err.(net.Error); ok && nerr.Timeout() {
+		return false, true
+	}
+	return false, false
+}
+
+// GetApps returns the list of app names.
+func GetApps() []string {
+	apps, err := DefaultClient().List()
+	if err != nil {
+		return nil
 	}
 	return apps
 }
 
 // GetActivate returns the current activated app name.
 func GetActivate() string {
-	name, _, _ := GetAppInfo("")
+	name, _ := DefaultClient().Activate()
 	return name
 }
 
-// GetAppStatus returns AppStatus from "status".
-func GetAppStatus() string {
-	resp, err := SendCommand("status")
+// GetAppStatus returns the app status, Main 为 AppUnknown 表示获取失败。
+func GetAppStatus() internal.StatusResult {
+	st, err := DefaultClient().Status()
 	if err != nil {
-		return internal.AppUnknown.String()
+		return internal.StatusResult{Main: internal.AppUnknown, Status: internal.AppUnknown.String()}
 	}
-	return resp
+	return st
 }
 
-// GetAppInfo 获取应用信息，name 为空则为当前激活 app，否则为指定 app。
-func GetAppInfo(name string) (string, string, string) {
-	cmd := "info"
-	if name != "" {
-		cmd = "info:" + name
-	}
-	resp, err := SendCommand(cmd)
-	if err != nil {
-		return "", "", ""
-	}
-	parts := strings.SplitN(resp, "|||", 3)
-	if len(parts) == 3 {
-		return parts[0], parts[1], parts[2]
-	}
-	return "", "", ""
+// GetAppInfo 获取应用信息，name 为空则为当前激活 app；失败时返回零值。
+func GetAppInfo(name string) internal.AppInfoResult {
+	info, _ := DefaultClient().Info(name)
+	return info
 }
 
 // ReloadConfig sends reload command and waits briefly
 func ReloadConfig() {
-	SendCommand("reload")
+	DefaultClient().Reload()
 	time.Sleep(100 * time.Millisecond)
 }
 
-// RestartApp sends restart command.
-func RestartApp() {
-	SendCommand("restart")
+// RestartApp sends restart command, target 为空则为默认实例。
+func RestartApp(target string) {
+	DefaultClient().Restart(target)
 }
 
-// StopApp sends stop command.
-func StopApp() {
-	SendCommand("stop")
+// StopApp sends stop command, target 为空则为默认实例。
+func StopApp(target string) {
+	DefaultClient().Stop(target)
 }
 
-// SwitchApp sends switch command.
-var OnAppSwitch func()
+// StartApp starts another instance of the named app alongside the running ones.
+func StartApp(name string) {
+	if _, err := DefaultClient().Start(name); err != nil {
+		fmt.Printf("[launcher] 同时运行 %s 失败: %v\n", name, err)
+	}
+}
 
+// ExitCore sends exit command.
+func ExitCore() {
+	DefaultClient().Exit()
+}
+
+// SwitchApp sends switch command.
 func SwitchApp(name string) {
-	SendCommand("switch:" + name)
+	DefaultClient().Switch(name)
+}
 
-	// 切换后也动态刷新
-	internal.UpdateTrayTitle(GetActivate())
-	if OnAppSwitch != nil {
-		OnAppSwitch()
+// RunApp sends run command, core 未运行时启动本地 evs.exe。
+// 启动后托盘由订阅重连自动同步，无需额外回调。
+func RunApp(args ...string) {
+	err := DefaultClient().Run(args...)
+	if _, isProtocolErr := err.(*internal.ProtocolError); err != nil && !isProtocolErr {
+		startCore()
 	}
 }
 
-// RunApp sends run command.
-// OnEVSRun is a callback for menu refresh after running evs.exe.
-var OnEVSRun func()
-
-func RunApp(args ...string) {
-	cmd := "run"
-	if len(args) > 0 {
-		cmd = "run:" + strings.Join(args, " ")
+// RunWithProfile sends run command with a profile, core 未运行时以 --profile 启动本地 evs.exe。
+func RunWithProfile(profile string) {
+	err := DefaultClient().RunProfile(profile)
+	if _, isProtocolErr := err.(*internal.ProtocolError); err != nil && !isProtocolErr {
+		startCore("--profile", profile)
+	} else if err != nil {
+		fmt.Printf("[trayRunApp] 使用 profile %s 启动失败: %v\n", profile, err)
 	}
-	_, err := SendCommand(cmd)
-	if err != nil {
-		absPath, errAbs := filepath.Abs("evs.exe")
-		if errAbs != nil {
-			fmt.Printf("[trayRunApp] 获取 evs.exe 路径失败: %v\n", errAbs)
-			return
-		}
+}
 
-		cmdObj := exec.Command(absPath)
-		cmdObj.SysProcAttr = &syscall.SysProcAttr{HideWindow: true} // 隐藏控制台窗口
-		cmdObj.Dir = "."
-		err2 := cmdObj.Start()
-		if err2 != nil {
-			fmt.Printf("[trayRunApp] 启动 evs.exe 失败: %v\n", err2)
-			return
-		}
+// startCore 启动本地 evs.exe，flags 为放在应用参数之前的 core 选项
+func startCore(flags ...string) {
+	absPath, errAbs := filepath.Abs("evs.exe")
+	if errAbs != nil {
+		fmt.Printf("[trayRunApp] 获取 evs.exe 路径失败: %v\n", errAbs)
+		return
+	}
 
-		evsProcess = cmdObj.Process
-		if OnEVSRun != nil {
-			go func() {
-				const (
-					maxWait  = 10 * time.Second
-					interval = 300 * time.Millisecond
-				)
-				waited := time.Duration(0)
-				for waited < maxWait {
-					apps := GetApps()
-					if len(apps) > 0 {
-						break
-					}
-					time.Sleep(interval)
-					waited += interval
-				}
-				OnEVSRun()
-			}()
-		}
+	// 非默认配置需要把 --config 传给 core，使其以对应实例启动
+	var coreArgs []string
+	if path := ConfigPath(); path != DefaultConfigPath {
+		coreArgs = append(coreArgs, "--config", path)
 	}
+	coreArgs = append(coreArgs, flags...)
+	cmdObj := exec.Command(absPath, coreArgs...)
+	internal.HideWindow(cmdObj) // 隐藏控制台窗口
+	cmdObj.Dir = "."
+	if err := cmdObj.Start(); err != nil {
+		fmt.Printf("[trayRunApp] 启动 evs.exe 失败: %v\n", err)
+		return
+	}
+
+	evsProcess = cmdObj.Process
 }
 
 // GetEVSProcess returns the current local evs.exe process.
@@ -0,0 +1,152 @@
+package command
+
+import (
+	"encoding/json"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/SSwser/exe-version-selector/internal"
+)
+
+// 断开后的重连间隔
+const reconnectInterval = 2 * time.Second
+
+// State 托盘展示所需的 core 状态，由订阅事件维护
+type State struct {
+	Connected bool // 订阅连接是否建立
+	Timeout   bool // 最近一次连接是否超时
+	Status    internal.StatusResult
+	Activate  string
+	Instance  string                 // 当前连接的实例名
+	Info      internal.AppInfoResult // 当前激活应用信息
+	Apps      []string
+	Scanned   []string                // Apps 中由扫描生成的应用，托盘单独分组显示
+	Managed   []internal.StatusResult // 全部托管实例（含已退出的）
+}
+
+var (
+	subMu      sync.Mutex
+	currentSub *Subscription // 当前订阅，切换实例时关闭以触发重连
+
+	stateMu sync.RWMutex
+	state   = State{Status: internal.StatusResult{Main: internal.AppUnknown, Status: internal.AppUnknown.String()}}
+)
+
+// CurrentState returns a copy of the latest known core state.
+func CurrentState() State {
+	stateMu.RLock()
+	defer stateMu.RUnlock()
+	return state
+}
+
+func updateState(f func(s *State)) State {
+	stateMu.Lock()
+	defer stateMu.Unlock()
+	f(&state)
+	return state
+}
+
+// Watch 维持一条订阅长连接，状态变化时调用 onChange；断开后定期重连（阻塞，需在 goroutine 中调用）
+func Watch(onChange func(State)) {
+	for {
+		client := DefaultClient()
+		sub, err := client.Subscribe()
+		if err != nil {
+			timeout := false
+			if nerr, ok := err.(net.Error); ok && nerr.Timeout() {
+				timeout = true
+			}
+			onChange(updateState(func(s *State) {
+				s.Connected = false
+				s.Timeout = timeout
+				s.Status = internal.StatusResult{Main: internal.AppUnknown, Status: internal.AppUnknown.String()}
+			}))
+			time.Sleep(reconnectInterval)
+			continue
+		}
+
+		// 连接建立后做一次全量同步，之后只按事件增量更新
+		subMu.Lock()
+		currentSub = sub
+		subMu.Unlock()
+		if client != DefaultClient() {
+			// 订阅建立期间目标实例已切换
+			sub.Close()
+		}
+		list, _ := client.ListAll()
+		info, _ := client.Info("")
+		onChange(updateState(func(s *State) {
+			s.Connected = true
+			s.Timeout = false
+			s.Status = sub.Snapshot.Status
+			s.Activate = sub.Snapshot.Activate
+			s.Instance = sub.Snapshot.Instance
+			s.Managed = sub.Snapshot.Managed
+			s.Apps = list.Apps
+			s.Scanned = list.Scanned
+			s.Info = info
+		}))
+
+		for ev := range sub.Events {
+			onChange(applyEvent(client, ev))
+		}
+		sub.Close()
+	}
+}
+
+// closeSubscription 关闭当前订阅，Watch 会立即用新的默认客户端重连
+func closeSubscription() {
+	subMu.Lock()
+	defer subMu.Unlock()
+	if currentSub != nil {
+		currentSub.Close()
+		currentSub = nil
+	}
+}
+
+// applyEvent 按事件更新状态，必要时补充查询
+func applyEvent(client *Client, ev internal.Event) State {
+	switch ev.Event {
+	case internal.EventStatus:
+		var st internal.StatusResult
+		if json.Unmarshal(ev.Data, &st) == nil {
+			return updateState(func(s *State) { s.Status = st })
+		}
+	case internal.EventManaged:
+		var managed internal.ManagedResult
+		if json.Unmarshal(ev.Data, &managed) == nil {
+			return updateState(func(s *State) { s.Managed = managed.Instances })
+		}
+	case internal.EventActivate:
+		var act internal.ActivateResult
+		if json.Unmarshal(ev.Data, &act) == nil {
+			info, _ := client.Info(act.Name)
+			return updateState(func(s *State) {
+				s.Activate = act.Name
+				s.Info = info
+			})
+		}
+	case internal.EventReload:
+		var reload internal.ReloadEvent
+		if json.Unmarshal(ev.Data, &reload) == nil {
+			// 激活应用的配置有变化时刷新应用信息（激活应用切换由 activate 事件处理）
+			refresh := false
+			for _, name := range reload.Changed {
+				refresh = refresh || name == reload.Activate
+			}
+			var info internal.AppInfoResult
+			if refresh {
+				info, _ = client.Info(reload.Activate)
+			}
+			return updateState(func(s *State) {
+				s.Apps = reload.Apps
+				s.Scanned = reload.Scanned
+				if refresh {
+					s.Info = info
+				}
+			})
+		}
+	}
+	return CurrentState()
+}
@@ -1,16 +1,20 @@
 package main
 
 import (
+	"flag"
 	"fmt"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"reflect"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/getlantern/systray"
 
 	"github.com/SSwser/exe-version-selector/internal"
+	"github.com/SSwser/exe-version-selector/internal/ui"
 	"github.com/SSwser/exe-version-selector/launcher/command"
 )
 
@@ -20,6 +24,25 @@ var switchNames []string
 
 // 缓存上一次的 app 列表用于防抖和变更检测
 var lastSwitchAppNames []string
+var menuScannedHeader *systray.MenuItem // “切换到”中扫描版本分组的标题
+
+var menuProfile *systray.MenuItem
+var menuProfileSubs []*systray.MenuItem
+var profileNames []string // 子菜单对应的 profile，第一项为 default
+
+var menuRunning *systray.MenuItem
+var menuRunningSlots []*runningSlot // “运行中”子菜单，实例变化时复用
+
+var menuStartAlso *systray.MenuItem
+var menuStartAlsoSubs []*systray.MenuItem
+var startAlsoNames []string
+
+var menuInstance *systray.MenuItem
+var menuInstanceSubs []*systray.MenuItem
+var instanceList []internal.InstanceInfo
+
+// 实例注册表的刷新间隔（实例启动/退出不会通知当前连接的 core）
+const instanceRefreshInterval = 5 * time.Second
 
 func trayOnReady() {
 	// 设置托盘图标
@@ -29,16 +52,18 @@ func trayOnReady() {
 	}
 
 	// 菜单分组配置（全部声明式）
-	menuConfig := []internal.MenuItemData{
+	menuConfig := []ui.MenuItemData{
 		{
 			Title:     "[未连接]",
 			Tooltip:   "与 EVS core 的 socket 连接状态",
 			Separator: true,
 			OnRefresh: func(item *systray.MenuItem) {
-				ok, timeout := command.Ping()
-				if ok {
+				st := command.CurrentState()
+				if st.Connected && st.Instance != "" {
+					item.SetTitle("[已连接] " + st.Instance)
+				} else if st.Connected {
 					item.SetTitle("[已连接]")
-				} else if timeout {
+				} else if st.Timeout {
 					item.SetTitle("[连接超时]")
 				} else {
 					item.SetTitle("[未连接]")
@@ -50,7 +75,7 @@ func trayOnReady() {
 			Tooltip: "应用运行状态",
 			Disable: true,
 			OnRefresh: func(item *systray.MenuItem) {
-				item.SetTitle("状态: " + command.GetAppStatus())
+				item.SetTitle("状态: " + command.CurrentState().Status.String())
 			},
 		},
 		{
@@ -58,7 +83,15 @@ func trayOnReady() {
 			Tooltip: "当前运行的应用",
 			Disable: true,
 			OnRefresh: func(item *systray.MenuItem) {
-				item.SetTitle("应用: " + command.GetActivate())
+				st := command.CurrentState()
+				title := "应用: " + st.Activate
+				if st.Info.Version != "" && st.Info.Name == st.Activate {
+					title += " (" + st.Info.Version + ")"
+				}
+				if st.Info.Profile != "" && st.Info.Name == st.Activate {
+					title += " @" + st.Info.Profile
+				}
+				item.SetTitle(title)
 			},
 		},
 		{
@@ -66,8 +99,7 @@ func trayOnReady() {
 			Tooltip: "可执行文件路径",
 			Disable: true,
 			OnRefresh: func(item *systray.MenuItem) {
-				_, p, _ := command.GetAppInfo("")
-				item.SetTitle("路径: " + p)
+				item.SetTitle("路径: " + command.CurrentState().Info.Path)
 			},
 		},
 		{
@@ -76,32 +108,55 @@ func trayOnReady() {
 			Disable:   true,
 			Separator: true,
 			OnRefresh: func(item *systray.MenuItem) {
-				_, _, a := command.GetAppInfo("")
-				item.SetTitle("参数: " + a)
+				item.SetTitle("参数: " + strings.Join(command.CurrentState().Info.Args, " "))
 			},
 		},
 		{
 			Title:   "打开目录",
 			Tooltip: "在文件资源管理器中打开当前应用所在文件夹",
 			OnClick: func(item *systray.MenuItem) {
-				_, appPath, _ := command.GetAppInfo("")
+				appPath := command.CurrentState().Info.Path
 				if appPath != "" {
 					dir := filepath.Dir(appPath)
 					exec.Command("explorer.exe", dir).Start()
 				}
 			},
 		},
+		{
+			Title:   "打开日志",
+			Tooltip: "打开当前应用的输出日志（崩溃前的输出也在其中）",
+			OnClick: func(item *systray.MenuItem) {
+				if logFile := command.CurrentState().Status.Log; logFile != "" {
+					exec.Command("notepad.exe", logFile).Start()
+				}
+			},
+		},
+		{
+			Title:   "实例",
+			Tooltip: "切换托盘连接的 core 实例",
+		},
 		{
 			Title:   "切换到",
 			Tooltip: "切换到其他应用",
 		},
+		{
+			Title:   "启动配置",
+			Tooltip: "选择当前应用的 profile 并以其重新启动",
+		},
+		{
+			Title:   "运行中",
+			Tooltip: "core 托管的全部运行中实例",
+		},
+		{
+			Title:   "同时运行",
+			Tooltip: "在已运行的实例之外再启动一个应用（不切换激活应用）",
+		},
 		{
 			Title:   "启动 / 重启",
 			Tooltip: "运行或重启当前激活的应用",
 			OnClick: func(item *systray.MenuItem) {
-				status := internal.ParseAppStatus(command.GetAppStatus())
-				if status == internal.AppRunning {
-					command.RestartApp()
+				if command.CurrentState().Status.Main.Alive() {
+					command.RestartApp("")
 				} else {
 					command.RunApp()
 				}
@@ -112,17 +167,14 @@ func trayOnReady() {
 			Tooltip:   "远程关闭当前激活应用",
 			Separator: true,
 			OnClick: func(item *systray.MenuItem) {
-				command.StopApp()
+				command.StopApp("")
 			},
 		},
 		{
 			Title:   "重载配置",
 			Tooltip: "重新加载 config.yaml",
 			OnClick: func(item *systray.MenuItem) {
-				command.ReloadConfig()
-				if menuSwitch != nil {
-					buildSwitchSubMenus()
-				}
+				command.ReloadConfig() // 菜单由 reload 事件刷新
 			},
 		},
 		{
@@ -134,23 +186,84 @@ func trayOnReady() {
 		},
 	}
 	for _, cfg := range menuConfig {
-		entry := internal.CreateMenuFromConfig(cfg)
-		if cfg.Title == "切换到" {
+		entry := ui.CreateMenuFromConfig(cfg)
+		switch cfg.Title {
+		case "切换到":
 			menuSwitch = entry.Item
+		case "实例":
+			menuInstance = entry.Item
+		case "启动配置":
+			menuProfile = entry.Item
+		case "运行中":
+			menuRunning = entry.Item
+		case "同时运行":
+			menuStartAlso = entry.Item
 		}
 		// 收集根菜单项，便于刷新
-		internal.RootMenuEntries = append(internal.RootMenuEntries, entry)
+		ui.RootMenuEntries = append(ui.RootMenuEntries, entry)
 	}
-	// 初始化后立即同步一次切换子菜单
-	buildSwitchSubMenus()
-
 	// 注册菜单刷新器
-	internal.RegisterMenuRefresher(func() {
-		internal.UpdateTrayTitle(command.GetActivate())
+	ui.RegisterMenuRefresher(func() {
+		ui.UpdateTrayTitle(command.CurrentState().Activate)
+	})
+
+	// 订阅 core 事件，状态变化时立即刷新菜单（不再定时轮询）
+	go command.Watch(func(command.State) {
+		ui.RefreshMenus()
+		buildSwitchSubMenus()
+		buildProfileSubMenus()
+		buildRunningSubMenus()
+		buildStartAlsoSubMenus()
 	})
 
-	// 启动菜单刷新器
-	internal.StartMenuRefresher()
+	go func() {
+		for {
+			buildInstanceSubMenus()
+			time.Sleep(instanceRefreshInterval)
+		}
+	}()
+}
+
+// 根据实例注册表生成“实例”子菜单，勾选当前连接的实例
+func buildInstanceSubMenus() {
+	if menuInstance == nil {
+		return
+	}
+
+	list := command.ListInstances()
+	if !reflect.DeepEqual(list, instanceList) {
+		for _, sub := range menuInstanceSubs {
+			sub.Hide()
+		}
+		menuInstanceSubs = nil
+		instanceList = list
+		for _, info := range list {
+			inst := info
+			sub := menuInstance.AddSubMenuItem(inst.Name, inst.Config)
+			menuInstanceSubs = append(menuInstanceSubs, sub)
+			go func(m *systray.MenuItem) {
+				for {
+					<-m.ClickedCh
+					if err := command.UseInstance(inst); err != nil {
+						fmt.Printf("[launcher] 切换实例失败: %v\n", err)
+					}
+					buildInstanceSubMenus()
+				}
+			}(sub)
+		}
+	}
+
+	current := command.ConfigPath()
+	if abs, err := filepath.Abs(current); err == nil {
+		current = abs
+	}
+	for i, sub := range menuInstanceSubs {
+		if instanceList[i].Config == current {
+			sub.Check()
+		} else {
+			sub.Uncheck()
+		}
+	}
 }
 
 // 动态生成“切换到”子菜单配置
@@ -159,36 +272,64 @@ func buildSwitchSubMenus() {
 		return
 	}
 
-	apps := command.GetApps()
-	changed := !reflect.DeepEqual(apps, lastSwitchAppNames)
+	st := command.CurrentState()
+	apps := st.Apps
+	groups := append(append([]string{}, apps...), st.Scanned...) // 分组变化也需要重建
+	changed := !reflect.DeepEqual(groups, lastSwitchAppNames)
 	if changed {
-		lastSwitchAppNames = make([]string, len(apps))
-		copy(lastSwitchAppNames, apps)
+		lastSwitchAppNames = groups
 		// 彻底隐藏和释放所有旧子项，避免子项残留
 		// systray.MenuItem 无法彻底销毁旧子项和 goroutine；不要再建议使用 Disable！
 		for _, sub := range menuSwitchSubs {
 			sub.Hide()
 		}
+		if menuScannedHeader != nil {
+			menuScannedHeader.Hide()
+			menuScannedHeader = nil
+		}
 		menuSwitchSubs = nil
 		switchNames = nil
+
+		// 手动添加的应用在前，扫描到的应用在分组标题之后
+		scanned := make(map[string]bool, len(st.Scanned))
+		for _, name := range st.Scanned {
+			scanned[name] = true
+		}
+		var manual, found []string
 		for _, name := range apps {
-			appName := name
-			sub := menuSwitch.AddSubMenuItem(appName, "切换到 "+appName)
-			menuSwitchSubs = append(menuSwitchSubs, sub)
-			switchNames = append(switchNames, appName)
-			go func(n string, m *systray.MenuItem) {
-				for {
-					<-m.ClickedCh
-					if n != command.GetActivate() {
-						command.SwitchApp(n)
+			if scanned[name] {
+				found = append(found, name)
+			} else {
+				manual = append(manual, name)
+			}
+		}
+		addItems := func(names []string) {
+			for _, name := range names {
+				appName := name
+				sub := menuSwitch.AddSubMenuItem(appName, "切换到 "+appName)
+				menuSwitchSubs = append(menuSwitchSubs, sub)
+				switchNames = append(switchNames, appName)
+				go func(n string, m *systray.MenuItem) {
+					for {
+						<-m.ClickedCh
+						if n != command.CurrentState().Activate {
+							command.SwitchApp(n)
+						}
 					}
-				}
-			}(appName, sub)
+				}(appName, sub)
+			}
+		}
+		addItems(manual)
+		if len(found) > 0 {
+			// 分组标题仅作展示，点击无效
+			menuScannedHeader = menuSwitch.AddSubMenuItem("── 扫描到的版本 ──", "由配置中的 scan 自动发现")
+			menuScannedHeader.Disable()
+			addItems(found)
 		}
 	}
 
 	// 每次都刷新 checked 状态
-	activate := command.GetActivate()
+	activate := st.Activate
 	for i, sub := range menuSwitchSubs {
 		if switchNames[i] == activate {
 			sub.Check()
@@ -198,6 +339,165 @@ func buildSwitchSubMenus() {
 	}
 }
 
+// 按当前应用定义的 profile 生成“启动配置”子菜单，勾选正在使用的 profile；应用没有 profile 时隐藏
+func buildProfileSubMenus() {
+	if menuProfile == nil {
+		return
+	}
+
+	info := command.CurrentState().Info
+	names := append([]string{internal.DefaultProfile}, info.Profiles...)
+	if !reflect.DeepEqual(names, profileNames) {
+		for _, sub := range menuProfileSubs {
+			sub.Hide()
+		}
+		menuProfileSubs = nil
+		profileNames = names
+		for _, name := range names {
+			title := name
+			if name == internal.DefaultProfile {
+				title = "默认"
+			}
+			sub := menuProfile.AddSubMenuItem(title, "以 "+title+" 方式启动")
+			menuProfileSubs = append(menuProfileSubs, sub)
+			go func(n string, m *systray.MenuItem) {
+				for {
+					<-m.ClickedCh
+					command.RunWithProfile(n)
+				}
+			}(name, sub)
+		}
+	}
+	if len(info.Profiles) == 0 {
+		menuProfile.Hide()
+		return
+	}
+	menuProfile.Show()
+
+	current := info.Profile
+	if current == "" {
+		current = internal.DefaultProfile
+	}
+	for i, sub := range menuProfileSubs {
+		if profileNames[i] == current {
+			sub.Check()
+		} else {
+			sub.Uncheck()
+		}
+	}
+}
+
+// runningSlot “运行中”子菜单的一项及其重启/停止子项。systray 无法删除菜单项，实例变化时复用已有的项，
+// 点击时作用于当前绑定的实例
+type runningSlot struct {
+	item    *systray.MenuItem
+	restart *systray.MenuItem
+	stop    *systray.MenuItem
+
+	mu     sync.Mutex
+	target string // 为空表示未使用（已隐藏）
+}
+
+func newRunningSlot() *runningSlot {
+	slot := &runningSlot{item: menuRunning.AddSubMenuItem("", "")}
+	slot.restart = slot.item.AddSubMenuItem("重启", "")
+	slot.stop = slot.item.AddSubMenuItem("停止", "")
+	go func() {
+		for {
+			select {
+			case <-slot.restart.ClickedCh:
+				if t := slot.getTarget(); t != "" {
+					command.RestartApp(t)
+				}
+			case <-slot.stop.ClickedCh:
+				if t := slot.getTarget(); t != "" {
+					command.StopApp(t)
+				}
+			}
+		}
+	}()
+	return slot
+}
+
+func (s *runningSlot) getTarget() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.target
+}
+
+func (s *runningSlot) setTarget(target string) {
+	s.mu.Lock()
+	s.target = target
+	s.mu.Unlock()
+}
+
+// 按运行中的托管实例生成“运行中”子菜单，每个实例可单独重启、停止，启动中/不健康时标注状态；没有实例运行时隐藏
+func buildRunningSubMenus() {
+	if menuRunning == nil {
+		return
+	}
+
+	var running []internal.StatusResult
+	for _, m := range command.CurrentState().Managed {
+		if m.Main.Alive() {
+			running = append(running, m)
+		}
+	}
+	for len(menuRunningSlots) < len(running) {
+		menuRunningSlots = append(menuRunningSlots, newRunningSlot())
+	}
+	for i, slot := range menuRunningSlots {
+		if i >= len(running) {
+			slot.setTarget("")
+			slot.item.Hide()
+			continue
+		}
+		target := running[i].Target
+		title := target
+		if running[i].Main != internal.AppRunning {
+			title += " (" + running[i].Status + ")"
+		}
+		slot.setTarget(target)
+		slot.item.SetTitle(title)
+		slot.item.SetTooltip(target)
+		slot.restart.SetTooltip("重启 " + target)
+		slot.stop.SetTooltip("停止 " + target)
+		slot.item.Show()
+	}
+	if len(running) == 0 {
+		menuRunning.Hide()
+	} else {
+		menuRunning.Show()
+	}
+}
+
+// 按应用列表生成“同时运行”子菜单
+func buildStartAlsoSubMenus() {
+	if menuStartAlso == nil {
+		return
+	}
+
+	apps := command.CurrentState().Apps
+	if reflect.DeepEqual(apps, startAlsoNames) {
+		return
+	}
+	for _, sub := range menuStartAlsoSubs {
+		sub.Hide()
+	}
+	menuStartAlsoSubs = nil
+	startAlsoNames = append([]string{}, apps...)
+	for _, name := range apps {
+		sub := menuStartAlso.AddSubMenuItem(name, "同时运行 "+name)
+		menuStartAlsoSubs = append(menuStartAlsoSubs, sub)
+		go func(n string, m *systray.MenuItem) {
+			for {
+				<-m.ClickedCh
+				command.StartApp(n)
+			}
+		}(name, sub)
+	}
+}
+
 func trayOnExit() {
 	evsProc := command.GetEVSProcess()
 	if evsProc == nil {
@@ -205,7 +505,7 @@ func trayOnExit() {
 	}
 
 	// 1. 优雅通知 evs
-	command.SendCommand("exit")
+	command.ExitCore()
 	// 2. 最多等待 3 秒
 	done := make(chan error, 1)
 	go func() { _, err := evsProc.Wait(); done <- err }()
@@ -222,10 +522,10 @@ func trayOnExit() {
 }
 
 func main() {
+	configPath := flag.String("config", command.DefaultConfigPath, "默认连接的 core 配置文件")
+	flag.Parse()
+	if err := command.Configure(*configPath); err != nil {
+		fmt.Printf("[launcher] socket 配置错误: %v\n", err)
+	}
 	systray.Run(trayOnReady, trayOnExit)
 }
-
-func init() {
-	command.OnEVSRun = buildSwitchSubMenus
-	command.OnAppSwitch = buildSwitchSubMenus
-}